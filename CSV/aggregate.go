@@ -0,0 +1,164 @@
+package csv2json
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// aggregation is one of the functions requested with --agg, like count or sum(amount).
+type aggregation struct {
+	function string // count, sum, avg, min or max
+	column   string // the column the function is applied to, empty for count
+}
+
+// key is the name of the field holding the aggregated value in the output records.
+func (a aggregation) key() string {
+	if a.column == "" {
+		return a.function
+	}
+	return a.function + "_" + a.column
+}
+
+// parseAggregations parses a list like "count,sum(amount),max(price)".
+func parseAggregations(value string) ([]aggregation, error) {
+	var aggregations []aggregation
+	for _, item := range splitList(value) {
+		open := strings.Index(item, "(")
+		if open == -1 {
+			if item != "count" {
+				return nil, fmt.Errorf("aggregation %q needs a column, as %s(column)", item, item)
+			}
+			aggregations = append(aggregations, aggregation{function: item})
+			continue
+		}
+		if !strings.HasSuffix(item, ")") {
+			return nil, fmt.Errorf("aggregation %q is missing a closing parenthesis", item)
+		}
+		function := strings.TrimSpace(item[:open])
+		column := strings.TrimSpace(item[open+1 : len(item)-1])
+		switch function {
+		case "count", "sum", "avg", "min", "max":
+		default:
+			return nil, fmt.Errorf("aggregation %q is not one of count, sum, avg, min or max", function)
+		}
+		if column == "" {
+			return nil, fmt.Errorf("aggregation %q has an empty column", item)
+		}
+		aggregations = append(aggregations, aggregation{function: function, column: column})
+	}
+	return aggregations, nil
+}
+
+// splitList splits a comma separated flag value, dropping the empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// group holds the running totals of every aggregation for one group of records.
+type group struct {
+	values  []string // the values of the group-by columns
+	count   int
+	sums    []float64
+	counts  []int // number of numeric values seen by each aggregation
+	minimum []float64
+	maximum []float64
+}
+
+func aggregateRecords(in <-chan map[string]string, out chan<- map[string]string, groupBy []string, aggregations []aggregation) {
+	groups := make(map[string]*group)
+	var order []string // Groups are written in the order they were first seen
+
+	for record := range in {
+		values := make([]string, len(groupBy))
+		for i, column := range groupBy {
+			values[i] = record[column]
+		}
+		id := strings.Join(values, "\x00")
+
+		g, ok := groups[id]
+		if !ok {
+			g = &group{
+				values:  values,
+				sums:    make([]float64, len(aggregations)),
+				counts:  make([]int, len(aggregations)),
+				minimum: make([]float64, len(aggregations)),
+				maximum: make([]float64, len(aggregations)),
+			}
+			groups[id] = g
+			order = append(order, id)
+		}
+		g.count++
+
+		for i, a := range aggregations {
+			if a.column == "" {
+				continue
+			}
+			raw := strings.TrimSpace(record[a.column])
+			if raw == "" { // Empty cells don't take part in the aggregation
+				continue
+			}
+			if a.function == "count" {
+				g.counts[i]++
+				continue
+			}
+			number, err := strconv.ParseFloat(raw, 64)
+			if err != nil { // Like malformed lines, values that aren't numbers are reported and skipped
+				slog.Warn("not a number, skipping it", "value", raw, "aggregation", a.function+"("+a.column+")")
+				continue
+			}
+			if g.counts[i] == 0 || number < g.minimum[i] {
+				g.minimum[i] = number
+			}
+			if g.counts[i] == 0 || number > g.maximum[i] {
+				g.maximum[i] = number
+			}
+			g.sums[i] += number
+			g.counts[i]++
+		}
+	}
+
+	for _, id := range order {
+		out <- groups[id].record(groupBy, aggregations)
+	}
+	close(out)
+}
+
+// record builds the output record of the group.
+func (g *group) record(groupBy []string, aggregations []aggregation) map[string]string {
+	record := make(map[string]string)
+	for i, column := range groupBy {
+		record[column] = g.values[i]
+	}
+	for i, a := range aggregations {
+		var value string
+		switch {
+		case a.function == "count" && a.column == "":
+			value = strconv.Itoa(g.count)
+		case a.function == "count":
+			value = strconv.Itoa(g.counts[i])
+		case g.counts[i] == 0: // Nothing to aggregate, so we leave the value empty
+		case a.function == "sum":
+			value = formatNumber(g.sums[i])
+		case a.function == "avg":
+			value = formatNumber(g.sums[i] / float64(g.counts[i]))
+		case a.function == "min":
+			value = formatNumber(g.minimum[i])
+		case a.function == "max":
+			value = formatNumber(g.maximum[i])
+		}
+		record[a.key()] = value
+	}
+	return record
+}
+
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
@@ -0,0 +1,60 @@
+package csv2json
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_aggregateRecords(t *testing.T) {
+	records := []map[string]string{
+		{"REGION": "east", "AMOUNT": "10"},
+		{"REGION": "west", "AMOUNT": "2.5"},
+		{"REGION": "east", "AMOUNT": "5"},
+		{"REGION": "east", "AMOUNT": ""},
+	}
+
+	tests := []struct {
+		name         string
+		groupBy      []string
+		aggregations string
+		want         []map[string]string
+	}{
+		{"Count per group", []string{"REGION"}, "count", []map[string]string{
+			{"REGION": "east", "count": "3"},
+			{"REGION": "west", "count": "1"},
+		}},
+		{"Numeric aggregations", []string{"REGION"}, "sum(AMOUNT),avg(AMOUNT),min(AMOUNT),max(AMOUNT),count(AMOUNT)", []map[string]string{
+			{"REGION": "east", "sum_AMOUNT": "15", "avg_AMOUNT": "7.5", "min_AMOUNT": "5", "max_AMOUNT": "10", "count_AMOUNT": "2"},
+			{"REGION": "west", "sum_AMOUNT": "2.5", "avg_AMOUNT": "2.5", "min_AMOUNT": "2.5", "max_AMOUNT": "2.5", "count_AMOUNT": "1"},
+		}},
+		{"Whole file summary", nil, "count,sum(AMOUNT)", []map[string]string{
+			{"count": "4", "sum_AMOUNT": "17.5"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aggregations, err := parseAggregations(tt.aggregations)
+			if err != nil {
+				t.Fatalf("parseAggregations() error = %v", err)
+			}
+
+			in := make(chan map[string]string)
+			out := make(chan map[string]string)
+			go func() {
+				for _, record := range records {
+					in <- record
+				}
+				close(in)
+			}()
+			go aggregateRecords(in, out, tt.groupBy, aggregations)
+
+			var got []map[string]string
+			for record := range out {
+				got = append(got, record)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("aggregateRecords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package csv2json
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"clikit/clierr"
+)
+
+// checkIssue is a structural problem found by --check.
+type checkIssue struct {
+	line    int
+	column  int // position of the problem in the line, 0 when it's the whole line
+	message string
+}
+
+func (i checkIssue) String() string {
+	if i.column > 0 {
+		return fmt.Sprintf("line %d, column %d: %s", i.line, i.column, i.message)
+	}
+	return fmt.Sprintf("line %d: %s", i.line, i.message)
+}
+
+// checkReport is everything --check found in one file.
+type checkReport struct {
+	lines        int // data lines read, without the headers
+	issues       []checkIssue
+	columnCounts map[int]int // number of lines having each number of fields
+}
+
+// checkCsv reads the whole CSV data without converting it, looking for duplicated or
+// empty headers, ragged rows, invalid UTF-8 and fields that can't be parsed, like
+// a quote in an unquoted field. Reading goes on after every issue.
+func checkCsv(fileData inputFile, r io.Reader) (*checkReport, error) {
+	report := &checkReport{columnCounts: map[int]int{}}
+	reader, headers, err := newRecordReader(fileData, r)
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		report.issues = append(report.issues, checkIssue{parseErr.Line, parseErr.Column, "headers can't be parsed: " + parseErr.Err.Error()})
+		return report, nil
+	} else if err == io.EOF {
+		report.issues = append(report.issues, checkIssue{1, 0, "the file is empty"})
+		return report, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(headers) > 0 && strings.HasPrefix(headers[0], "\uFEFF") {
+		report.issues = append(report.issues, checkIssue{1, 1, "the file starts with a byte order mark, which ends up in the first header"})
+	}
+	seen := map[string]int{}
+	for i, header := range headers {
+		if !utf8.ValidString(header) {
+			report.issues = append(report.issues, checkIssue{1, 0, fmt.Sprintf("header %d is not valid UTF-8", i+1)})
+		}
+		if strings.TrimSpace(header) == "" {
+			report.issues = append(report.issues, checkIssue{1, 0, fmt.Sprintf("header %d is empty", i+1)})
+			continue
+		}
+		if first, ok := seen[header]; ok {
+			report.issues = append(report.issues, checkIssue{1, 0, fmt.Sprintf("header %q is repeated, it's already header %d", header, first)})
+			continue
+		}
+		seen[header] = i + 1
+	}
+
+	for {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if errors.As(err, &parseErr) {
+			report.lines++
+			report.issues = append(report.issues, checkIssue{parseErr.Line, parseErr.Column, parseErr.Err.Error()})
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		report.lines++
+		lineNumber, _ := reader.FieldPos(0)
+		report.columnCounts[len(line)]++
+		if len(line) != len(headers) {
+			report.issues = append(report.issues, checkIssue{lineNumber, 0, fmt.Sprintf("ragged row, %d fields instead of %d", len(line), len(headers))})
+		}
+		for i, field := range line {
+			if !utf8.ValidString(field) {
+				_, column := reader.FieldPos(i)
+				report.issues = append(report.issues, checkIssue{lineNumber, column, fmt.Sprintf("field %d is not valid UTF-8", i+1)})
+			}
+		}
+	}
+	return report, nil
+}
+
+// printCheck prints the issues of the file followed by a summary, with the number
+// of fields of the lines when they don't all have the same.
+func printCheck(path string, report *checkReport) {
+	for _, issue := range report.issues {
+		fmt.Printf("%s: %s\n", path, issue)
+	}
+	if len(report.columnCounts) > 1 {
+		counts := make([]int, 0, len(report.columnCounts))
+		for count := range report.columnCounts {
+			counts = append(counts, count)
+		}
+		sort.Ints(counts)
+		parts := make([]string, len(counts))
+		for i, count := range counts {
+			parts[i] = fmt.Sprintf("%d fields on %d lines", count, report.columnCounts[count])
+		}
+		fmt.Printf("%s: inconsistent column counts, %s\n", path, strings.Join(parts, ", "))
+	}
+	fmt.Printf("%s: %d lines checked, %d issues\n", path, report.lines, len(report.issues))
+}
+
+// checkFiles runs --check on every input file, failing when any of them has issues.
+func checkFiles(fileData inputFile) error {
+	issues := 0
+	for _, path := range inputPaths(fileData) {
+		file, err := openInput(path)
+		if err != nil {
+			return err
+		}
+		fileData.filepath = path
+		report, err := checkCsv(fileData, file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		printCheck(path, report)
+		issues += len(report.issues)
+	}
+	if issues > 0 {
+		return clierr.Errorf(clierr.Validation, "check found %d issues", issues)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package csv2json
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_checkCsv(t *testing.T) {
+	data := "\uFEFFid,name,name,\n" +
+		"1,Ann,A,x\n" +
+		"2,Bob\n" +
+		"3,B\"ob,B,x\n" +
+		"4,\xff,C,x\n" +
+		"5,Eve,E,x\n"
+	report, err := checkCsv(inputFile{separator: "comma"}, strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("checkCsv() error = %v", err)
+	}
+	want := []string{
+		"line 1, column 1: the file starts with a byte order mark, which ends up in the first header",
+		`line 1: header "name" is repeated, it's already header 2`,
+		"line 1: header 4 is empty",
+		"line 3: ragged row, 2 fields instead of 4",
+		`line 4, column 4: bare " in non-quoted-field`,
+		"line 5, column 3: field 2 is not valid UTF-8",
+	}
+	got := make([]string, len(report.issues))
+	for i, issue := range report.issues {
+		got[i] = issue.String()
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checkCsv() issues =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if report.lines != 5 {
+		t.Errorf("checkCsv() lines = %d, want 5", report.lines)
+	}
+	if want := map[int]int{4: 3, 2: 1}; !reflect.DeepEqual(report.columnCounts, want) {
+		t.Errorf("checkCsv() column counts = %v, want %v", report.columnCounts, want)
+	}
+}
+
+func Test_checkCsvClean(t *testing.T) {
+	report, err := checkCsv(inputFile{separator: "comma"}, strings.NewReader("id,name\n1,Ann\n"))
+	if err != nil {
+		t.Fatalf("checkCsv() error = %v", err)
+	}
+	if len(report.issues) != 0 || report.lines != 1 {
+		t.Errorf("checkCsv() = %d issues on %d lines, want none on 1", len(report.issues), report.lines)
+	}
+}
@@ -0,0 +1,12 @@
+// Command csv2json converts CSV files to JSON, see package csv2json.
+package main
+
+import (
+	"os"
+
+	"csv2json"
+)
+
+func main() {
+	csv2json.Main(os.Args[0], os.Args[1:])
+}
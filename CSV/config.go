@@ -0,0 +1,42 @@
+package csv2json
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+
+	"clikit/config"
+)
+
+// applyConfig sets the flags not given on the command line to the values of the
+// CSV2JSON_* environment variables, like CSV2JSON_PRETTY, else of the config file, the
+// ones of the section applying to the command of the same name. The config file is
+// ~/.csv2jsonrc unless another one is given with --config or $CSV2JSON_CONFIG. It
+// returns the settings of all the flags.
+func applyConfig(flags *flag.FlagSet, section, path string) ([]config.Setting, error) {
+	optional := false
+	if path == "" {
+		path = os.Getenv("CSV2JSON_CONFIG")
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path, optional = filepath.Join(home, ".csv2jsonrc"), true
+	}
+	file, err := config.ReadFile(path, optional)
+	if err != nil {
+		return nil, err
+	}
+
+	// the flags choosing the config file and printing it don't come from it
+	var settable []config.Flag
+	for _, f := range config.Flags(flags) {
+		if f.Name != "config" && f.Name != "show-config" {
+			settable = append(settable, f)
+		}
+	}
+	c := &config.Config{File: file, EnvPrefix: "CSV2JSON"}
+	return c.Apply(section, settable)
+}
@@ -0,0 +1,53 @@
+package csv2json
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_applyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "csv2jsonrc")
+	if err := os.WriteFile(path, []byte("pretty = true\nseparator = semicolon\n[diff]\nkey = id\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CSV2JSON_SEPARATOR", "comma")
+
+	tests := []struct {
+		name          string
+		section       string
+		args          []string
+		wantPretty    bool
+		wantSeparator string
+		wantKey       string
+	}{
+		{"File and environment", "", nil, true, "comma", ""},
+		{"Command line", "", []string{"--pretty=false", "--separator=semicolon"}, false, "semicolon", ""},
+		{"Section", "diff", nil, true, "comma", "id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			pretty := flags.Bool("pretty", false, "")
+			separator := flags.String("separator", "comma", "")
+			key := flags.String("key", "", "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := applyConfig(flags, tt.section, path); err != nil {
+				t.Fatal(err)
+			}
+			if *pretty != tt.wantPretty || *separator != tt.wantSeparator || *key != tt.wantKey {
+				t.Errorf("applyConfig() set pretty=%t separator=%s key=%s, want pretty=%t separator=%s key=%s", *pretty, *separator, *key, tt.wantPretty, tt.wantSeparator, tt.wantKey)
+			}
+		})
+	}
+}
+
+func Test_applyConfigMissingFile(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	if _, err := applyConfig(flags, "", filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("applyConfig() didn't fail on a missing config file")
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package csv2json
 
 import (
 	"encoding/csv"
@@ -7,41 +7,269 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	"clikit/clierr"
+	"clikit/config"
+	"clikit/logging"
 )
 
-func main() {
+// Subcommand is what comes before the arguments of csv2json when the executable runs
+// it, like convert csv2json when it's a command of goclitool. The watch mode runs
+// csv2json again for every file.
+var Subcommand []string
+
+// Main runs csv2json with the arguments, like it's run from the command line under the
+// name of program, which is how its usage calls it. It parses the flags of
+// flag.CommandLine, replaced so Main can be run again.
+func Main(program string, args []string) {
+	os.Args = append([]string{program}, args...)
+	flag.CommandLine = flag.NewFlagSet(program, flag.ExitOnError)
+	run()
+}
+
+// flagsHook, when set, is given the flags of csv2json instead of parsing them,
+// getFileData then returning errFlagsCollected. It's how Flags learns them.
+var flagsHook func(flags *flag.FlagSet)
+
+var errFlagsCollected = errors.New("flags collected")
+
+// Flags returns the flags of csv2json, without running it, for the tools describing
+// them like goclitool completing them.
+func Flags() *flag.FlagSet {
+	commandLine, args := flag.CommandLine, os.Args
+	defer func() { flag.CommandLine, os.Args, flagsHook = commandLine, args, nil }()
+	flag.CommandLine = flag.NewFlagSet("csv2json", flag.ContinueOnError)
+	// getFileData wants a file before defining the flags
+	os.Args = []string{"csv2json", ""}
+	var flags *flag.FlagSet
+	flagsHook = func(fs *flag.FlagSet) {
+		flags = fs
+	}
+	getFileData()
+	return flags
+}
+
+// logOptions are the values of the logging flags, see package logging.
+var logOptions logging.Options
+
+func run() {
+	// The diagnostics are text until the logging flags are parsed, the progress being shown
+	logging.Setup(os.Stderr, logging.Options{}, slog.LevelInfo)
 	// Showing useful information when the user enters the --help option
 	flag.Usage = func() {
-		fmt.Printf("Usage: %s [options] <csvFile>\nOptions:\n", os.Args[0])
+		fmt.Printf("Usage: %s [options] <csvFile>...\n       %s diff [options] <old.csv> <new.csv>\n       %s config show [options]\n       %s selfupdate [options]\nSeveral files, or a glob like 'daily-*.csv', are merged into a single output named after the first one\nThe flags not given come from the environment, like CSV2JSON_PRETTY=true, else from the config file\nThe exit code tells the kind of error: 1 internal, 2 usage, 3 validation, 4 io and 5 network\nOptions:\n", os.Args[0], os.Args[0], os.Args[0], os.Args[0])
 		flag.PrintDefaults()
 	}
+	// config show is --show-config, printing the settings of the flags given with it
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "show" {
+		os.Args = append([]string{os.Args[0], "--show-config"}, os.Args[3:]...)
+	}
+	// The diff command has its own flags, see runDiff
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		check(runDiff(os.Args[2:]))
+		return
+	}
+	// So does selfupdate, see runSelfUpdate
+	if len(os.Args) > 1 && os.Args[1] == "selfupdate" {
+		check(runSelfUpdate(os.Args[2:]))
+		return
+	}
 	// Getting the file data that was entered by the user
 	fileData, err := getFileData()
 
 	if err != nil {
-		exitGracefully(err)
+		exitGracefully(clierr.Wrap(clierr.Usage, err))
 	}
-	// Validating the file entered
-	if _, err := checkIfValidFile(fileData.filepath); err != nil {
-		exitGracefully(err)
+	if fileData.settings != nil {
+		check(config.Print(os.Stdout, fileData.settings))
+		return
+	}
+	// In serve mode there is no file, conversions come from HTTP requests
+	if fileData.serveAddr != "" {
+		serve(fileData)
+		return
+	}
+	// In watch mode we're given a directory, and each CSV file showing up there is converted
+	if fileData.watchInterval > 0 {
+		if err := checkIfValidDir(fileData.filepath); err != nil {
+			exitGracefully(err)
+		}
+		watchDirectory(fileData)
+		return
+	}
+	// Validating the files entered, fixed-width files come with any extension, like .txt or .dat
+	for _, path := range inputPaths(fileData) {
+		var err error
+		if fileData.fixedColumns != nil {
+			err = checkIfExists(path)
+		} else {
+			_, err = checkIfValidFile(path)
+		}
+		if err != nil {
+			exitGracefully(err)
+		}
+	}
+	// Checking the files only reads them, nothing is written
+	if fileData.check {
+		check(checkFiles(fileData))
+		return
+	}
+	// Counting what happens during the conversion, for the summary at the end
+	if fileData.stats || fileData.statsOut != "" {
+		fileData.counters = newRunStats()
+	}
+	// Resumable conversions run on their own loop, see convertResumable
+	if fileData.resume {
+		convertResumable(fileData)
+		check(reportStats(fileData))
+		return
 	}
 	// Declaring the channels that our go-routines are going to use
 	writerChannel := make(chan map[string]string)
 	done := make(chan bool)
 	// Running both of our go-routines, the first one responsible for reading and the second one for writing
 	go processCsvFile(fileData, writerChannel)
-	go writeJSONFile(fileData.filepath, writerChannel, done, fileData.pretty)
+	records, stageErr := addStages(fileData, writerChannel)
+	records = countRecords(fileData.counters, records)
+	// A failed sort stops the conversion, spilling the chunks can fill the disk
+	go func() {
+		if err := <-stageErr; err != nil {
+			exitGracefully(err)
+		}
+	}()
+	// The schema is inferred from the records on their way to the writer
+	var schema *schemaBuilder
+	if fileData.emitSchema {
+		schema = newSchemaBuilder()
+		records = collectSchema(schema, records)
+	}
+	// A preview prints the first records to stdout instead of writing the JSON file
+	if fileData.preview > 0 {
+		go writePreview(records, done, fileData.preview)
+	} else if fileData.profile != "" {
+		go writeProfile(fileData, records, done)
+	} else if fileData.sink == "kafka" {
+		go writeKafka(fileData, records, done)
+	} else {
+		encoder, err := getEncoder(fileData)
+		if err != nil {
+			exitGracefully(err)
+		}
+		go writeRecords(outputLocation(fileData), records, done, encoder, fileData.split)
+	}
 	// Waiting for the done channel to receive a value, so that we can terminate the programn execution
 	<-done
+	if schema != nil {
+		check(writeSchema(schema, outputLocation(fileData), fileData.format != "ndjson"))
+	}
+	check(reportStats(fileData))
+}
+
+// addStages adds the optional stages between the reader and the writer, returning
+// the channel the writer has to consume, and the one receiving the error of the
+// stages, nil when they went fine, once that channel is closed.
+func addStages(fileData inputFile, records <-chan map[string]string) (<-chan map[string]string, <-chan error) {
+	stageErr := make(chan error, 1)
+	// Sampling comes first, so the other stages only see the sampled records
+	if fileData.sampleFraction > 0 {
+		sampledChannel := make(chan map[string]string)
+		go sampleFraction(records, sampledChannel, fileData.sampleFraction, newSampleRand(fileData.sampleSeed))
+		records = sampledChannel
+	} else if fileData.sampleRows > 0 {
+		sampledChannel := make(chan map[string]string)
+		go sampleRows(records, sampledChannel, fileData.sampleRows, newSampleRand(fileData.sampleSeed))
+		records = sampledChannel
+	}
+	// Aggregating and sorting are extra stages between the reader and the writer
+	if len(fileData.aggregations) > 0 {
+		groupedChannel := make(chan map[string]string)
+		go aggregateRecords(records, groupedChannel, fileData.groupBy, fileData.aggregations)
+		records = groupedChannel
+	}
+	if fileData.sortBy != "" {
+		sortedChannel := make(chan map[string]string)
+		go func(records <-chan map[string]string) {
+			stageErr <- sortRecords(records, sortedChannel, fileData.sortBy, fileData.sortDesc)
+		}(records)
+		return sortedChannel, stageErr
+	}
+	stageErr <- nil
+	return records, stageErr
 }
 
 type inputFile struct {
+	// settings are printed instead of converting anything, with --show-config
+	settings  []config.Setting
 	filepath  string
 	separator string
+	delimiter string // replaces the separator when set, it may be several characters long
 	pretty    bool
+	sortBy    string // column used to order the records, empty means input order
+	sortDesc  bool   // whether the records are sorted in descending order
+	// groupBy and aggregations turn the output into one record per group
+	groupBy      []string
+	aggregations []aggregation
+	// rules are checked on every record, the ones failing are written to rejectsPath
+	rules       []validationRule
+	rejectsPath string
+	preview     int  // number of records printed to stdout instead of writing the file
+	resume      bool // whether the conversion checkpoints its progress and continues from the last checkpoint
+	split       int  // maximum number of records per JSON file, 0 writes a single file
+	// watchInterval is how long a file stays unchanged before it's converted in watch mode, 0 when not watching
+	watchInterval    time.Duration
+	serveAddr        string // address to listen on for HTTP conversions, empty when not serving
+	output           string // where the JSON is written, a local path or a s3:// or gs:// URL
+	format           string // output format, json when empty
+	templatePath     string // template rendering each record for the template format
+	index            string // Elasticsearch index of the esbulk format
+	idColumn         string // column holding the document _id of the esbulk and mongo formats, optional
+	normalizeHeaders bool   // whether the headers are cleaned up before being used as keys
+	keyCase          string // camel, snake or kebab to rewrite the keys in that case, empty to keep them
+	// dateColumns are rewritten as RFC3339 dates, parsed with the first matching layout
+	dateColumns  []string
+	dateLayouts  []string
+	dateLocation *time.Location // used for dates without a time zone, UTC when nil
+	stats        bool           // whether a summary is printed at the end of the run
+	statsOut     string         // where the summary is written as JSON, empty to skip it
+	counters     *runStats      // set by main when stats are collected, nil otherwise
+	// sources are the CSV files merged into a single output, nil for a single file, which
+	// is then filepath. sourceColumn, if set, holds the file name each record comes from
+	sources      []string
+	sourceColumn string
+	// masks hide the sensitive columns of the records, hashes are salted with maskSalt
+	masks    []maskRule
+	maskSalt string
+	// emitSchema writes a JSON Schema of the records next to the output
+	emitSchema bool
+	// fixedColumns cut the lines of a fixed-width input, nil when the input is CSV
+	fixedColumns []fixedColumn
+	// sink is kafka when the records are published to the topic instead of written to
+	// a file, keyed by keyColumn if it's set
+	sink      string
+	brokers   []string
+	topic     string
+	keyColumn string
+	// check only reads the input, reporting its structural issues
+	check bool
+	// profile is text or json to print the statistics of every column instead of
+	// converting the records, with the profileTop most frequent values
+	profile    string
+	profileTop int
+	// a random sample of the records is converted when either sampleFraction or
+	// sampleRows is set, sampleSeed makes it reproducible
+	sampleFraction float64
+	sampleRows     int
+	sampleSeed     int64
+	// numbers is the locale of the numbers rewritten with a dot as decimal mark, nil to
+	// keep them as they are
+	numbers *numberFormat
 }
 
 func check(e error) {
@@ -50,9 +278,13 @@ func check(e error) {
 	}
 }
 
+// exitGracefully logs the error and exits with the code of its category, see package
+// clierr. The chunks spilled by a sort are removed first and the remote uploads
+// aborted, the deferred calls not running on exit.
 func exitGracefully(err error) {
-	fmt.Fprintf(os.Stderr, "error: %v\n", err)
-	os.Exit(1)
+	removeSpills()
+	abortUploads()
+	clierr.Exit(err)
 }
 
 func getFileData() (inputFile, error) {
@@ -64,80 +296,573 @@ func getFileData() (inputFile, error) {
 	// Define the option flags
 	// this will contain the name of the flag, the default value and a description of the flag
 	separator := flag.String("separator", "comma", "column separator")
+	delimiter := flag.String("delimiter", "", "Literal column delimiter used instead of the separator, like | or \\t, several characters like || or ~|~ are accepted")
 	pretty := flag.Bool("pretty", false, "Prettify JSON or not")
+	sortBy := flag.String("sort-by", "", "Sort records by column before writing, as COL or COL:desc, the numbers before the text")
+	groupBy := flag.String("group-by", "", "Comma separated columns to group records by")
+	agg := flag.String("agg", "", "Aggregations emitted per group, e.g. count,sum(amount),avg(price),min(x),max(x)")
+	validate := flag.String("validate", "", "Validation rules as col:required;col:regex=EXPR;col:min=N;col:max=N;col:enum=a|b, or @file with one rule per line")
+	rejectsPath := flag.String("rejects", "", "Write rejected rows with their errors to this file as JSON lines")
+	preview := flag.Int("preview", 0, "Print the first N converted records to stdout and exit without writing the JSON file")
+	split := flag.Int("split", 0, "Roll over to a new data.part-NNNN.json file every N records")
+	watch := flag.Bool("watch", false, "Treat the argument as a directory and convert every CSV file created or modified in it, moving them to done/")
+	watchInterval := flag.Duration("watch-interval", 2*time.Second, "How long a CSV file has to stay unchanged in the watched directory before it's converted")
+	normalize := flag.Bool("normalize-headers", false, "Trim, lowercase and replace spaces with underscores in the headers, renaming duplicates as col_2, col_3...")
+	keyCase := flag.String("key-case", "keep", "Rewrite the keys taken from the headers as camel, snake or kebab case, or keep them as they are")
+	dateColumns := flag.String("date-columns", "", "Comma separated columns parsed as dates and written as RFC3339")
+	dateFormat := flag.String("date-format", "", "Comma separated formats tried in order for the date columns, like MM/DD/YYYY HH:mm or epoch (defaults to common formats)")
+	decimalComma := flag.Bool("decimal-comma", false, "Read numbers like 1.234,56 written with a decimal comma, and write them as 1234.56")
+	thousandsSeparator := flag.String("thousands-separator", "", "Separator grouping the digits of numbers, rewritten without it: ., ,, ', space or none (default . with --decimal-comma, none otherwise)")
+	dateTimezone := flag.String("date-timezone", "", "Time zone of the dates that don't have one, like Europe/Paris (default UTC)")
+	format := flag.String("format", "", "Output format: json (default), ndjson, template, esbulk, mongo or toml")
+	index := flag.String("index", "", "Elasticsearch index the records go to, for --format=esbulk")
+	idColumn := flag.String("id-column", "", "Column used as the document _id, for --format=esbulk or mongo")
+	templatePath := flag.String("template", "", "Template file rendering each record with text/template, for --format=template")
+	output := flag.String("output", "", "Write the JSON to this location instead of next to the CSV file, local paths and s3:// or gs:// URLs are accepted")
+	serveAddr := flag.String("serve", "", "Listen on this address, e.g. :8080, and convert CSV data POSTed to /convert")
+	stats := flag.Bool("stats", false, "Print a summary of rows read, written and rejected, bytes processed, empty values per column and throughput at the end")
+	sample := flag.Float64("sample", 0, "Convert a random fraction of the records, like 0.01 for about 1% of them")
+	sampleRowsFlag := flag.Int("sample-rows", 0, "Convert a random sample of exactly N records, or all of them when there are fewer")
+	sampleSeed := flag.Int64("sample-seed", 0, "Seed of the random sampling, to get the same sample on every run (random by default)")
+	profile := flag.Bool("profile", false, "Print the statistics of every column, like their type, null percentage, distinct count, min, max, mean and most frequent values, instead of converting the file")
+	profileFormatFlag := flag.String("profile-format", "text", "Format of the --profile report: text or json")
+	profileTopFlag := flag.Int("profile-top", 5, "Number of most frequent values listed per column by --profile")
+	checkOnly := flag.Bool("check", false, "Only read the CSV files, reporting ragged rows, repeated headers, invalid UTF-8 and unparseable fields, and fail if there are any")
+	sink := flag.String("sink", "file", "Where the records go: file, or kafka to publish them to --topic")
+	brokersList := flag.String("brokers", "", "Comma separated Kafka brokers as host:port, for --sink=kafka")
+	topic := flag.String("topic", "", "Kafka topic the records are published to, for --sink=kafka")
+	keyColumn := flag.String("key-column", "", "Column used as the key of the Kafka messages, for --sink=kafka")
+	formatIn := flag.String("format-in", "csv", "Input format: csv or fixed, for fixed-width files described by --column-spec")
+	columnSpec := flag.String("column-spec", "", "Columns of a fixed-width file as name:start:length, with start counted from 1, e.g. id:1:5,name:6:20")
+	emitSchema := flag.Bool("emit-schema", false, "Write a JSON Schema of the records, with their inferred types, next to the output as NAME.schema.json")
+	mask := flag.String("mask", "", "Hide sensitive columns as col:hash, col:redact or col:last4, e.g. email:hash,ssn:redact,phone:last4")
+	maskSalt := flag.String("mask-salt", "", "Salt prepended to the values hashed by --mask")
+	sourceColumn := flag.String("source-column", "", "Add this column to every record, holding the name of the CSV file it comes from")
+	statsOut := flag.String("stats-out", "", "Write the end of run summary to this file as JSON")
+	resume := flag.Bool("resume", false, "Checkpoint the progress to a .state file next to the JSON file and continue from it after an interruption")
+	configPath := flag.String("config", "", "Config file giving the default values of the flags as name = value lines, like pretty = true (default ~/.csv2jsonrc or $CSV2JSON_CONFIG)")
+	logging.AddFlags(flag.CommandLine, &logOptions)
+	showConfig := flag.Bool("show-config", false, "Print the value of every flag and where it comes from, the command line, the environment, the config file or its default, and exit")
 
+	if flagsHook != nil {
+		flagsHook(flag.CommandLine)
+		return inputFile{}, errFlagsCollected
+	}
 	flag.Parse()
 
-	fileLocation := flag.Arg(0) // this basically returns the first argument which is not a flag
+	// The flags not given take their value from the environment, else from the config file
+	settings, err := applyConfig(flag.CommandLine, "", *configPath)
+	if err != nil {
+		return inputFile{}, err
+	}
+	if err := logging.Setup(os.Stderr, logOptions, slog.LevelInfo); err != nil {
+		return inputFile{}, err
+	}
+	if *showConfig {
+		return inputFile{settings: settings}, nil
+	}
+
+	// Every argument which is not a flag is a CSV file, or a glob matching some of them
+	files, err := expandInputs(flag.Args())
+	if err != nil {
+		return inputFile{}, err
+	}
+	var fileLocation string
+	var sources []string
+	if len(files) > 0 {
+		fileLocation = files[0]
+	}
+	if len(files) > 1 {
+		sources = files
+	}
 
 	// validating the separator we have recieved
 	if !(*separator == "comma" || *separator == "semicolon") {
 		return inputFile{}, errors.New("separator has to be either comma or semicolon")
 	}
 
+	// validating the delimiter, which can't be a character encoding/csv gives a meaning to
+	if *delimiter == `\t` {
+		*delimiter = "\t"
+	}
+	if *delimiter != "" {
+		if *separator != "comma" {
+			return inputFile{}, errors.New("delimiter can't be combined with separator")
+		}
+		if strings.ContainsAny(*delimiter, "\"\r\n\x1f") || !utf8.ValidString(*delimiter) {
+			return inputFile{}, fmt.Errorf("delimiter %q can't contain quotes, line breaks or unit separators", *delimiter)
+		}
+	}
+
+	// validating the sort column and its optional direction
+	sortColumn, sortDesc, err := parseSortBy(*sortBy)
+	if err != nil {
+		return inputFile{}, err
+	}
+
+	// validating the aggregations, grouping without any of them just counts the records
+	aggregations, err := parseAggregations(*agg)
+	if err != nil {
+		return inputFile{}, err
+	}
+	groupColumns := splitList(*groupBy)
+	if len(groupColumns) > 0 && len(aggregations) == 0 {
+		aggregations = []aggregation{{function: "count"}}
+	}
+
+	if *preview < 0 {
+		return inputFile{}, fmt.Errorf("preview has to be a positive number of records, got %d", *preview)
+	}
+
+	if *split < 0 {
+		return inputFile{}, fmt.Errorf("split has to be a positive number of records, got %d", *split)
+	}
+	// Resuming needs the records to be written in the same order they are read, into a single file
+	if *resume && (*sortBy != "" || *groupBy != "" || *agg != "" || *preview > 0 || *split > 0) {
+		return inputFile{}, errors.New("resume can't be combined with sort-by, group-by, agg, preview or split")
+	}
+
+	// The interval is only kept in watch mode, where it has to be a positive duration
+	var interval time.Duration
+	if *watch {
+		if *watchInterval <= 0 {
+			return inputFile{}, fmt.Errorf("watch-interval has to be a positive duration, got %s", *watchInterval)
+		}
+		if *preview > 0 {
+			return inputFile{}, errors.New("watch can't be combined with preview")
+		}
+		interval = *watchInterval
+	}
+
+	// validating the output format
+	switch *format {
+	case "", "json", "ndjson", "toml":
+	case "template":
+		if *templatePath == "" {
+			return inputFile{}, errors.New("the template format needs a --template file")
+		}
+	case "esbulk":
+		if *index == "" {
+			return inputFile{}, errors.New("the esbulk format needs an --index")
+		}
+	case "mongo":
+		if *index != "" {
+			return inputFile{}, errors.New("index needs --format=esbulk")
+		}
+	default:
+		return inputFile{}, errors.New("format has to be either json, ndjson, template, esbulk, mongo or toml")
+	}
+	if *format != "esbulk" && *format != "mongo" && (*index != "" || *idColumn != "") {
+		return inputFile{}, errors.New("index and id-column need --format=esbulk or mongo")
+	}
+
+	// validating the case of the keys, which is applied after normalize-headers
+	var keyCaseName string
+	switch *keyCase {
+	case "keep":
+	case "camel", "snake", "kebab":
+		keyCaseName = *keyCase
+	default:
+		return inputFile{}, errors.New("key-case has to be either camel, snake, kebab or keep")
+	}
+
+	// validating the date options, they're only kept when there are date columns
+	var dates []string
+	var layouts []string
+	var location *time.Location
+	if dates = splitList(*dateColumns); len(dates) > 0 {
+		formats := defaultDateFormats
+		if *dateFormat != "" {
+			formats = splitList(*dateFormat)
+		}
+		layouts = dateLayouts(formats)
+		if *dateTimezone != "" {
+			if location, err = time.LoadLocation(*dateTimezone); err != nil {
+				return inputFile{}, fmt.Errorf("date-timezone: %w", err)
+			}
+		}
+	} else if *dateFormat != "" || *dateTimezone != "" {
+		return inputFile{}, errors.New("date-format and date-timezone need some date-columns")
+	}
+
+	// the numbers are only rewritten when their format is given
+	var numbers *numberFormat
+	if *decimalComma || *thousandsSeparator != "" {
+		if numbers, err = newNumberFormat(*decimalComma, *thousandsSeparator); err != nil {
+			return inputFile{}, err
+		}
+	}
+
+	// Merged files are read one after the other by the reader go-routine
+	if len(sources) > 0 && (*resume || *watch) {
+		return inputFile{}, errors.New("resume and watch only take a single CSV file or directory")
+	}
+	if *serveAddr != "" && *sourceColumn != "" {
+		return inputFile{}, errors.New("serve can't be combined with source-column")
+	}
+
+	// Resuming seeks and truncates files, which can only be done on the local disk
+	if *resume && isMultiCharDelimiter(*delimiter) {
+		return inputFile{}, errors.New("resume can't be combined with a delimiter of several characters")
+	}
+	if *resume && (isRemote(fileLocation) || isRemote(*output)) {
+		return inputFile{}, errors.New("resume only works with local input and output files")
+	}
+	// Every file converted by the watcher needs its own output, next to it
+	if *watch && *output != "" {
+		return inputFile{}, errors.New("watch can't be combined with output")
+	}
+
+	// A server streams every response, so there is no file to preview, resume, split or watch
+	if *serveAddr != "" && (*preview > 0 || *resume || *split > 0 || *watch) {
+		return inputFile{}, errors.New("serve can't be combined with preview, resume, split or watch")
+	}
+	// and its responses are JSON, the kafka sink being checked with the others below
+	if *serveAddr != "" && *format != "" && *format != "json" && *format != "ndjson" {
+		return inputFile{}, errors.New("serve only answers in the json and ndjson formats")
+	}
+
+	// validating the rules every record has to pass
+	rules, err := parseValidation(*validate)
+	if err != nil {
+		return inputFile{}, err
+	}
+
+	// validating the input format, fixed-width files need the position of every column
+	var fixedColumns []fixedColumn
+	switch *formatIn {
+	case "csv":
+		if *columnSpec != "" {
+			return inputFile{}, errors.New("column-spec needs --format-in=fixed")
+		}
+	case "fixed":
+		if fixedColumns, err = parseFixedColumns(*columnSpec); err != nil {
+			return inputFile{}, err
+		}
+		if *resume {
+			return inputFile{}, errors.New("resume only works with CSV input")
+		}
+	default:
+		return inputFile{}, errors.New("format-in has to be either csv or fixed")
+	}
+
+	// Checking is done on the raw data of the files given on the command line
+	if *checkOnly {
+		if *formatIn != "csv" {
+			return inputFile{}, errors.New("check only works with CSV input")
+		}
+		if *preview > 0 || *resume || *serveAddr != "" || *watch {
+			return inputFile{}, errors.New("check can't be combined with preview, resume, serve or watch")
+		}
+	}
+
+	// validating the sampling, a seed only makes sense when sampling
+	if *sample < 0 || *sample > 1 {
+		return inputFile{}, fmt.Errorf("sample has to be a fraction between 0 and 1, got %v", *sample)
+	}
+	if *sampleRowsFlag < 0 {
+		return inputFile{}, fmt.Errorf("sample-rows has to be a positive number of records, got %d", *sampleRowsFlag)
+	}
+	sampling := *sample > 0 || *sampleRowsFlag > 0
+	if *sample > 0 && *sampleRowsFlag > 0 {
+		return inputFile{}, errors.New("sample can't be combined with sample-rows")
+	}
+	if sampling && *resume {
+		return inputFile{}, errors.New("resume can't be combined with sample or sample-rows")
+	}
+	var seed int64
+	if *sampleSeed != 0 {
+		if !sampling {
+			return inputFile{}, errors.New("sample-seed needs --sample or --sample-rows")
+		}
+		seed = *sampleSeed
+	}
+
+	// The profile takes the place of the output, so it's printed to stdout
+	var profileFormat string
+	var profileTop int
+	if *profile {
+		if *profileFormatFlag != "text" && *profileFormatFlag != "json" {
+			return inputFile{}, errors.New("profile-format has to be either text or json")
+		}
+		if *profileTopFlag < 0 {
+			return inputFile{}, fmt.Errorf("profile-top has to be a positive number of values, got %d", *profileTopFlag)
+		}
+		if *preview > 0 || *resume || *serveAddr != "" || *watch || *split > 0 || *output != "" || *checkOnly || *emitSchema || *sink != "file" {
+			return inputFile{}, errors.New("profile can't be combined with preview, resume, serve, watch, split, output, check, emit-schema or sink")
+		}
+		profileFormat, profileTop = *profileFormatFlag, *profileTopFlag
+	}
+
+	// validating the sink, Kafka messages are JSON records published as they're converted
+	var sinkName string
+	var brokers []string
+	switch *sink {
+	case "file":
+		if *brokersList != "" || *topic != "" || *keyColumn != "" {
+			return inputFile{}, errors.New("brokers, topic and key-column need --sink=kafka")
+		}
+	case "kafka":
+		if brokers = parseBrokers(*brokersList); len(brokers) == 0 || *topic == "" {
+			return inputFile{}, errors.New("the kafka sink needs some --brokers and a --topic")
+		}
+		if *format != "" && *format != "json" {
+			return inputFile{}, errors.New("the kafka sink publishes JSON records, it can't be combined with format")
+		}
+		if *preview > 0 || *split > 0 || *output != "" || *resume || *serveAddr != "" || *emitSchema {
+			return inputFile{}, errors.New("the kafka sink can't be combined with preview, split, output, resume, serve or emit-schema")
+		}
+		sinkName = *sink
+	default:
+		return inputFile{}, errors.New("sink has to be either file or kafka")
+	}
+
+	// The schema describes the JSON written to a file, so there has to be one
+	if *emitSchema {
+		if *format != "" && *format != "json" && *format != "ndjson" {
+			return inputFile{}, errors.New("emit-schema only works with the json and ndjson formats")
+		}
+		// A resumed conversion doesn't see the records written before the interruption
+		if *preview > 0 || *resume || *serveAddr != "" {
+			return inputFile{}, errors.New("emit-schema can't be combined with preview, resume or serve")
+		}
+	}
+
+	// validating the masked columns, the salt is only used for hashing them
+	masks, err := parseMasks(*mask)
+	if err != nil {
+		return inputFile{}, err
+	}
+	if *maskSalt != "" && len(masks) == 0 {
+		return inputFile{}, errors.New("mask-salt needs some columns to --mask")
+	}
+
 	// If everything goes well and we get to this point,
 	// we return the corresponding struct instance with all required data
-	return inputFile{fileLocation, *separator, *pretty}, nil
+	return inputFile{
+		filepath:         fileLocation,
+		separator:        *separator,
+		delimiter:        *delimiter,
+		pretty:           *pretty,
+		sortBy:           sortColumn,
+		sortDesc:         sortDesc,
+		groupBy:          groupColumns,
+		aggregations:     aggregations,
+		rules:            rules,
+		rejectsPath:      *rejectsPath,
+		preview:          *preview,
+		resume:           *resume,
+		split:            *split,
+		watchInterval:    interval,
+		serveAddr:        *serveAddr,
+		output:           *output,
+		format:           *format,
+		templatePath:     *templatePath,
+		index:            *index,
+		idColumn:         *idColumn,
+		normalizeHeaders: *normalize,
+		keyCase:          keyCaseName,
+		dateColumns:      dates,
+		dateLayouts:      layouts,
+		dateLocation:     location,
+		stats:            *stats,
+		statsOut:         *statsOut,
+		sources:          sources,
+		sourceColumn:     *sourceColumn,
+		masks:            masks,
+		maskSalt:         *maskSalt,
+		emitSchema:       *emitSchema,
+		fixedColumns:     fixedColumns,
+		sink:             sinkName,
+		brokers:          brokers,
+		topic:            *topic,
+		keyColumn:        *keyColumn,
+		check:            *checkOnly,
+		profile:          profileFormat,
+		profileTop:       profileTop,
+		sampleFraction:   *sample,
+		sampleRows:       *sampleRowsFlag,
+		sampleSeed:       seed,
+		numbers:          numbers,
+	}, nil
 }
 
 func checkIfValidFile(filename string) (bool, error) {
 	// checking if entered file is CSV by using the filepath package from the standard library
 	if fileExtension := filepath.Ext(filename); fileExtension != ".csv" {
-		return false, fmt.Errorf("file %s is not CSV", filename)
+		return false, clierr.Errorf(clierr.Usage, "file %s is not CSV", filename)
 	}
 
-	// checking if filepath entered belongs to an existing file. We use the stat method from the os package (standard library)
-	if _, err := os.Stat(filename); err != nil && os.IsNotExist(err) {
-		return false, fmt.Errorf("file %s does not exist", filename)
+	if err := checkIfExists(filename); err != nil {
+		return false, err
 	}
 
 	// if everything goes well and we get to this point
 	return true, nil
 }
 
+func checkIfExists(filename string) error {
+	// remote objects are only checked once we open them
+	if isRemote(filename) {
+		return nil
+	}
+
+	// checking if filepath entered belongs to an existing file. We use the stat method from the os package (standard library)
+	if _, err := os.Stat(filename); err != nil && os.IsNotExist(err) {
+		return clierr.Errorf(clierr.IO, "file %s does not exist", filename)
+	}
+	return nil
+}
+
 func processCsvFile(fileData inputFile, writerChannel chan map[string]string) {
-	file, err := os.Open(fileData.filepath)
-	check(err)
-	defer file.Close()
+	defer close(writerChannel)
 
-	// Define headers and line slice
-	var headers, line []string
+	// Rows that can't be converted or fail validation go to the rejects writer
+	rejects, err := newRejectWriter(fileData.rejectsPath)
+	check(err)
+	defer rejects.close()
 
-	// Initialize the csv reader
-	reader := csv.NewReader(file)
+	// Several files are merged into a single output, and they all need the headers of the first one
+	var headers []string
+	for _, path := range inputPaths(fileData) {
+		fileData.filepath = path
+		if len(fileData.sources) > 0 {
+			rejects.source = path
+		}
+		if headers, err = readCsvFile(fileData, headers, rejects, writerChannel); err != nil {
+			exitGracefully(err)
+		}
+	}
+}
 
-	// if the separator supplied from the commandline is semicolon, we need to add it here
-	if fileData.separator == "semicolon" {
-		reader.Comma = ';'
+func readCsvFile(fileData inputFile, wantHeaders []string, rejects *rejectWriter, writerChannel chan<- map[string]string) ([]string, error) {
+	file, err := openInput(fileData.filepath)
+	if err != nil {
+		return nil, err
 	}
+	defer file.Close()
+	return sendCsvRecords(fileData, file, wantHeaders, rejects, writerChannel)
+}
 
-	// Reading the first line where we will find our headers
-	headers, err = reader.Read()
-	check(err)
+// readCsv sends every record of the CSV data to the channel, closing it once
+// the data is over or reading it fails.
+func readCsv(fileData inputFile, r io.Reader, rejects *rejectWriter, writerChannel chan<- map[string]string) error {
+	defer close(writerChannel)
+	_, err := sendCsvRecords(fileData, r, nil, rejects, writerChannel)
+	return err
+}
+
+// sendCsvRecords sends every record of the CSV data to the channel and returns its headers.
+// When wantHeaders isn't nil, the data has to have the same headers, in any order.
+func sendCsvRecords(fileData inputFile, r io.Reader, wantHeaders []string, rejects *rejectWriter, writerChannel chan<- map[string]string) ([]string, error) {
+	// Reading the first line where we will find our headers, fixed-width files have them in the column spec
+	reader, headers, err := newRecordReader(fileData, r)
+	if err != nil {
+		return nil, err
+	}
+	headers = prepareHeaders(fileData, headers)
+	if wantHeaders != nil && !sameHeaders(headers, wantHeaders) {
+		return nil, fmt.Errorf("headers of %s %v don't match the first file %v", fileData.filepath, headers, wantHeaders)
+	}
+	if fileData.sourceColumn != "" && slices.Contains(headers, fileData.sourceColumn) {
+		return nil, fmt.Errorf("source-column %s is already a column of %s", fileData.sourceColumn, fileData.filepath)
+	}
+	fileData.counters.setHeaders(headers)
 
 	// Iterate over each line of the CSV file
 	for {
-		line, err = reader.Read()
-		// close the channel if we get to the end of the file
-
+		line, err := reader.Read()
+		// stop once we get to the end of the file
 		if err == io.EOF {
-			close(writerChannel)
-			break
+			fileData.counters.addBytes(reader.InputOffset())
+			return headers, nil
 		} else if err != nil {
-			exitGracefully(err)
+			return nil, err
 		}
-		// Processing a CSV Line
-		record, err := processLine(headers, line)
-		if err != nil {
-			fmt.Printf("Line: %sError: %s\n", line, err)
+		lineNumber, _ := reader.FieldPos(0)
+		record, ok := convertLine(fileData, headers, line, lineNumber, rejects)
+		if !ok {
 			continue
 		}
+		// tagging the record with the file it comes from
+		if fileData.sourceColumn != "" {
+			record[fileData.sourceColumn] = filepath.Base(fileData.filepath)
+		}
 
 		// send the processed record to the channel
 		writerChannel <- record
 	}
 }
 
+// recordReader reads the fields of every line of the input, like csv.Reader does.
+type recordReader interface {
+	Read() ([]string, error)
+	FieldPos(field int) (line, column int)
+	InputOffset() int64
+}
+
+// newRecordReader returns the reader of the input along with its headers.
+func newRecordReader(fileData inputFile, r io.Reader) (recordReader, []string, error) {
+	if fileData.fixedColumns != nil {
+		return newFixedWidthReader(r, fileData.fixedColumns), fixedHeaders(fileData.fixedColumns), nil
+	}
+	// encoding/csv only splits on a single rune, longer delimiters are replaced before it reads them
+	if isMultiCharDelimiter(fileData.delimiter) {
+		source := newDelimiterReader(r, fileData.delimiter)
+		reader := &delimitedReader{newCsvReader(fileData, source), source}
+		headers, err := reader.Read()
+		return reader, headers, err
+	}
+	reader := newCsvReader(fileData, r)
+	headers, err := reader.Read()
+	return reader, headers, err
+}
+
+func newCsvReader(fileData inputFile, r io.Reader) *csv.Reader {
+	// Initialize the csv reader, ragged rows are rejected by processLine instead of stopping the reader
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	// if the separator supplied from the commandline is semicolon, we need to add it here
+	if fileData.separator == "semicolon" {
+		reader.Comma = ';'
+	}
+	// a delimiter takes the place of the separator, see newRecordReader for the longer ones
+	if isMultiCharDelimiter(fileData.delimiter) {
+		reader.Comma = unitSeparator
+	} else if fileData.delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(fileData.delimiter)
+	}
+	return reader
+}
+
+// convertLine turns a CSV line into a record, sending it to the rejects writer
+// when it can't be converted or it doesn't pass the validation rules.
+func convertLine(fileData inputFile, headers, line []string, lineNumber int, rejects *rejectWriter) (map[string]string, bool) {
+	// Processing a CSV Line
+	record, err := processLine(headers, line)
+	if err != nil {
+		fileData.counters.readLine(nil, true)
+		fields, _, _ := maskRejected(fileData.masks, fileData.maskSalt, headers, line, nil, nil)
+		rejects.reject(lineNumber, fields, nil, []fieldError{{Message: err.Error()}})
+		return nil, false
+	}
+	// Normalizing the numbers and dates and checking the record against the validation rules, if any
+	normalizeNumbers(fileData, record)
+	errs := normalizeDates(fileData, record)
+	errs = append(errs, validateRecord(fileData.rules, record)...)
+	fileData.counters.readLine(record, len(errs) > 0)
+	if len(errs) > 0 {
+		// The rejected values of the masked columns are hidden too
+		fields, record, errs := maskRejected(fileData.masks, fileData.maskSalt, headers, line, record, errs)
+		rejects.reject(lineNumber, fields, record, errs)
+		return nil, false
+	}
+	// Masking comes last, so the rules are checked against the real values
+	maskRecord(fileData.masks, fileData.maskSalt, record)
+	return record, true
+}
+
 func processLine(headers []string, datalist []string) (map[string]string, error) {
 	// validating if we are getting the same number of headers and columns, otherwise return an error
 	if len(datalist) != len(headers) {
@@ -154,49 +879,111 @@ func processLine(headers []string, datalist []string) (map[string]string, error)
 	return recordMap, nil
 }
 
-func writeJSONFile(csvPath string, writerChannel <-chan map[string]string, done chan<- bool, pretty bool) {
-	writeString := createStringWriter(csvPath) // Instantiating a JSON writer function
-	jsonFunc, breakLine := getJSONFunc(pretty) // Instantiating the JSON parse function and the breakline character
+func writeJSONFile(jsonPath string, writerChannel <-chan map[string]string, done chan<- bool, pretty bool, split int) {
+	writeRecords(jsonPath, writerChannel, done, jsonEncoder(pretty), split)
+}
+
+// writeRecords writes the records into the output using the encoder's layout, rolling
+// over to a new part every split records when splitting is enabled.
+func writeRecords(location string, writerChannel <-chan map[string]string, done chan<- bool, encoder recordEncoder, split int) {
+	part := 1                                                                  // When splitting, the number of the file we're writing
+	writeString := createStringWriter(jsonPartLocation(location, part, split)) // Instantiating a writer function
 	// Log for informing
-	fmt.Println("Writing JSON file...")
-	// Writing the first character of our JSON file. We always start with a "[" since we always generate array of record
-	writeString("["+breakLine, false)
-	first := true
+	slog.Info("writing the file", "format", encoder.name, "path", jsonPartLocation(location, part, split))
+	// Writing the start of our file, for JSON we always start with a "[" since we always generate array of record
+	writeString(encoder.header, false)
+	count := 0 // The number of records written into the current file
 	for {
 		// Waiting for pushed records into our writerChannel
 		record, more := <-writerChannel
 		if more {
-			if !first { // If it's not the first record, we break the line
-				writeString(","+breakLine, false)
-			} else {
-				first = false // If it's the first one, we don't break the line
+			if split > 0 && count == split { // The current file is full, so we close it and roll over to the next part
+				writeString(encoder.footer, true)
+				part++
+				writeString = createStringWriter(jsonPartLocation(location, part, split))
+				writeString(encoder.header, false)
+				count = 0
 			}
+			if count > 0 { // If it's not the first record, we separate it from the previous one
+				writeString(encoder.separator, false)
+			}
+			count++
 
-			jsonData := jsonFunc(record) // Parsing the record into JSON
-			writeString(jsonData, false) // Writing the JSON string with our writer function
+			data, err := encoder.encode(record) // Encoding the record
+			check(err)
+			writeString(data, false) // Writing the encoded string with our writer function
 		} else { // If we get here, it means there aren't more record to parse. So we need to close the file
-			writeString(breakLine+"]", true) // Writing the final character and closing the file
-			fmt.Println("Completed!")        // Logging that we're done
-			done <- true                     // Sending the signal to the main function so it can correctly exit out.
-			break                            // Stoping the for-loop
+			writeString(encoder.footer, true) // Writing the end of the file and closing it
+			slog.Info("completed")            // Logging that we're done
+			done <- true                      // Sending the signal to the main function so it can correctly exit out.
+			break                             // Stoping the for-loop
 		}
 	}
 }
 
-func createStringWriter(csvPath string) func(string, bool) {
-	jsonDir := filepath.Dir(csvPath)                                                       // Getting the directory where the CSV file is
-	jsonName := fmt.Sprintf("%s.json", strings.TrimSuffix(filepath.Base(csvPath), ".csv")) // Declaring the JSON filename, using the CSV file name as base
-	finalLocation := filepath.Join(jsonDir, jsonName)                                      // Declaring the JSON file location, using the previous variables as base
+func writePreview(writerChannel <-chan map[string]string, done chan<- bool, limit int) {
+	jsonFunc, breakLine := getJSONFunc(true) // Previews are always pretty printed
+	fmt.Print("[" + breakLine)
+	for i := 0; i < limit; i++ {
+		record, more := <-writerChannel
+		if !more { // The file has less records than the preview size
+			break
+		}
+		if i > 0 {
+			fmt.Print("," + breakLine)
+		}
+		fmt.Print(jsonFunc(record))
+	}
+	fmt.Println(breakLine + "]")
+	// We don't wait for the rest of the records, the program ends as soon as the preview is printed
+	done <- true
+}
+
+// outputLocation is where the output goes, the --output location or else next to the
+// CSV file, with the extension of the output format.
+func outputLocation(fileData inputFile) string {
+	if fileData.output != "" {
+		return fileData.output
+	}
+	location := jsonFileLocation(fileData.filepath)
+	if encoder, err := getEncoder(fileData); err == nil {
+		location = strings.TrimSuffix(location, ".json") + encoder.extension
+	}
+	return location
+}
+
+func jsonFileLocation(csvPath string) string {
+	// remote objects keep their bucket and key prefix, only the extension changes
+	if isRemote(csvPath) {
+		return strings.TrimSuffix(csvPath, filepath.Ext(csvPath)) + ".json"
+	}
+	jsonDir := filepath.Dir(csvPath)                                                                      // Getting the directory where the CSV file is
+	jsonName := fmt.Sprintf("%s.json", strings.TrimSuffix(filepath.Base(csvPath), filepath.Ext(csvPath))) // Declaring the JSON filename, using the input file name as base
+	return filepath.Join(jsonDir, jsonName)                                                               // Declaring the JSON file location, using the previous variables as base
+}
+
+// jsonPartLocation returns where a part of a split output goes, like data.part-0001.json.
+// Without splitting everything goes into the single output file.
+func jsonPartLocation(location string, part, split int) string {
+	if split == 0 {
+		return location
+	}
+	ext := filepath.Ext(location)
+	return fmt.Sprintf("%s.part-%04d%s", strings.TrimSuffix(location, ext), part, ext)
+}
+
+func createStringWriter(finalLocation string) func(string, bool) {
 	// Opening the JSON file that we want to start writing
-	f, err := os.Create(finalLocation)
+	f, err := openOutput(finalLocation)
 	check(err)
 	// This is the function we want to return, we're going to use it to write the JSON file
 	return func(data string, close bool) { // 2 arguments: The piece of text we want to write, and whether or not we should close the file
-		_, err := f.WriteString(data) // Writing the data string into the file
+		_, err := io.WriteString(f, data) // Writing the data string into the file
 		check(err)
-		// If close is "true", it means there are no more data left to be written, so we close the file
+		// If close is "true", it means there are no more data left to be written, so we close the file.
+		// Remote uploads are only finished once they're closed, so the error matters here
 		if close {
-			f.Close()
+			check(f.Close())
 		}
 	}
 }
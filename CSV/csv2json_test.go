@@ -1,4 +1,4 @@
-package main
+package csv2json
 
 import (
 	"flag"
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func Test_getFileData(t *testing.T) {
@@ -17,12 +18,85 @@ func Test_getFileData(t *testing.T) {
 		osArgs  []string  // the command arguments used for the test
 	}{
 		// Here we're declaring each unit test input and output data as defined before
-		{"Default parameters", inputFile{"test.csv", "comma", false}, false, []string{"cmd", "test.csv"}},
+		{"Default parameters", inputFile{filepath: "test.csv", separator: "comma", pretty: false}, false, []string{"cmd", "test.csv"}},
 		{"No parameters", inputFile{}, true, []string{"cmd"}},
-		{"Semicolon enabled", inputFile{"test.csv", "semicolon", false}, false, []string{"cmd", "--separator=semicolon", "test.csv"}},
-		{"Pretty enabled", inputFile{"test.csv", "comma", true}, false, []string{"cmd", "--pretty", "test.csv"}},
-		{"Pretty and semicolon enabled", inputFile{"test.csv", "semicolon", true}, false, []string{"cmd", "--pretty", "--separator=semicolon", "test.csv"}},
+		{"Semicolon enabled", inputFile{filepath: "test.csv", separator: "semicolon", pretty: false}, false, []string{"cmd", "--separator=semicolon", "test.csv"}},
+		{"Pretty enabled", inputFile{filepath: "test.csv", separator: "comma", pretty: true}, false, []string{"cmd", "--pretty", "test.csv"}},
+		{"Pretty and semicolon enabled", inputFile{filepath: "test.csv", separator: "semicolon", pretty: true}, false, []string{"cmd", "--pretty", "--separator=semicolon", "test.csv"}},
 		{"Separator not identified", inputFile{}, true, []string{"cmd", "--separator=pipe", "test.csv"}},
+		{"Sort ascending", inputFile{filepath: "test.csv", separator: "comma", sortBy: "COL1"}, false, []string{"cmd", "--sort-by=COL1", "test.csv"}},
+		{"Sort descending", inputFile{filepath: "test.csv", separator: "comma", sortBy: "COL1", sortDesc: true}, false, []string{"cmd", "--sort-by=COL1:desc", "test.csv"}},
+		{"Sort direction not identified", inputFile{}, true, []string{"cmd", "--sort-by=COL1:up", "test.csv"}},
+		{"Group by with default count", inputFile{filepath: "test.csv", separator: "comma", groupBy: []string{"COL1"}, aggregations: []aggregation{{function: "count"}}}, false, []string{"cmd", "--group-by=COL1", "test.csv"}},
+		{"Group by with aggregations", inputFile{filepath: "test.csv", separator: "comma", groupBy: []string{"COL1"}, aggregations: []aggregation{{function: "count"}, {function: "sum", column: "COL2"}}}, false, []string{"cmd", "--group-by=COL1", "--agg=count,sum(COL2)", "test.csv"}},
+		{"Preview enabled", inputFile{filepath: "test.csv", separator: "comma", preview: 5}, false, []string{"cmd", "--preview=5", "test.csv"}},
+		{"Negative preview", inputFile{}, true, []string{"cmd", "--preview=-1", "test.csv"}},
+		{"Resume enabled", inputFile{filepath: "test.csv", separator: "comma", resume: true}, false, []string{"cmd", "--resume", "test.csv"}},
+		{"Resume and sort", inputFile{}, true, []string{"cmd", "--resume", "--sort-by=COL1", "test.csv"}},
+		{"Split enabled", inputFile{filepath: "test.csv", separator: "comma", split: 100}, false, []string{"cmd", "--split=100", "test.csv"}},
+		{"Watch enabled", inputFile{filepath: "incoming", separator: "comma", watchInterval: 5 * time.Second}, false, []string{"cmd", "--watch", "--watch-interval=5s", "incoming"}},
+		{"Watch and preview", inputFile{}, true, []string{"cmd", "--watch", "--preview=5", "incoming"}},
+		{"Serve enabled", inputFile{separator: "comma", serveAddr: ":8080"}, false, []string{"cmd", "--serve=:8080"}},
+		{"Serve and split", inputFile{}, true, []string{"cmd", "--serve=:8080", "--split=10"}},
+		{"Serve as NDJSON", inputFile{separator: "comma", serveAddr: ":8080", format: "ndjson"}, false, []string{"cmd", "--serve=:8080", "--format=ndjson"}},
+		{"Serve as TOML", inputFile{}, true, []string{"cmd", "--serve=:8080", "--format=toml"}},
+		{"Serve to Kafka", inputFile{}, true, []string{"cmd", "--serve=:8080", "--sink=kafka", "--brokers=localhost:9092", "--topic=t"}},
+		{"Remote output", inputFile{filepath: "test.csv", separator: "comma", output: "s3://bucket/test.json"}, false, []string{"cmd", "--output=s3://bucket/test.json", "test.csv"}},
+		{"Resume remote input", inputFile{}, true, []string{"cmd", "--resume", "gs://bucket/test.csv"}},
+		{"Template format", inputFile{filepath: "test.csv", separator: "comma", format: "template", templatePath: "row.tmpl"}, false, []string{"cmd", "--format=template", "--template=row.tmpl", "test.csv"}},
+		{"Template format without template", inputFile{}, true, []string{"cmd", "--format=template", "test.csv"}},
+		{"Date columns", inputFile{filepath: "test.csv", separator: "comma", dateColumns: []string{"COL1", "COL2"}, dateLayouts: []string{"01/02/2006", "epoch"}}, false, []string{"cmd", "--date-columns=COL1,COL2", "--date-format=MM/DD/YYYY,epoch", "test.csv"}},
+		{"Date format without columns", inputFile{}, true, []string{"cmd", "--date-format=MM/DD/YYYY", "test.csv"}},
+		{"Date time zone not identified", inputFile{}, true, []string{"cmd", "--date-columns=COL1", "--date-timezone=Nowhere/Land", "test.csv"}},
+		{"Normalized headers", inputFile{filepath: "test.csv", separator: "comma", normalizeHeaders: true}, false, []string{"cmd", "--normalize-headers", "test.csv"}},
+		{"Stats printed", inputFile{filepath: "test.csv", separator: "comma", stats: true}, false, []string{"cmd", "--stats", "test.csv"}},
+		{"Stats written as JSON", inputFile{filepath: "test.csv", separator: "comma", statsOut: "stats.json"}, false, []string{"cmd", "--stats-out=stats.json", "test.csv"}},
+		{"Several files merged", inputFile{filepath: "a.csv", separator: "comma", sources: []string{"a.csv", "b.csv"}, sourceColumn: "file"}, false, []string{"cmd", "--source-column=file", "a.csv", "b.csv"}},
+		{"Glob matching nothing", inputFile{}, true, []string{"cmd", "nothing-*.csv"}},
+		{"Resume with several files", inputFile{}, true, []string{"cmd", "--resume", "a.csv", "b.csv"}},
+		{"Masked columns", inputFile{filepath: "test.csv", separator: "comma", masks: []maskRule{{column: "email", method: "hash"}, {column: "phone", method: "last", keep: 4}}, maskSalt: "pepper"}, false, []string{"cmd", "--mask=email:hash,phone:last4", "--mask-salt=pepper", "test.csv"}},
+		{"Mask method not identified", inputFile{}, true, []string{"cmd", "--mask=email:scramble", "test.csv"}},
+		{"Mask salt without columns", inputFile{}, true, []string{"cmd", "--mask-salt=pepper", "test.csv"}},
+		{"Schema emitted", inputFile{filepath: "test.csv", separator: "comma", emitSchema: true}, false, []string{"cmd", "--emit-schema", "test.csv"}},
+		{"Schema of a template", inputFile{}, true, []string{"cmd", "--emit-schema", "--format=template", "--template=t.tmpl", "test.csv"}},
+		{"Schema with resume", inputFile{}, true, []string{"cmd", "--emit-schema", "--resume", "test.csv"}},
+		{"Fixed-width input", inputFile{filepath: "test.dat", separator: "comma", fixedColumns: []fixedColumn{{"id", 1, 5}, {"name", 6, 20}}}, false, []string{"cmd", "--format-in=fixed", "--column-spec=id:1:5,name:6:20", "test.dat"}},
+		{"Fixed-width without columns", inputFile{}, true, []string{"cmd", "--format-in=fixed", "test.dat"}},
+		{"Column spec of a CSV file", inputFile{}, true, []string{"cmd", "--column-spec=id:1:5", "test.csv"}},
+		{"Input format not identified", inputFile{}, true, []string{"cmd", "--format-in=xml", "test.xml"}},
+		{"Elasticsearch bulk format", inputFile{filepath: "test.csv", separator: "comma", format: "esbulk", index: "sales", idColumn: "id"}, false, []string{"cmd", "--format=esbulk", "--index=sales", "--id-column=id", "test.csv"}},
+		{"Elasticsearch bulk without index", inputFile{}, true, []string{"cmd", "--format=esbulk", "test.csv"}},
+		{"Index without esbulk format", inputFile{}, true, []string{"cmd", "--index=sales", "test.csv"}},
+		{"Kafka sink", inputFile{filepath: "test.csv", separator: "comma", sink: "kafka", brokers: []string{"kafka1:9093", "kafka2:9092"}, topic: "sales", keyColumn: "id"}, false, []string{"cmd", "--sink=kafka", "--brokers=kafka1:9093,kafka2", "--topic=sales", "--key-column=id", "test.csv"}},
+		{"Kafka sink without topic", inputFile{}, true, []string{"cmd", "--sink=kafka", "--brokers=kafka1", "test.csv"}},
+		{"Kafka sink with split", inputFile{}, true, []string{"cmd", "--sink=kafka", "--brokers=kafka1", "--topic=sales", "--split=10", "test.csv"}},
+		{"Topic without kafka sink", inputFile{}, true, []string{"cmd", "--topic=sales", "test.csv"}},
+		{"MongoDB format", inputFile{filepath: "test.csv", separator: "comma", format: "mongo", idColumn: "id"}, false, []string{"cmd", "--format=mongo", "--id-column=id", "test.csv"}},
+		{"Index with MongoDB format", inputFile{}, true, []string{"cmd", "--format=mongo", "--index=sales", "test.csv"}},
+		{"Camel case keys", inputFile{filepath: "test.csv", separator: "comma", keyCase: "camel"}, false, []string{"cmd", "--key-case=camel", "test.csv"}},
+		{"Keys kept as they are", inputFile{filepath: "test.csv", separator: "comma"}, false, []string{"cmd", "--key-case=keep", "test.csv"}},
+		{"Key case not identified", inputFile{}, true, []string{"cmd", "--key-case=upper", "test.csv"}},
+		{"Multi-character delimiter", inputFile{filepath: "test.csv", separator: "comma", delimiter: "~|~"}, false, []string{"cmd", "--delimiter=~|~", "test.csv"}},
+		{"Tab delimiter", inputFile{filepath: "test.csv", separator: "comma", delimiter: "\t"}, false, []string{"cmd", `--delimiter=\t`, "test.csv"}},
+		{"Delimiter with separator", inputFile{}, true, []string{"cmd", "--delimiter=||", "--separator=semicolon", "test.csv"}},
+		{"Delimiter with quotes", inputFile{}, true, []string{"cmd", `--delimiter="|`, "test.csv"}},
+		{"Resume with multi-character delimiter", inputFile{}, true, []string{"cmd", "--delimiter=||", "--resume", "test.csv"}},
+		{"Check only", inputFile{filepath: "test.csv", separator: "comma", check: true}, false, []string{"cmd", "--check", "test.csv"}},
+		{"Check with resume", inputFile{}, true, []string{"cmd", "--check", "--resume", "test.csv"}},
+		{"Profile as text", inputFile{filepath: "test.csv", separator: "comma", profile: "text", profileTop: 5}, false, []string{"cmd", "--profile", "test.csv"}},
+		{"Profile as JSON", inputFile{filepath: "test.csv", separator: "comma", profile: "json", profileTop: 3}, false, []string{"cmd", "--profile", "--profile-format=json", "--profile-top=3", "test.csv"}},
+		{"Profile format not identified", inputFile{}, true, []string{"cmd", "--profile", "--profile-format=html", "test.csv"}},
+		{"Profile with output", inputFile{}, true, []string{"cmd", "--profile", "--output=out.json", "test.csv"}},
+		{"Sample fraction", inputFile{filepath: "test.csv", separator: "comma", sampleFraction: 0.01, sampleSeed: 42}, false, []string{"cmd", "--sample=0.01", "--sample-seed=42", "test.csv"}},
+		{"Sample rows", inputFile{filepath: "test.csv", separator: "comma", sampleRows: 100}, false, []string{"cmd", "--sample-rows=100", "test.csv"}},
+		{"Sample fraction above one", inputFile{}, true, []string{"cmd", "--sample=2", "test.csv"}},
+		{"Sample fraction and rows", inputFile{}, true, []string{"cmd", "--sample=0.5", "--sample-rows=10", "test.csv"}},
+		{"Sample seed without sampling", inputFile{}, true, []string{"cmd", "--sample-seed=42", "test.csv"}},
+		{"TOML format", inputFile{filepath: "test.csv", separator: "comma", format: "toml"}, false, []string{"cmd", "--format=toml", "test.csv"}},
+		{"Decimal comma", inputFile{filepath: "test.csv", separator: "semicolon", numbers: mustNumberFormat(true, "")}, false, []string{"cmd", "--separator=semicolon", "--decimal-comma", "test.csv"}},
+		{"Thousands separator not identified", inputFile{}, true, []string{"cmd", "--thousands-separator=_", "test.csv"}},
+		{"Thousands separator as the decimal mark", inputFile{}, true, []string{"cmd", "--decimal-comma", "--thousands-separator=,", "test.csv"}},
+		{"Aggregation not identified", inputFile{}, true, []string{"cmd", "--group-by=COL1", "--agg=median(COL2)", "test.csv"}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -131,13 +205,12 @@ func Test_writeJSONFile(t *testing.T) {
 	}
 	// Defining our test cases
 	tests := []struct {
-		csvPath  string // The "fake" csv path.
 		jsonPath string // The existing JSON file with the expected data
 		pretty   bool   // Whether the output is formatted or not
 		name     string // The name of the test
 	}{
-		{"compact.csv", "compact.json", false, "Compact JSON"},
-		{"pretty.csv", "pretty.json", true, "Pretty JSON"},
+		{"compact.json", false, "Compact JSON"},
+		{"pretty.json", true, "Pretty JSON"},
 	}
 	// Iterating over our test cases
 	for _, tt := range tests {
@@ -154,7 +227,7 @@ func Test_writeJSONFile(t *testing.T) {
 				close(writerChannel)
 			}()
 			// Running our targeted function
-			go writeJSONFile(tt.csvPath, writerChannel, done, tt.pretty)
+			go writeJSONFile(tt.jsonPath, writerChannel, done, tt.pretty, 0)
 			// Waiting for the past function to end
 			<-done
 			// Getting the text from the JSON file created by the previous function
@@ -175,3 +248,55 @@ func Test_writeJSONFile(t *testing.T) {
 		})
 	}
 }
+
+func Test_writeJSONFileSplit(t *testing.T) {
+	dataMap := []map[string]string{
+		{"COL1": "1"},
+		{"COL1": "2"},
+		{"COL1": "3"},
+	}
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "data.json")
+
+	writerChannel := make(chan map[string]string)
+	done := make(chan bool)
+	go func() {
+		for _, record := range dataMap {
+			writerChannel <- record
+		}
+		close(writerChannel)
+	}()
+	go writeJSONFile(jsonPath, writerChannel, done, false, 2)
+	<-done
+
+	// Two records fit in the first part, the last one rolls over to a second part
+	want := map[string]string{
+		"data.part-0001.json": `[{"COL1":"1"},{"COL1":"2"}]`,
+		"data.part-0002.json": `[{"COL1":"3"}]`,
+	}
+	for name, wantOutput := range want {
+		testOutput, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("writeJSONFile(), Output file got error: %v", err)
+			continue
+		}
+		if string(testOutput) != wantOutput {
+			t.Errorf("writeJSONFile() %s = %v, want %v", name, string(testOutput), wantOutput)
+		}
+	}
+}
+
+func Test_Flags(t *testing.T) {
+	commandLine, args := flag.CommandLine, os.Args
+	flags := Flags()
+	if flags == nil || flags.Lookup("pretty") == nil || flags.Lookup("log-format") == nil || flags.Lookup("show-config") == nil {
+		t.Fatalf("Flags() = %v, without pretty, log-format or show-config", flags)
+	}
+	if f := flags.Lookup("separator"); f.DefValue != "comma" {
+		t.Errorf("the default separator is %q, want comma", f.DefValue)
+	}
+	// csv2json runs as it did before
+	if flag.CommandLine != commandLine || !reflect.DeepEqual(os.Args, args) || flagsHook != nil {
+		t.Error("Flags() didn't restore the command line")
+	}
+}
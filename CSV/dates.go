@@ -0,0 +1,109 @@
+package csv2json
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultDateFormats are tried in order when --date-format isn't given.
+var defaultDateFormats = []string{
+	time.RFC3339Nano,
+	"YYYY-MM-DD HH:mm:ss",
+	"YYYY-MM-DDTHH:mm:ss",
+	"YYYY-MM-DD HH:mm",
+	"YYYY-MM-DD",
+	"M/D/YYYY HH:mm:ss",
+	"M/D/YYYY H:mm",
+	"M/D/YYYY",
+	"epoch",
+}
+
+// dateTokens translates the usual date tokens into Go layouts. Longer tokens come
+// first so YYYY isn't read as two YY.
+var dateTokens = strings.NewReplacer(
+	"YYYY", "2006",
+	"YY", "06",
+	"MM", "01",
+	"M", "1",
+	"DD", "02",
+	"D", "2",
+	"HH", "15",
+	"H", "15",
+	"hh", "03",
+	"h", "3",
+	"mm", "04",
+	"ss", "05",
+	"SSS", "000",
+	"A", "PM",
+)
+
+// dateLayouts turns the --date-format list into Go layouts. Formats are written with
+// tokens like MM/DD/YYYY HH:mm:ss, or are one of epoch and epoch_ms for Unix timestamps.
+func dateLayouts(formats []string) []string {
+	layouts := make([]string, len(formats))
+	for i, format := range formats {
+		switch {
+		case format == "epoch", format == "epoch_ms", format == time.RFC3339Nano:
+			layouts[i] = format
+		default:
+			layouts[i] = dateTokens.Replace(format)
+		}
+	}
+	return layouts
+}
+
+// parseDate tries every layout in order and returns the date as an RFC3339 string.
+// Dates without a time zone are read in the given location.
+func parseDate(value string, layouts []string, location *time.Location) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range layouts {
+		var t time.Time
+		switch layout {
+		case "epoch", "epoch_ms":
+			number, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			if layout == "epoch" {
+				t = time.Unix(number, 0).In(location)
+			} else {
+				t = time.UnixMilli(number).In(location)
+			}
+		default:
+			var err error
+			if t, err = time.ParseInLocation(layout, value, location); err != nil {
+				continue
+			}
+		}
+		return t.Format(time.RFC3339), true
+	}
+	return "", false
+}
+
+// normalizeDates rewrites the date columns of the record as RFC3339 strings, reporting
+// the values that couldn't be parsed with any of the layouts. Empty values are kept.
+func normalizeDates(fileData inputFile, record map[string]string) []fieldError {
+	if len(fileData.dateColumns) == 0 {
+		return nil
+	}
+	location := fileData.dateLocation
+	if location == nil {
+		location = time.UTC
+	}
+	var errs []fieldError
+	for _, column := range fileData.dateColumns {
+		value, ok := record[column]
+		if !ok || strings.TrimSpace(value) == "" {
+			continue
+		}
+		date, ok := parseDate(value, fileData.dateLayouts, location)
+		if !ok {
+			errs = append(errs, fieldError{column, fmt.Sprintf("value %q is not a date in any of the accepted formats", value)})
+			continue
+		}
+		record[column] = date
+	}
+	return errs
+}
@@ -0,0 +1,36 @@
+package csv2json
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_parseDate(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	check(err)
+
+	tests := []struct {
+		name     string
+		value    string
+		formats  []string
+		location *time.Location
+		want     string
+		wantOk   bool
+	}{
+		{"Default US date and time", "1/2/2009 04:42", defaultDateFormats, time.UTC, "2009-01-02T04:42:00Z", true},
+		{"Default ISO date", "2009-01-02", defaultDateFormats, time.UTC, "2009-01-02T00:00:00Z", true},
+		{"Default epoch seconds", "1231000000", defaultDateFormats, time.UTC, "2009-01-03T16:26:40Z", true},
+		{"Day first format", "02/01/2009", []string{"DD/MM/YYYY"}, time.UTC, "2009-01-02T00:00:00Z", true},
+		{"Epoch milliseconds", "1231000000123", []string{"epoch_ms"}, time.UTC, "2009-01-03T16:26:40Z", true},
+		{"Local time zone", "2009-07-01 12:00", []string{"YYYY-MM-DD HH:mm"}, paris, "2009-07-01T12:00:00+02:00", true},
+		{"Not a date", "yesterday", defaultDateFormats, time.UTC, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseDate(tt.value, dateLayouts(tt.formats), tt.location)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("parseDate() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
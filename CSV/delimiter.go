@@ -0,0 +1,75 @@
+package csv2json
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"unicode/utf8"
+)
+
+// unitSeparator replaces the multi-character delimiters before the data reaches
+// encoding/csv, which only splits fields on a single rune.
+const unitSeparator = '\x1f'
+
+// isMultiCharDelimiter reports whether the delimiter needs the delimiterReader.
+func isMultiCharDelimiter(delimiter string) bool {
+	return utf8.RuneCountInString(delimiter) > 1
+}
+
+// delimiterReader rewrites every delimiter found outside of quoted fields as a unit
+// separator, so "a||b" is read as "a\x1fb" when the delimiter is "||".
+type delimiterReader struct {
+	r         *bufio.Reader
+	delimiter []byte
+	quoted    bool  // whether we're inside a quoted field
+	read      int64 // bytes read from the underlying reader
+}
+
+func newDelimiterReader(r io.Reader, delimiter string) *delimiterReader {
+	return &delimiterReader{r: bufio.NewReader(r), delimiter: []byte(delimiter)}
+}
+
+func (d *delimiterReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		d.read++
+		// A doubled quote inside a quoted field toggles twice, so it stays quoted
+		if b == '"' {
+			d.quoted = !d.quoted
+		}
+		if !d.quoted && b == d.delimiter[0] {
+			rest, _ := d.r.Peek(len(d.delimiter) - 1)
+			if string(rest) == string(d.delimiter[1:]) {
+				d.r.Discard(len(rest))
+				d.read += int64(len(rest))
+				b = unitSeparator
+			}
+		}
+		p[n] = b
+		n++
+		// Returning at the end of every line keeps the reads short, like a terminal does
+		if b == '\n' {
+			break
+		}
+	}
+	return n, nil
+}
+
+// delimitedReader is a csv.Reader reading through a delimiterReader. Its offsets count
+// the bytes of the original data, but they're only exact once the data is over, since
+// both readers buffer what they read.
+type delimitedReader struct {
+	*csv.Reader
+	source *delimiterReader
+}
+
+func (d *delimitedReader) InputOffset() int64 {
+	return d.source.read
+}
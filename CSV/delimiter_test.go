@@ -0,0 +1,53 @@
+package csv2json
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_readCsvDelimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		delimiter string
+		data      string
+		want      []map[string]string
+	}{
+		{"Single rune", "|", "id|name\n1|Ann\n", []map[string]string{{"id": "1", "name": "Ann"}}},
+		{"Two characters", "||", "id||name\n1||Ann|Bob\n", []map[string]string{{"id": "1", "name": "Ann|Bob"}}},
+		{"Three characters", "~|~", "id~|~name\r\n1~|~Ann\r\n2~|~\r\n", []map[string]string{{"id": "1", "name": "Ann"}, {"id": "2", "name": ""}}},
+		{"Quoted delimiter", "||", "id||name\n1||\"Ann || \"\"Bob\"\"\"\n", []map[string]string{{"id": "1", "name": `Ann || "Bob"`}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writerChannel := make(chan map[string]string)
+			go readCsv(inputFile{separator: "comma", delimiter: tt.delimiter}, strings.NewReader(tt.data), &rejectWriter{}, writerChannel)
+			var got []map[string]string
+			for record := range writerChannel {
+				got = append(got, record)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readCsv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_delimiterReaderOffset(t *testing.T) {
+	data := "a~|~b\n1~|~2\n"
+	reader, _, err := newRecordReader(inputFile{delimiter: "~|~"}, strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := reader.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := reader.InputOffset(); got != int64(len(data)) {
+		t.Errorf("InputOffset() = %d, want %d", got, len(data))
+	}
+}
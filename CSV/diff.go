@@ -0,0 +1,223 @@
+package csv2json
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"clikit/clierr"
+	"clikit/logging"
+)
+
+// csvDiff is the result of comparing two CSV files, as written by the diff command.
+type csvDiff struct {
+	Added   []map[string]string `json:"added"`
+	Removed []map[string]string `json:"removed"`
+	Changed []changedRow        `json:"changed"`
+}
+
+// changedRow is a row found in both files, with the columns whose value changed.
+type changedRow struct {
+	Key     map[string]string       `json:"key"`
+	Changes map[string]changedValue `json:"changes"`
+}
+
+// changedValue holds both values of a column, nil when the column is missing from a file.
+type changedValue struct {
+	Old *string `json:"old"`
+	New *string `json:"new"`
+}
+
+// runDiff implements "csv2json diff old.csv new.csv --key=id". Flags may come before
+// or after the files.
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Printf("Usage: %s diff [options] <old.csv> <new.csv>\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	key := flags.String("key", "", "Comma separated columns identifying a row in both files")
+	separator := flags.String("separator", "comma", "column separator")
+	pretty := flags.Bool("pretty", false, "Prettify JSON or not")
+	output := flags.String("output", "", "Write the differences to this file instead of stdout")
+	logging.AddFlags(flags, &logOptions)
+
+	var files []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		files = append(files, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	// the flags not given come from the environment or the [diff] section of the config file
+	if _, err := applyConfig(flags, "diff", ""); err != nil {
+		return clierr.Wrap(clierr.Usage, err)
+	}
+	if err := logging.Setup(os.Stderr, logOptions, slog.LevelInfo); err != nil {
+		return clierr.Wrap(clierr.Usage, err)
+	}
+	if len(files) != 2 {
+		return clierr.Errorf(clierr.Usage, "diff needs the old and the new CSV files")
+	}
+	keys := splitList(*key)
+	if len(keys) == 0 {
+		return clierr.Errorf(clierr.Usage, "diff needs the --key columns identifying the rows")
+	}
+	if *separator != "comma" && *separator != "semicolon" {
+		return clierr.Errorf(clierr.Usage, "separator has to be either comma or semicolon")
+	}
+
+	fileData := inputFile{separator: *separator}
+	oldRecords, err := readAllRecords(fileData, files[0])
+	if err != nil {
+		return err
+	}
+	newRecords, err := readAllRecords(fileData, files[1])
+	if err != nil {
+		return err
+	}
+	diff, err := diffRecords(oldRecords, newRecords, keys)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if *pretty {
+		data, err = json.MarshalIndent(diff, "", "   ")
+	} else {
+		data, err = json.Marshal(diff)
+	}
+	if err != nil {
+		return err
+	}
+	var w io.WriteCloser = os.Stdout
+	if *output != "" {
+		if w, err = openOutput(*output); err != nil {
+			return err
+		}
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if *output != "" {
+		if err := w.Close(); err != nil {
+			return err
+		}
+		fmt.Printf("%d added, %d removed, %d changed\n", len(diff.Added), len(diff.Removed), len(diff.Changed))
+	}
+	return nil
+}
+
+// readAllRecords reads every record of a CSV file, in order.
+func readAllRecords(fileData inputFile, path string) ([]map[string]string, error) {
+	file, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	writerChannel := make(chan map[string]string)
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- readCsv(fileData, file, &rejectWriter{}, writerChannel)
+	}()
+	var records []map[string]string
+	for record := range writerChannel {
+		records = append(records, record)
+	}
+	if err := <-readErr; err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return records, nil
+}
+
+// diffRecords matches the rows of both files by their key columns, whatever their order.
+// Added and changed rows are in the order of the new file, removed ones in the order
+// of the old file.
+func diffRecords(oldRecords, newRecords []map[string]string, keys []string) (*csvDiff, error) {
+	diff := &csvDiff{Added: []map[string]string{}, Removed: []map[string]string{}, Changed: []changedRow{}}
+	oldByKey := make(map[string]map[string]string, len(oldRecords))
+	for _, record := range oldRecords {
+		key, err := rowKey(record, keys)
+		if err != nil {
+			return nil, fmt.Errorf("old file: %w", err)
+		}
+		if _, ok := oldByKey[key]; ok {
+			return nil, fmt.Errorf("old file: key %s is repeated", key)
+		}
+		oldByKey[key] = record
+	}
+
+	seen := make(map[string]bool, len(newRecords))
+	for _, record := range newRecords {
+		key, err := rowKey(record, keys)
+		if err != nil {
+			return nil, fmt.Errorf("new file: %w", err)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("new file: key %s is repeated", key)
+		}
+		seen[key] = true
+
+		old, ok := oldByKey[key]
+		if !ok {
+			diff.Added = append(diff.Added, record)
+			continue
+		}
+		if changes := changedColumns(old, record); len(changes) > 0 {
+			keyValues := make(map[string]string, len(keys))
+			for _, column := range keys {
+				keyValues[column] = record[column]
+			}
+			diff.Changed = append(diff.Changed, changedRow{keyValues, changes})
+		}
+	}
+	for _, record := range oldRecords {
+		if key, _ := rowKey(record, keys); !seen[key] {
+			diff.Removed = append(diff.Removed, record)
+		}
+	}
+	return diff, nil
+}
+
+// rowKey joins the values of the key columns, quoted so "a,b" + "c" differs from "a" + "b,c".
+func rowKey(record map[string]string, keys []string) (string, error) {
+	parts := make([]string, len(keys))
+	for i, column := range keys {
+		value, ok := record[column]
+		if !ok {
+			return "", fmt.Errorf("key column %s not found", column)
+		}
+		parts[i] = fmt.Sprintf("%q", value)
+	}
+	return strings.Join(parts, ","), nil
+}
+
+func changedColumns(old, new map[string]string) map[string]changedValue {
+	changes := map[string]changedValue{}
+	for column, oldValue := range old {
+		newValue, ok := new[column]
+		if !ok {
+			changes[column] = changedValue{Old: stringPointer(oldValue)}
+		} else if newValue != oldValue {
+			changes[column] = changedValue{Old: stringPointer(oldValue), New: stringPointer(newValue)}
+		}
+	}
+	for column, newValue := range new {
+		if _, ok := old[column]; !ok {
+			changes[column] = changedValue{New: stringPointer(newValue)}
+		}
+	}
+	return changes
+}
+
+func stringPointer(s string) *string {
+	return &s
+}
@@ -0,0 +1,63 @@
+package csv2json
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_diffRecords(t *testing.T) {
+	oldRecords := []map[string]string{
+		{"id": "1", "name": "Ann", "price": "10"},
+		{"id": "2", "name": "Bob", "price": "20"},
+		{"id": "3", "name": "Eve", "price": "30"},
+	}
+	newRecords := []map[string]string{
+		{"id": "4", "name": "Zoe", "price": "40"},
+		{"id": "3", "name": "Eve", "price": "35"},
+		{"id": "1", "name": "Ann", "price": "10"},
+	}
+	diff, err := diffRecords(oldRecords, newRecords, []string{"id"})
+	if err != nil {
+		t.Fatalf("diffRecords() error = %v", err)
+	}
+	data, _ := json.Marshal(diff)
+	want := `{"added":[{"id":"4","name":"Zoe","price":"40"}],` +
+		`"removed":[{"id":"2","name":"Bob","price":"20"}],` +
+		`"changed":[{"key":{"id":"3"},"changes":{"price":{"old":"30","new":"35"}}}]}`
+	if string(data) != want {
+		t.Errorf("diffRecords() = %s, want %s", data, want)
+	}
+}
+
+func Test_diffRecordsColumns(t *testing.T) {
+	oldRecords := []map[string]string{{"id": "1", "region": "eu", "old": "x"}}
+	newRecords := []map[string]string{{"id": "1", "region": "eu", "new": "y"}}
+	diff, err := diffRecords(oldRecords, newRecords, []string{"id", "region"})
+	if err != nil {
+		t.Fatalf("diffRecords() error = %v", err)
+	}
+	data, _ := json.Marshal(diff.Changed)
+	want := `[{"key":{"id":"1","region":"eu"},"changes":{"new":{"old":null,"new":"y"},"old":{"old":"x","new":null}}}]`
+	if string(data) != want {
+		t.Errorf("diffRecords() changed = %s, want %s", data, want)
+	}
+}
+
+func Test_diffRecordsErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		oldRecords []map[string]string
+		newRecords []map[string]string
+	}{
+		{"Missing key column", []map[string]string{{"name": "Ann"}}, nil},
+		{"Repeated key in old file", []map[string]string{{"id": "1"}, {"id": "1"}}, nil},
+		{"Repeated key in new file", nil, []map[string]string{{"id": "1"}, {"id": "1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := diffRecords(tt.oldRecords, tt.newRecords, []string{"id"}); err == nil {
+				t.Errorf("diffRecords() should fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package csv2json
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// fixedColumn is one column of a fixed-width file, given with --column-spec as
+// name:start:length where start is the position of its first character, from 1.
+type fixedColumn struct {
+	name   string
+	start  int
+	length int
+}
+
+// parseFixedColumns parses a column spec like "id:1:5,name:6:20,amount:26:10".
+func parseFixedColumns(value string) ([]fixedColumn, error) {
+	var columns []fixedColumn
+	for _, item := range splitList(value) {
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("column spec %q has to be written as name:start:length", item)
+		}
+		start, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || start < 1 {
+			return nil, fmt.Errorf("column spec %q has to start at a position from 1", item)
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+		if err != nil || length < 1 {
+			return nil, fmt.Errorf("column spec %q has to have a positive length", item)
+		}
+		columns = append(columns, fixedColumn{strings.TrimSpace(parts[0]), start, length})
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("the fixed input format needs a --column-spec")
+	}
+	return columns, nil
+}
+
+func fixedHeaders(columns []fixedColumn) []string {
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.name
+	}
+	return headers
+}
+
+// fixedWidthReader cuts every line of a fixed-width file into the fields of the columns.
+// It has no header line, the headers are the names of the columns.
+type fixedWidthReader struct {
+	r       *bufio.Reader
+	columns []fixedColumn
+	line    int   // number of the last line read
+	offset  int64 // bytes read so far
+}
+
+func newFixedWidthReader(r io.Reader, columns []fixedColumn) *fixedWidthReader {
+	return &fixedWidthReader{r: bufio.NewReader(r), columns: columns}
+}
+
+// Read returns the fields of the next line that isn't blank. Positions are counted in
+// characters, and the padding around the values is trimmed. Columns past the end of
+// a short line are empty.
+func (f *fixedWidthReader) Read() ([]string, error) {
+	for {
+		text, err := f.r.ReadString('\n')
+		if text == "" && err != nil {
+			return nil, err
+		}
+		f.line++
+		f.offset += int64(len(text))
+		text = strings.TrimRight(text, "\r\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		runes := []rune(text)
+		fields := make([]string, len(f.columns))
+		for i, column := range f.columns {
+			start := column.start - 1
+			if start >= len(runes) {
+				continue
+			}
+			end := min(start+column.length, len(runes))
+			fields[i] = strings.TrimSpace(string(runes[start:end]))
+		}
+		return fields, nil
+	}
+}
+
+// FieldPos returns the line and the position of the field, like csv.Reader does.
+func (f *fixedWidthReader) FieldPos(field int) (line, column int) {
+	return f.line, f.columns[field].start
+}
+
+// InputOffset returns the number of bytes read so far.
+func (f *fixedWidthReader) InputOffset() int64 {
+	return f.offset
+}
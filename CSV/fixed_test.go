@@ -0,0 +1,57 @@
+package csv2json
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_parseFixedColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []fixedColumn
+		wantErr bool
+	}{
+		{"Columns", "id:1:5, name:6:20", []fixedColumn{{"id", 1, 5}, {"name", 6, 20}}, false},
+		{"No columns", "", nil, true},
+		{"Missing length", "id:1", nil, true},
+		{"Start from zero", "id:0:5", nil, true},
+		{"Empty length", "id:1:0", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFixedColumns(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFixedColumns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFixedColumns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readCsvFixedWidth(t *testing.T) {
+	columns, _ := parseFixedColumns("id:1:3,name:4:8,amount:12:7")
+	data := "001Ann     0012.50\r\n" +
+		"\n" +
+		"002Zoë     0007.00\n" +
+		"003Bob"
+	writerChannel := make(chan map[string]string)
+	fileData := inputFile{fixedColumns: columns}
+	go readCsv(fileData, strings.NewReader(data), &rejectWriter{}, writerChannel)
+
+	var got []map[string]string
+	for record := range writerChannel {
+		got = append(got, record)
+	}
+	want := []map[string]string{
+		{"id": "001", "name": "Ann", "amount": "0012.50"},
+		{"id": "002", "name": "Zoë", "amount": "0007.00"},
+		{"id": "003", "name": "Bob", "amount": ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readCsv() = %v, want %v", got, want)
+	}
+}
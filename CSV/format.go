@@ -0,0 +1,274 @@
+package csv2json
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// recordEncoder describes how the records are laid out in an output file.
+type recordEncoder struct {
+	name      string // used when logging, like JSON
+	extension string // extension of the output file, with the leading dot
+	header    string // written at the start of every output file
+	separator string // written between two records
+	footer    string // written at the end of every output file
+	encode    func(map[string]string) (string, error)
+}
+
+// getEncoder returns the encoder for the --format requested by the user.
+func getEncoder(fileData inputFile) (recordEncoder, error) {
+	switch fileData.format {
+	case "", "json":
+		return jsonEncoder(fileData.pretty), nil
+	case "ndjson":
+		return ndjsonEncoder(), nil
+	case "template":
+		return templateEncoder(fileData.templatePath)
+	case "esbulk":
+		return esbulkEncoder(fileData.index, fileData.idColumn)
+	case "mongo":
+		return mongoEncoder(fileData.idColumn), nil
+	case "toml":
+		return tomlEncoder(), nil
+	}
+	return recordEncoder{}, fmt.Errorf("unknown format %q", fileData.format)
+}
+
+// jsonEncoder writes the records as a single JSON array, built on top of getJSONFunc.
+func jsonEncoder(pretty bool) recordEncoder {
+	jsonFunc, breakLine := getJSONFunc(pretty)
+	return recordEncoder{
+		name:      "JSON",
+		extension: ".json",
+		header:    "[" + breakLine,
+		separator: "," + breakLine,
+		footer:    breakLine + "]",
+		encode: func(record map[string]string) (string, error) {
+			return jsonFunc(record), nil
+		},
+	}
+}
+
+// ndjsonEncoder writes one compact JSON record per line.
+func ndjsonEncoder() recordEncoder {
+	jsonFunc, _ := getJSONFunc(false)
+	return recordEncoder{
+		name:      "NDJSON",
+		extension: ".ndjson",
+		encode: func(record map[string]string) (string, error) {
+			return jsonFunc(record) + "\n", nil
+		},
+	}
+}
+
+// esbulkEncoder writes the records as the body of an Elasticsearch _bulk request, every
+// document line preceded by its index action. Without an id column Elasticsearch
+// generates the document IDs.
+func esbulkEncoder(index, idColumn string) (recordEncoder, error) {
+	if index == "" {
+		return recordEncoder{}, fmt.Errorf("the esbulk format needs an --index")
+	}
+	jsonFunc, _ := getJSONFunc(false)
+	return recordEncoder{
+		name:      "Elasticsearch bulk",
+		extension: ".bulk",
+		encode: func(record map[string]string) (string, error) {
+			action := map[string]string{"_index": index}
+			if idColumn != "" {
+				id := record[idColumn]
+				if id == "" {
+					return "", fmt.Errorf("record has no value for the id column %s", idColumn)
+				}
+				action["_id"] = id
+			}
+			data, err := json.Marshal(map[string]interface{}{"index": action})
+			if err != nil {
+				return "", err
+			}
+			return string(data) + "\n" + jsonFunc(record) + "\n", nil
+		},
+	}, nil
+}
+
+// mongoEncoder writes one MongoDB Extended JSON document per line, as mongoimport reads
+// them. Numbers are written as numbers and RFC3339 dates, like the ones produced by
+// --date-columns, as $date. The id column, if any, is renamed _id.
+func mongoEncoder(idColumn string) recordEncoder {
+	return recordEncoder{
+		name:      "MongoDB Extended JSON",
+		extension: ".json",
+		encode: func(record map[string]string) (string, error) {
+			document := make(map[string]interface{}, len(record))
+			for column, value := range record {
+				if column == idColumn {
+					column = "_id"
+				}
+				document[column] = mongoValue(value)
+			}
+			data, err := json.Marshal(document)
+			return string(data) + "\n", err
+		},
+	}
+}
+
+// mongoValue types a value for Extended JSON. Numbers with leading zeros, like zip
+// codes, are kept as strings.
+func mongoValue(value string) interface{} {
+	switch {
+	case mongoIntegerRegex.MatchString(value):
+		// Integers too large for a 64-bit integer would lose digits as doubles
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return map[string]string{"$numberDecimal": value}
+		}
+		return json.Number(value)
+	case mongoNumberRegex.MatchString(value):
+		return json.Number(value)
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return map[string]string{"$date": t.UTC().Format("2006-01-02T15:04:05.000Z")}
+	}
+	return value
+}
+
+var (
+	mongoIntegerRegex = regexp.MustCompile(`^-?(0|[1-9][0-9]*)$`)
+	mongoNumberRegex  = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+)
+
+// tomlEncoder writes the records as a TOML array of tables named records, so they're
+// read back as {"records": [...]}. Keys are sorted to keep the output stable.
+func tomlEncoder() recordEncoder {
+	return recordEncoder{
+		name:      "TOML",
+		extension: ".toml",
+		separator: "\n",
+		encode: func(record map[string]string) (string, error) {
+			keys := make([]string, 0, len(record))
+			for key := range record {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			var b strings.Builder
+			b.WriteString("[[records]]\n")
+			for _, key := range keys {
+				b.WriteString(tomlKey(key) + " = " + tomlString(record[key]) + "\n")
+			}
+			return b.String(), nil
+		},
+	}
+}
+
+var tomlBareKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlKey quotes the keys that can't be written as bare keys, like "Order ID".
+func tomlKey(key string) string {
+	if tomlBareKeyRegex.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+// tomlString writes a TOML basic string, escaping quotes, backslashes and control characters.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// templateFuncs are the helpers available to the --template files, on top of the
+// text/template builtins like html, js and printf.
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"replace": func(old, new, s string) string {
+		return strings.ReplaceAll(s, old, new)
+	},
+	// sql quotes a value as a SQL string literal
+	"sql": func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	},
+	// json quotes a value as a JSON string
+	"json": func(s string) (string, error) {
+		data, err := json.Marshal(s)
+		return string(data), err
+	},
+}
+
+// templateEncoder renders every record through the template file. The record is the
+// template data, so columns are available as {{.name}} or {{index . "Order ID"}}.
+// Templates named "header" and "footer", if defined, are rendered once at the start
+// and at the end of every output file.
+func templateEncoder(path string) (recordEncoder, error) {
+	if path == "" {
+		return recordEncoder{}, fmt.Errorf("the template format needs a --template file")
+	}
+	name := filepath.Base(path)
+	tmpl, err := template.New(name).Funcs(templateFuncs).ParseFiles(path)
+	if err != nil {
+		return recordEncoder{}, err
+	}
+
+	render := func(name string, data interface{}) (string, error) {
+		var b strings.Builder
+		err := tmpl.ExecuteTemplate(&b, name, data)
+		return b.String(), err
+	}
+	encoder := recordEncoder{
+		name:      "template",
+		extension: templateExtension(name),
+		encode: func(record map[string]string) (string, error) {
+			return render(name, record)
+		},
+	}
+	for _, part := range []struct {
+		name string
+		text *string
+	}{{"header", &encoder.header}, {"footer", &encoder.footer}} {
+		if tmpl.Lookup(part.name) == nil {
+			continue
+		}
+		if *part.text, err = render(part.name, nil); err != nil {
+			return recordEncoder{}, err
+		}
+	}
+	return encoder, nil
+}
+
+// templateExtension guesses the output extension from the template name, so
+// insert.sql.tmpl produces .sql files. Anything else produces .txt files.
+func templateExtension(name string) string {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if ext := filepath.Ext(name); ext != "" {
+		return ext
+	}
+	return ".txt"
+}
@@ -0,0 +1,133 @@
+package csv2json
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_templateEncoder(t *testing.T) {
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "insert.sql.tmpl")
+	templateString := `{{define "header"}}BEGIN;
+{{end}}{{define "footer"}}COMMIT;
+{{end}}INSERT INTO sales (name, price) VALUES ({{sql .NAME}}, {{.PRICE}});
+`
+	check(os.WriteFile(templatePath, []byte(templateString), 0644))
+
+	encoder, err := templateEncoder(templatePath)
+	if err != nil {
+		t.Fatalf("templateEncoder() error = %v", err)
+	}
+	if encoder.extension != ".sql" {
+		t.Errorf("templateEncoder() extension = %v, want .sql", encoder.extension)
+	}
+	if encoder.header != "BEGIN;\n" || encoder.footer != "COMMIT;\n" {
+		t.Errorf("templateEncoder() header = %q, footer = %q", encoder.header, encoder.footer)
+	}
+
+	got, err := encoder.encode(map[string]string{"NAME": "O'Brien", "PRICE": "10"})
+	if err != nil {
+		t.Fatalf("encode() error = %v", err)
+	}
+	want := "INSERT INTO sales (name, price) VALUES ('O''Brien', 10);\n"
+	if got != want {
+		t.Errorf("encode() = %q, want %q", got, want)
+	}
+}
+
+func Test_getEncoder(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		want    string // The extension of the output
+		wantErr bool
+	}{
+		{"Default format", "", ".json", false},
+		{"NDJSON format", "ndjson", ".ndjson", false},
+		{"Template without file", "template", "", true},
+		{"Elasticsearch bulk without index", "esbulk", "", true},
+		{"MongoDB format", "mongo", ".json", false},
+		{"TOML format", "toml", ".toml", false},
+		{"Format not identified", "xml", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder, err := getEncoder(inputFile{format: tt.format})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getEncoder() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if encoder.extension != tt.want {
+				t.Errorf("getEncoder() extension = %v, want %v", encoder.extension, tt.want)
+			}
+		})
+	}
+}
+
+func Test_esbulkEncoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		idColumn string
+		record   map[string]string
+		want     string
+		wantErr  bool
+	}{
+		{"Generated IDs", "", map[string]string{"id": "7", "name": "Ann"},
+			`{"index":{"_index":"sales"}}` + "\n" + `{"id":"7","name":"Ann"}` + "\n", false},
+		{"ID column", "id", map[string]string{"id": "7", "name": "Ann"},
+			`{"index":{"_id":"7","_index":"sales"}}` + "\n" + `{"id":"7","name":"Ann"}` + "\n", false},
+		{"Missing ID", "id", map[string]string{"id": "", "name": "Ann"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder, err := esbulkEncoder("sales", tt.idColumn)
+			if err != nil {
+				t.Fatalf("esbulkEncoder() error = %v", err)
+			}
+			got, err := encoder.encode(tt.record)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("encode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("encode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_mongoEncoder(t *testing.T) {
+	encoder := mongoEncoder("Order ID")
+	record := map[string]string{
+		"Order ID": "42",
+		"Price":    "1200.50",
+		"Zip":      "01234",
+		"Big":      "123456789012345678901234",
+		"Date":     "2009-01-02T06:17:00+01:00",
+		"Name":     "Ann",
+		"Empty":    "",
+	}
+	got, err := encoder.encode(record)
+	if err != nil {
+		t.Fatalf("encode() error = %v", err)
+	}
+	want := `{"Big":{"$numberDecimal":"123456789012345678901234"},"Date":{"$date":"2009-01-02T05:17:00.000Z"},"Empty":"","Name":"Ann","Price":1200.50,"Zip":"01234","_id":42}` + "\n"
+	if got != want {
+		t.Errorf("encode() = %s, want %s", got, want)
+	}
+}
+
+func Test_tomlEncoder(t *testing.T) {
+	encoder := tomlEncoder()
+	got, err := encoder.encode(map[string]string{"Order ID": "7", "name": "Ann \"A\" \\ B", "note": "line\nbreak\x01"})
+	if err != nil {
+		t.Fatalf("encode() error = %v", err)
+	}
+	want := "[[records]]\n" +
+		`"Order ID" = "7"` + "\n" +
+		`name = "Ann \"A\" \\ B"` + "\n" +
+		`note = "line\nbreak\u0001"` + "\n"
+	if got != want {
+		t.Errorf("encode() = %q, want %q", got, want)
+	}
+}
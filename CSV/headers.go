@@ -0,0 +1,101 @@
+package csv2json
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// prepareHeaders applies the header options to the headers read from the input.
+func prepareHeaders(fileData inputFile, headers []string) []string {
+	if fileData.normalizeHeaders {
+		headers = normalizeHeaders(headers)
+	}
+	if fileData.keyCase != "" {
+		headers = transformKeyCase(headers, fileData.keyCase)
+	}
+	return headers
+}
+
+// normalizeHeaders trims and lowercases the headers, replaces the whitespace inside them
+// with underscores and renames repeated headers as col, col_2, col_3... Empty headers
+// are named after their position, like column_4.
+func normalizeHeaders(headers []string) []string {
+	normalized := make([]string, len(headers))
+	for i, header := range headers {
+		normalized[i] = strings.ToLower(strings.Join(strings.Fields(header), "_"))
+	}
+	return uniqueHeaders(normalized, "_")
+}
+
+// transformKeyCase rewrites the headers in the camel, snake or kebab case, so
+// "Order ID" becomes orderId, order_id or order-id. Words are split on anything
+// that isn't a letter or a digit, and where the case changes, like in OrderID.
+func transformKeyCase(headers []string, keyCase string) []string {
+	separator := map[string]string{"camel": "", "snake": "_", "kebab": "-"}[keyCase]
+	transformed := make([]string, len(headers))
+	for i, header := range headers {
+		words := splitWords(header)
+		for j, word := range words {
+			word = strings.ToLower(word)
+			if keyCase == "camel" && j > 0 {
+				runes := []rune(word)
+				runes[0] = unicode.ToUpper(runes[0])
+				word = string(runes)
+			}
+			words[j] = word
+		}
+		transformed[i] = strings.Join(words, separator)
+	}
+	return uniqueHeaders(transformed, separator)
+}
+
+// splitWords splits a header into its words, keeping acronyms together, so
+// "HTTPServer Port2" gives HTTP, Server and Port2.
+func splitWords(header string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(header)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			continue
+		}
+		if len(word) > 0 && unicode.IsUpper(r) {
+			previous := word[len(word)-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				words = append(words, string(word))
+				word = nil
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// uniqueHeaders renames repeated headers as col, col_2, col_3..., using the separator
+// between the name and its number. Empty headers are named after their position, like column_4.
+func uniqueHeaders(headers []string, separator string) []string {
+	unique := make([]string, len(headers))
+	seen := make(map[string]bool)
+	for i, name := range headers {
+		if name == "" {
+			name = fmt.Sprintf("column%s%d", separator, i+1)
+		}
+		// Looking for the first free suffix, a header could already be called col_2
+		candidate := name
+		for n := 2; seen[candidate]; n++ {
+			candidate = fmt.Sprintf("%s%s%d", name, separator, n)
+		}
+		seen[candidate] = true
+		unique[i] = candidate
+	}
+	return unique
+}
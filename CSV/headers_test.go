@@ -0,0 +1,46 @@
+package csv2json
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_normalizeHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		want    []string
+	}{
+		{"Already normalized", []string{"id", "name"}, []string{"id", "name"}},
+		{"Spaces and case", []string{" Order ID ", "Customer  Name"}, []string{"order_id", "customer_name"}},
+		{"Duplicated headers", []string{"col", "Col", "col "}, []string{"col", "col_2", "col_3"}},
+		{"Suffix already taken", []string{"col", "col_2", "col"}, []string{"col", "col_2", "col_3"}},
+		{"Empty header", []string{"id", ""}, []string{"id", "column_2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeHeaders(tt.headers); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeHeaders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_transformKeyCase(t *testing.T) {
+	headers := []string{"Order ID", "customer_name", "HTTPServer Port2", "unitPrice", "", "order-id"}
+	tests := []struct {
+		keyCase string
+		want    []string
+	}{
+		{"camel", []string{"orderId", "customerName", "httpServerPort2", "unitPrice", "column5", "orderId2"}},
+		{"snake", []string{"order_id", "customer_name", "http_server_port2", "unit_price", "column_5", "order_id_2"}},
+		{"kebab", []string{"order-id", "customer-name", "http-server-port2", "unit-price", "column-5", "order-id-2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keyCase, func(t *testing.T) {
+			if got := transformKeyCase(headers, tt.keyCase); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("transformKeyCase() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
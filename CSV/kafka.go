@@ -0,0 +1,100 @@
+package csv2json
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"clikit/clierr"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// The Kafka sink publishes the records with franz-go, acknowledged by all the in-sync
+// replicas of an idempotent producer, so the retries neither duplicate nor reorder
+// them. The client refreshes the metadata and retries when the leader of a partition
+// moves or is being elected, and sends the messages with a key to the partition the
+// Java client would pick. There is no TLS or SASL.
+
+// kafkaDeliveryTimeout is how long a record is retried before the sink gives up on it.
+var kafkaDeliveryTimeout = 2 * time.Minute
+
+// kafkaOptions are the options of the client publishing to the topic.
+func kafkaOptions(brokers []string, topic string) []kgo.Opt {
+	return []kgo.Opt{
+		kgo.SeedBrokers(brokers...),
+		kgo.DefaultProduceTopic(topic),
+		kgo.ClientID("csv2json"),
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+		kgo.RecordDeliveryTimeout(kafkaDeliveryTimeout),
+	}
+}
+
+// publishKafka publishes every record as a JSON message, keyed by the value of the key
+// column when there is one, and returns how many were published. Once a record
+// fails, the next ones aren't sent, and the ones already sent are waited for, so the
+// count is exact.
+func publishKafka(fileData inputFile, records <-chan map[string]string, opts ...kgo.Opt) (int, error) {
+	client, err := kgo.NewClient(append(kafkaOptions(fileData.brokers, fileData.topic), opts...)...)
+	if err != nil {
+		return 0, clierr.Wrap(clierr.Usage, fmt.Errorf("kafka: %w", err))
+	}
+	defer client.Close()
+
+	var mu sync.Mutex
+	var sent, published int
+	var failure error
+	jsonFunc, _ := getJSONFunc(false)
+	for record := range records {
+		mu.Lock()
+		failed := failure != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		message := &kgo.Record{Value: []byte(jsonFunc(record))}
+		if fileData.keyColumn != "" {
+			message.Key = []byte(record[fileData.keyColumn])
+		}
+		sent++
+		client.Produce(context.Background(), message, func(_ *kgo.Record, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				published++
+			} else if failure == nil {
+				failure = err
+			}
+		})
+	}
+	if err := client.Flush(context.Background()); err != nil {
+		return published, err
+	}
+	if failure != nil {
+		return published, clierr.Wrap(clierr.Network, fmt.Errorf("kafka: %d of the %d records sent were published, the next ones weren't sent: %w", published, sent, failure))
+	}
+	return published, nil
+}
+
+// writeKafka publishes the records to the topic, see publishKafka.
+func writeKafka(fileData inputFile, writerChannel <-chan map[string]string, done chan<- bool) {
+	slog.Info("publishing the records", "topic", fileData.topic)
+	published, err := publishKafka(fileData, writerChannel)
+	check(err)
+	slog.Info("completed", "published", published)
+	done <- true
+}
+
+// parseBrokers parses a list like "kafka1:9092,kafka2", using the default port when there's none.
+func parseBrokers(value string) []string {
+	brokers := splitList(value)
+	for i, broker := range brokers {
+		if _, _, err := net.SplitHostPort(broker); err != nil {
+			brokers[i] = net.JoinHostPort(broker, "9092")
+		}
+	}
+	return brokers
+}
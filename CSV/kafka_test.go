@@ -0,0 +1,131 @@
+package csv2json
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// consumeKafka reads the n first messages of the topic, as key=value by partition.
+func consumeKafka(t *testing.T, cluster *kfake.Cluster, topic string, n int) map[int32][]string {
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(cluster.ListenAddrs()...),
+		kgo.ConsumeTopics(topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	got := map[int32][]string{}
+	for count := 0; count < n; {
+		fetches := client.PollFetches(ctx)
+		if ctx.Err() != nil {
+			t.Fatalf("consumed %d messages of %d", count, n)
+		}
+		fetches.EachRecord(func(r *kgo.Record) {
+			got[r.Partition] = append(got[r.Partition], string(r.Key)+"="+string(r.Value))
+			count++
+		})
+	}
+	for _, messages := range got {
+		sort.Strings(messages)
+	}
+	return got
+}
+
+// publishRecords publishes the records to the sales topic of the cluster.
+func publishRecords(cluster *kfake.Cluster, records []map[string]string) (int, error) {
+	in := make(chan map[string]string, len(records))
+	for _, record := range records {
+		in <- record
+	}
+	close(in)
+	fileData := inputFile{brokers: cluster.ListenAddrs(), topic: "sales", keyColumn: "ID"}
+	return publishKafka(fileData, in,
+		kgo.RetryBackoffFn(func(int) time.Duration { return 10 * time.Millisecond }),
+		kgo.MetadataMinAge(10*time.Millisecond))
+}
+
+func Test_publishKafka(t *testing.T) {
+	records := []map[string]string{{"ID": "21"}, {"ID": "1"}, {"ID": "21"}}
+	tests := []struct {
+		name    string
+		control func(c *kfake.Cluster)
+	}{
+		{"Published", func(c *kfake.Cluster) {}},
+		{"Leader moved", func(c *kfake.Cluster) {
+			// the first produce request lands on a broker that isn't the leader anymore
+			c.ControlKey(int16(kmsg.Produce), func(req kmsg.Request) (kmsg.Response, error, bool) {
+				return produceError(req, kerr.NotLeaderForPartition.Code), nil, true
+			})
+		}},
+		{"Replica not available", func(c *kfake.Cluster) {
+			// the brokers return it while a follower is down, the leader being fine
+			c.ControlKey(int16(kmsg.Metadata), func(req kmsg.Request) (kmsg.Response, error, bool) {
+				c.DropControl()
+				return nil, nil, false
+			})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := kfake.MustCluster(kfake.NumBrokers(2), kfake.SeedTopics(2, "sales"))
+			defer cluster.Close()
+			tt.control(cluster)
+			published, err := publishRecords(cluster, records)
+			if err != nil || published != len(records) {
+				t.Fatalf("publishKafka() = %d, %v", published, err)
+			}
+			// "21" and "1" hash to the partitions 0 and 1 of 2, like with the Java client
+			want := map[int32][]string{0: {`21={"ID":"21"}`, `21={"ID":"21"}`}, 1: {`1={"ID":"1"}`}}
+			if got := consumeKafka(t, cluster, "sales", len(records)); !reflect.DeepEqual(got, want) {
+				t.Errorf("published %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func Test_publishKafka_failure(t *testing.T) {
+	cluster := kfake.MustCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "sales"))
+	defer cluster.Close()
+	cluster.ControlKey(int16(kmsg.Produce), func(req kmsg.Request) (kmsg.Response, error, bool) {
+		cluster.KeepControl()
+		return produceError(req, kerr.TopicAuthorizationFailed.Code), nil, true
+	})
+	published, err := publishRecords(cluster, []map[string]string{{"ID": "1"}, {"ID": "2"}})
+	if err == nil || published != 0 {
+		t.Fatalf("publishKafka() = %d, %v", published, err)
+	}
+	if !strings.Contains(err.Error(), "0 of the ") {
+		t.Errorf("publishKafka() error = %v, want the number of records published", err)
+	}
+}
+
+// produceError answers the produce request with the error on every partition.
+func produceError(req kmsg.Request, code int16) kmsg.Response {
+	produce := req.(*kmsg.ProduceRequest)
+	resp := produce.ResponseKind().(*kmsg.ProduceResponse)
+	for _, topic := range produce.Topics {
+		respTopic := kmsg.NewProduceResponseTopic()
+		respTopic.Topic = topic.Topic
+		for _, partition := range topic.Partitions {
+			respPartition := kmsg.NewProduceResponseTopicPartition()
+			respPartition.Partition = partition.Partition
+			respPartition.ErrorCode = code
+			respTopic.Partitions = append(respTopic.Partitions, respPartition)
+		}
+		resp.Topics = append(resp.Topics, respTopic)
+	}
+	return resp
+}
@@ -0,0 +1,109 @@
+package csv2json
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maskRule hides the values of one column, written as column:method where method is
+//
+//	hash    the value is replaced by its SHA-256, salted with --mask-salt
+//	redact  the value is replaced by REDACTED
+//	lastN   only the last N characters are kept, like last4
+type maskRule struct {
+	column string
+	method string // hash, redact or last
+	keep   int    // characters kept by the last method
+}
+
+// parseMasks parses a list like "email:hash,ssn:redact,phone:last4".
+func parseMasks(value string) ([]maskRule, error) {
+	var masks []maskRule
+	for _, item := range splitList(value) {
+		i := strings.LastIndex(item, ":")
+		if i < 1 {
+			return nil, fmt.Errorf("mask %q has to be written as column:method", item)
+		}
+		mask := maskRule{column: strings.TrimSpace(item[:i]), method: strings.TrimSpace(item[i+1:])}
+		switch {
+		case mask.method == "hash", mask.method == "redact":
+		case strings.HasPrefix(mask.method, "last"):
+			keep, err := strconv.Atoi(mask.method[len("last"):])
+			if err != nil || keep < 1 {
+				return nil, fmt.Errorf("mask %q has to keep a positive number of characters, like last4", item)
+			}
+			mask.method, mask.keep = "last", keep
+		default:
+			return nil, fmt.Errorf("mask method %q not identified, use hash, redact or lastN", mask.method)
+		}
+		masks = append(masks, mask)
+	}
+	return masks, nil
+}
+
+// maskRecord replaces the values of the masked columns. Empty values are kept as they are.
+func maskRecord(masks []maskRule, salt string, record map[string]string) {
+	for _, mask := range masks {
+		value, ok := record[mask.column]
+		if !ok || value == "" {
+			continue
+		}
+		record[mask.column] = maskValue(mask, salt, value)
+	}
+}
+
+// maskRejected returns the fields, record and errors of a rejected line with the values
+// of the masked columns hidden, the fields by the index of their header, so the rejects
+// file and the logs don't show them either. The record and the line aren't changed.
+func maskRejected(masks []maskRule, salt string, headers, fields []string, record map[string]string, errs []fieldError) ([]string, map[string]string, []fieldError) {
+	if len(masks) == 0 {
+		return fields, record, errs
+	}
+	fields = slices.Clone(fields)
+	for i, header := range headers {
+		if i >= len(fields) || fields[i] == "" {
+			continue
+		}
+		for _, mask := range masks {
+			if mask.column == header {
+				fields[i] = maskValue(mask, salt, fields[i])
+			}
+		}
+	}
+	if record == nil {
+		return fields, nil, errs
+	}
+	masked := maps.Clone(record)
+	maskRecord(masks, salt, masked)
+	errs = slices.Clone(errs)
+	// the messages quote the values they're about
+	for i, e := range errs {
+		if value := record[e.Column]; value != "" && value != masked[e.Column] {
+			message := strings.ReplaceAll(e.Message, strconv.Quote(value), strconv.Quote(masked[e.Column]))
+			errs[i].Message = strings.ReplaceAll(message, value, masked[e.Column])
+		}
+	}
+	return fields, masked, errs
+}
+
+func maskValue(mask maskRule, salt, value string) string {
+	switch mask.method {
+	case "hash":
+		sum := sha256.Sum256([]byte(salt + value))
+		return hex.EncodeToString(sum[:])
+	case "last":
+		hidden := utf8.RuneCountInString(value) - mask.keep
+		if hidden <= 0 {
+			return value
+		}
+		runes := []rune(value)
+		return strings.Repeat("*", hidden) + string(runes[hidden:])
+	}
+	return "REDACTED"
+}
@@ -0,0 +1,97 @@
+package csv2json
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_parseMasks(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []maskRule
+		wantErr bool
+	}{
+		{"No masks", "", nil, false},
+		{"Every method", "email:hash,ssn:redact,phone:last4", []maskRule{
+			{column: "email", method: "hash"},
+			{column: "ssn", method: "redact"},
+			{column: "phone", method: "last", keep: 4},
+		}, false},
+		{"Column with a colon", "a:b:redact", []maskRule{{column: "a:b", method: "redact"}}, false},
+		{"Missing method", "email", nil, true},
+		{"Method not identified", "email:scramble", nil, true},
+		{"Nothing kept", "phone:last0", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMasks(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMasks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMasks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_maskRecord(t *testing.T) {
+	masks, _ := parseMasks("email:hash,ssn:redact,phone:last4,short:last4")
+	record := map[string]string{"email": "ann@example.com", "ssn": "123-45-6789", "phone": "555-123-4567", "short": "12", "city": "Rome"}
+	maskRecord(masks, "", record)
+	want := map[string]string{
+		"email": "71d4f55f72fa128dfb468a1a3901507c804b74316488744d769d7f4b16696476",
+		"ssn":   "REDACTED",
+		"phone": "********4567",
+		"short": "12",
+		"city":  "Rome",
+	}
+	if !reflect.DeepEqual(record, want) {
+		t.Errorf("maskRecord() = %v, want %v", record, want)
+	}
+	if maskValue(maskRule{method: "hash"}, "pepper", "ann@example.com") == record["email"] {
+		t.Errorf("maskValue() should depend on the salt")
+	}
+}
+
+func Test_maskRejected(t *testing.T) {
+	masks, _ := parseMasks("email:hash,ssn:redact")
+	rules, err := parseValidation("email:regex=@example\\.org$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "rejects.ndjson")
+	rejects, err := newRejectWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileData := inputFile{separator: "comma", masks: masks, rules: rules}
+	data := "name,email,ssn\nAnn,ann@example.com,123-45-6789\nBob,bob@example.com\nCid,cid@example.org,987-65-4321\n"
+	writerChannel := make(chan map[string]string)
+	go readCsv(fileData, strings.NewReader(data), rejects, writerChannel)
+	var converted []map[string]string
+	for record := range writerChannel {
+		converted = append(converted, record)
+	}
+	rejects.close()
+
+	if len(converted) != 1 || converted[0]["ssn"] != "REDACTED" {
+		t.Errorf("converted %v", converted)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, raw := range []string{"ann@example.com", "123-45-6789", "bob@example.com"} {
+		if strings.Contains(string(b), raw) {
+			t.Errorf("the rejects show %s: %s", raw, b)
+		}
+	}
+	if lines := strings.Count(string(b), "\n"); lines != 2 || strings.Count(string(b), "REDACTED") != 2 || !strings.Contains(string(b), "Bob") {
+		t.Errorf("rejects = %s", b)
+	}
+}
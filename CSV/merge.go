@@ -0,0 +1,48 @@
+package csv2json
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// expandInputs expands the globs among the arguments into the CSV files they match,
+// keeping the order of the arguments. Remote locations are never expanded.
+func expandInputs(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		if isRemote(arg) || !strings.ContainsAny(arg, "*?[") {
+			files = append(files, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %s: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no file matches %s", arg)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// inputPaths returns every file read by the conversion.
+func inputPaths(fileData inputFile) []string {
+	if len(fileData.sources) > 0 {
+		return fileData.sources
+	}
+	return []string{fileData.filepath}
+}
+
+// sameHeaders reports whether both files have the same columns, whatever their order.
+func sameHeaders(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
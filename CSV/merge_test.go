@@ -0,0 +1,82 @@
+package csv2json
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_expandInputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"daily-2.csv", "daily-1.csv", "other.csv"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("id\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := expandInputs([]string{filepath.Join(dir, "daily-*.csv"), "s3://bucket/*.csv", "single.csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "daily-1.csv"), filepath.Join(dir, "daily-2.csv"), "s3://bucket/*.csv", "single.csv"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandInputs() = %v, want %v", got, want)
+	}
+	if _, err := expandInputs([]string{filepath.Join(dir, "missing-*.csv")}); err == nil {
+		t.Errorf("expandInputs() should fail when a glob matches nothing")
+	}
+}
+
+func Test_sameHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want bool
+	}{
+		{"Same order", []string{"id", "name"}, []string{"id", "name"}, true},
+		{"Other order", []string{"name", "id"}, []string{"id", "name"}, true},
+		{"Other column", []string{"id", "city"}, []string{"id", "name"}, false},
+		{"Extra column", []string{"id", "name", "city"}, []string{"id", "name"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameHeaders(tt.a, tt.b); got != tt.want {
+				t.Errorf("sameHeaders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_processCsvFileMerge(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "day-1.csv")
+	second := filepath.Join(dir, "day-2.csv")
+	os.WriteFile(first, []byte("id,name\n1,Ann\n"), 0644)
+	os.WriteFile(second, []byte("name,id\nBob,2\nEve,3\n"), 0644)
+
+	fileData := inputFile{filepath: first, separator: "comma", sources: []string{first, second}, sourceColumn: "file"}
+	writerChannel := make(chan map[string]string)
+	go processCsvFile(fileData, writerChannel)
+	var got []map[string]string
+	for record := range writerChannel {
+		got = append(got, record)
+	}
+	want := []map[string]string{
+		{"id": "1", "name": "Ann", "file": "day-1.csv"},
+		{"id": "2", "name": "Bob", "file": "day-2.csv"},
+		{"id": "3", "name": "Eve", "file": "day-2.csv"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("processCsvFile() = %v, want %v", got, want)
+	}
+}
+
+func Test_readCsvFileHeadersMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "other.csv")
+	os.WriteFile(path, []byte("id,city\n1,Rome\n"), 0644)
+	_, err := readCsvFile(inputFile{filepath: path, separator: "comma"}, []string{"id", "name"}, &rejectWriter{}, make(chan map[string]string))
+	if err == nil || !strings.Contains(err.Error(), "don't match") {
+		t.Errorf("readCsvFile() error = %v, want headers mismatch", err)
+	}
+}
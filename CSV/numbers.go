@@ -0,0 +1,90 @@
+package csv2json
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// numberFormat reads the numbers written the way some locales do, like 1.234,56, and
+// rewrites them as 1234.56 so they're seen as numbers when sorting, aggregating,
+// validating and inferring types.
+type numberFormat struct {
+	decimal   string         // decimal mark, "," or "."
+	thousands []string       // separators grouping the digits by three, none when empty
+	regex     *regexp.Regexp // matches the numbers written in that format
+}
+
+// thousandsSeparators are the values of --thousands-separator. space also takes the
+// no-break spaces used by French and Swiss formats.
+var thousandsSeparators = map[string][]string{
+	"none":  nil,
+	".":     {"."},
+	",":     {","},
+	"'":     {"'"},
+	"space": {" ", "\u00a0", "\u202f"},
+}
+
+// newNumberFormat returns the format of --decimal-comma and --thousands-separator. The
+// thousands separator defaults to . with a decimal comma, and to none otherwise.
+func newNumberFormat(decimalComma bool, thousands string) (*numberFormat, error) {
+	decimal := "."
+	if decimalComma {
+		decimal = ","
+	}
+	if thousands == "" {
+		thousands = "none"
+		if decimalComma {
+			thousands = "."
+		}
+	}
+	separators, ok := thousandsSeparators[thousands]
+	if !ok {
+		return nil, errors.New("thousands-separator has to be either ., ,, ', space or none")
+	}
+	if thousands == decimal {
+		return nil, fmt.Errorf("thousands-separator can't be the decimal mark %s", decimal)
+	}
+
+	digits := `[0-9]+`
+	if len(separators) > 0 {
+		quoted := make([]string, len(separators))
+		for i, separator := range separators {
+			quoted[i] = regexp.QuoteMeta(separator)
+		}
+		// either plain digits, or digits grouped by three with the same separator
+		group := `(?:` + strings.Join(quoted, "|") + `)`
+		digits = `(?:[0-9]+|[0-9]{1,3}(?:` + group + `[0-9]{3})+)`
+	}
+	regex := regexp.MustCompile(`^-?` + digits + `(?:` + regexp.QuoteMeta(decimal) + `[0-9]+)?$`)
+	return &numberFormat{decimal: decimal, thousands: separators, regex: regex}, nil
+}
+
+// normalize returns the value with a dot as decimal mark and without the thousands
+// separators, or the value as it is when it isn't a number in that format.
+func (f *numberFormat) normalize(value string) string {
+	trimmed := strings.TrimSpace(value)
+	if !f.regex.MatchString(trimmed) {
+		return value
+	}
+	for _, separator := range f.thousands {
+		trimmed = strings.ReplaceAll(trimmed, separator, "")
+	}
+	return strings.Replace(trimmed, f.decimal, ".", 1)
+}
+
+// normalizeNumbers rewrites the numbers of the record, leaving the date columns to
+// normalizeDates so dates like 01.02.2009 aren't touched.
+func normalizeNumbers(fileData inputFile, record map[string]string) {
+	if fileData.numbers == nil {
+		return
+	}
+	for column, value := range record {
+		if value == "" || slices.Contains(fileData.dateColumns, column) {
+			continue
+		}
+		record[column] = fileData.numbers.normalize(value)
+	}
+}
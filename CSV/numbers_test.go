@@ -0,0 +1,65 @@
+package csv2json
+
+import (
+	"reflect"
+	"testing"
+)
+
+func mustNumberFormat(decimalComma bool, thousands string) *numberFormat {
+	format, err := newNumberFormat(decimalComma, thousands)
+	if err != nil {
+		panic(err)
+	}
+	return format
+}
+
+func Test_numberFormat_normalize(t *testing.T) {
+	tests := []struct {
+		name         string
+		decimalComma bool
+		thousands    string
+		value        string
+		want         string
+	}{
+		{"European number", true, "", "1.234,56", "1234.56"},
+		{"Negative European number", true, "", "-1.234.567,8", "-1234567.8"},
+		{"Decimal comma only", true, "", "3,5", "3.5"},
+		{"Ungrouped digits", true, "", "1234,5", "1234.5"},
+		{"Grouped integer", true, "", "1.234", "1234"},
+		{"Wrong grouping kept", true, "", "1.23,4", "1.23,4"},
+		{"Mixed separators kept", true, "space", "1 234.567,8", "1 234.567,8"},
+		{"Dotted date kept", true, "", "01.02.2009", "01.02.2009"},
+		{"Text kept", true, "", "Visa", "Visa"},
+		{"Swiss number", false, "'", "1'234.5", "1234.5"},
+		{"French number", true, "space", "1 234,5", "1234.5"},
+		{"English number", false, ",", "1,234,567.89", "1234567.89"},
+		{"Plain number", false, ",", "12.5", "12.5"},
+		{"Surrounding spaces", true, "", " 2,5 ", "2.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustNumberFormat(tt.decimalComma, tt.thousands).normalize(tt.value); got != tt.want {
+				t.Errorf("normalize(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_newNumberFormat(t *testing.T) {
+	if _, err := newNumberFormat(false, "."); err == nil {
+		t.Error("newNumberFormat() accepted a dot as thousands separator and decimal mark")
+	}
+	if _, err := newNumberFormat(true, "_"); err == nil {
+		t.Error("newNumberFormat() accepted an unknown thousands separator")
+	}
+}
+
+func Test_normalizeNumbers(t *testing.T) {
+	fileData := inputFile{numbers: mustNumberFormat(true, ""), dateColumns: []string{"day"}}
+	record := map[string]string{"price": "1.200,50", "day": "1.200", "name": "Betina", "empty": ""}
+	normalizeNumbers(fileData, record)
+	want := map[string]string{"price": "1200.50", "day": "1.200", "name": "Betina", "empty": ""}
+	if !reflect.DeepEqual(record, want) {
+		t.Errorf("normalizeNumbers() = %v, want %v", record, want)
+	}
+}
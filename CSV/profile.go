@@ -0,0 +1,172 @@
+package csv2json
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// profileDistinctLimit is the number of distinct values counted per column. Past it,
+// new values aren't counted anymore, so the distinct count is a lower bound.
+var profileDistinctLimit = 100000
+
+// columnStats gathers the statistics of one column for --profile.
+type columnStats struct {
+	columnProfile
+	values   map[string]int64 // occurrences of every distinct value
+	capped   bool             // whether some values weren't counted, see profileDistinctLimit
+	numbers  int64            // values that are numbers
+	sum      float64
+	min, max string // smallest and largest values, compared as numbers when both are
+}
+
+func (c *columnStats) observe(value string) {
+	c.columnProfile.observe(value)
+	if value == "" {
+		return
+	}
+	if _, ok := c.values[value]; ok || len(c.values) < profileDistinctLimit {
+		c.values[value]++
+	} else {
+		c.capped = true
+	}
+	if numberRegex.MatchString(value) {
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			c.numbers++
+			c.sum += number
+		}
+	}
+	if c.min == "" || compareValues(value, c.min) < 0 {
+		c.min = value
+	}
+	if c.max == "" || compareValues(value, c.max) > 0 {
+		c.max = value
+	}
+}
+
+// valueCount is one of the most frequent values of a column.
+type valueCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// columnReport is the profile of a column, as printed or written as JSON.
+type columnReport struct {
+	Column          string       `json:"column"`
+	Type            string       `json:"type"`
+	Count           int64        `json:"count"`
+	Nulls           int64        `json:"nulls"`
+	NullPercentage  float64      `json:"null_percentage"`
+	Distinct        int          `json:"distinct"`
+	DistinctAtLeast bool         `json:"distinct_at_least,omitempty"` // the distinct count is a lower bound
+	Min             string       `json:"min,omitempty"`
+	Max             string       `json:"max,omitempty"`
+	Mean            *float64     `json:"mean,omitempty"` // only for numeric columns
+	Top             []valueCount `json:"top"`
+}
+
+func (c *columnStats) report(column string, top int) columnReport {
+	report := columnReport{
+		Column:          column,
+		Type:            c.inferredType(),
+		Count:           c.present,
+		Nulls:           c.empty,
+		Distinct:        len(c.values),
+		DistinctAtLeast: c.capped,
+		Min:             c.min,
+		Max:             c.max,
+		Top:             []valueCount{},
+	}
+	if c.present > 0 {
+		report.NullPercentage = math.Round(float64(c.empty)/float64(c.present)*10000) / 100
+	}
+	if (report.Type == "integer" || report.Type == "number") && c.numbers > 0 {
+		mean := c.sum / float64(c.numbers)
+		report.Mean = &mean
+	}
+	for value, count := range c.values {
+		report.Top = append(report.Top, valueCount{value, count})
+	}
+	// The most frequent values first, ties in the order of the values so the output is stable
+	sort.Slice(report.Top, func(i, j int) bool {
+		if report.Top[i].Count != report.Top[j].Count {
+			return report.Top[i].Count > report.Top[j].Count
+		}
+		return report.Top[i].Value < report.Top[j].Value
+	})
+	if len(report.Top) > top {
+		report.Top = report.Top[:top]
+	}
+	return report
+}
+
+// profileRecords gathers the statistics of every column of the records, sorted by
+// the name of the columns.
+func profileRecords(records <-chan map[string]string, top int) []columnReport {
+	columns := map[string]*columnStats{}
+	for record := range records {
+		for column, value := range record {
+			stats, ok := columns[column]
+			if !ok {
+				stats = &columnStats{
+					columnProfile: columnProfile{integer: true, number: true, boolean: true, dateTime: true},
+					values:        map[string]int64{},
+				}
+				columns[column] = stats
+			}
+			stats.observe(value)
+		}
+	}
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	reports := make([]columnReport, len(names))
+	for i, name := range names {
+		reports[i] = columns[name].report(name, top)
+	}
+	return reports
+}
+
+// printProfile writes the profile as a table, or as JSON.
+func printProfile(w io.Writer, reports []columnReport, format string) error {
+	if format == "json" {
+		data, err := json.MarshalIndent(reports, "", "   ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "COLUMN\tTYPE\tNULL %\tDISTINCT\tMIN\tMAX\tMEAN\tTOP VALUES")
+	for _, r := range reports {
+		distinct := strconv.Itoa(r.Distinct)
+		if r.DistinctAtLeast {
+			distinct = ">=" + distinct
+		}
+		mean := ""
+		if r.Mean != nil {
+			mean = strconv.FormatFloat(*r.Mean, 'f', 2, 64)
+		}
+		top := make([]string, len(r.Top))
+		for i, value := range r.Top {
+			top[i] = fmt.Sprintf("%s (%d)", value.Value, value.Count)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%.2f\t%s\t%s\t%s\t%s\t%s\n", r.Column, r.Type, r.NullPercentage, distinct, r.Min, r.Max, mean, strings.Join(top, ", "))
+	}
+	return table.Flush()
+}
+
+// writeProfile prints the profile of the records instead of writing them.
+func writeProfile(fileData inputFile, writerChannel <-chan map[string]string, done chan<- bool) {
+	check(printProfile(os.Stdout, profileRecords(writerChannel, fileData.profileTop), fileData.profile))
+	done <- true
+}
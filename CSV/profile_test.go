@@ -0,0 +1,60 @@
+package csv2json
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_profileRecords(t *testing.T) {
+	records := make(chan map[string]string)
+	go func() {
+		defer close(records)
+		for _, record := range []map[string]string{
+			{"city": "Rome", "price": "10"},
+			{"city": "Paris", "price": "2.5"},
+			{"city": "Rome", "price": ""},
+			{"city": "", "price": "7.5"},
+		} {
+			records <- record
+		}
+	}()
+
+	mean := 20.0 / 3
+	want := []columnReport{
+		{Column: "city", Type: "string", Count: 4, Nulls: 1, NullPercentage: 25, Distinct: 2, Min: "Paris", Max: "Rome",
+			Top: []valueCount{{"Rome", 2}}},
+		{Column: "price", Type: "number", Count: 4, Nulls: 1, NullPercentage: 25, Distinct: 3, Min: "2.5", Max: "10", Mean: &mean,
+			Top: []valueCount{{"10", 1}}},
+	}
+	got := profileRecords(records, 1)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("profileRecords() = %+v, want %+v", got, want)
+	}
+
+	var out bytes.Buffer
+	if err := printProfile(&out, got, "text"); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 3 || !strings.Contains(lines[2], "6.67") {
+		t.Errorf("printProfile() = %q", out.String())
+	}
+}
+
+func Test_profileDistinctLimit(t *testing.T) {
+	defer func(limit int) { profileDistinctLimit = limit }(profileDistinctLimit)
+	profileDistinctLimit = 2
+
+	records := make(chan map[string]string)
+	go func() {
+		defer close(records)
+		for _, value := range []string{"a", "b", "c", "a"} {
+			records <- map[string]string{"col": value}
+		}
+	}()
+	got := profileRecords(records, 5)[0]
+	if got.Distinct != 2 || !got.DistinctAtLeast || got.Top[0] != (valueCount{"a", 2}) {
+		t.Errorf("profileRecords() = %+v, want 2 distinct values at least, a counted twice", got)
+	}
+}
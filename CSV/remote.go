@@ -0,0 +1,257 @@
+package csv2json
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"clikit/clierr"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+)
+
+// Remote locations are object storage URLs, like s3://bucket/key or gs://bucket/key,
+// read and written with the SDKs of the providers, taking the credentials the way
+// their command line tools do:
+//
+//	s3://  the environment, ~/.aws/config and ~/.aws/credentials with AWS_PROFILE, SSO,
+//	       and the roles of the instance or the container. AWS_REGION defaults to
+//	       us-east-1, and AWS_ENDPOINT_URL points to an S3 compatible store
+//	gs://  the application default credentials: GOOGLE_APPLICATION_CREDENTIALS, the
+//	       ones of "gcloud auth application-default login" or the service account of
+//	       the instance, else GOOGLE_OAUTH_ACCESS_TOKEN when it's set
+//
+// Both input and output are streamed, nothing is staged on the local disk.
+
+// s3PartSize is the size of each part of an S3 multipart upload. S3 requires
+// every part but the last one to be at least 5MB.
+var s3PartSize int64 = 8 << 20
+
+func isRemote(location string) bool {
+	return strings.HasPrefix(location, "s3://") || strings.HasPrefix(location, "gs://")
+}
+
+// splitRemote splits s3://bucket/some/key into its scheme, bucket and key.
+func splitRemote(location string) (scheme, bucket, key string, err error) {
+	scheme, rest, _ := strings.Cut(location, "://")
+	bucket, key, _ = strings.Cut(rest, "/")
+	if bucket == "" || key == "" {
+		return "", "", "", fmt.Errorf("%s has to be written as %s://bucket/key", location, scheme)
+	}
+	return scheme, bucket, key, nil
+}
+
+// openInput opens the CSV data, either a local file or a remote object.
+func openInput(location string) (io.ReadCloser, error) {
+	if !isRemote(location) {
+		return os.Open(location)
+	}
+	scheme, bucket, key, err := splitRemote(location)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if scheme == "s3" {
+		client, err := newS3Client(ctx, bucket)
+		if err != nil {
+			return nil, err
+		}
+		object, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+		if err != nil {
+			return nil, remoteError(location, err)
+		}
+		return object.Body, nil
+	}
+
+	client, err := newGCSClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := client.Bucket(bucket).Object(key).NewReader(ctx)
+	if err != nil {
+		client.Close()
+		return nil, remoteError(location, err)
+	}
+	return &gcsReader{reader, client}, nil
+}
+
+// openOutput creates the output, either a local file or a remote object. Remote
+// objects are only complete once Close returns without an error, and never once a
+// Write failed.
+func openOutput(location string) (io.WriteCloser, error) {
+	if !isRemote(location) {
+		return os.Create(location)
+	}
+	scheme, bucket, key, err := splitRemote(location)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if scheme == "s3" {
+		client, err := newS3Client(ctx, bucket)
+		if err != nil {
+			return nil, err
+		}
+		// The uploader sends small outputs with a single PUT, and bigger ones with a
+		// multipart upload that it aborts when it fails
+		uploader := manager.NewUploader(client, func(u *manager.Uploader) {
+			u.PartSize = s3PartSize
+			u.Concurrency = 1
+		})
+		return startUpload(location, func(ctx context.Context, r io.Reader) error {
+			_, err := uploader.Upload(ctx, &s3.PutObjectInput{Bucket: aws.String(bucket), Key: aws.String(key), Body: r})
+			return err
+		}), nil
+	}
+
+	client, err := newGCSClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return startUpload(location, func(ctx context.Context, r io.Reader) error {
+		defer client.Close()
+		// the object isn't created when the context is canceled before closing the writer
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		w := client.Bucket(bucket).Object(key).NewWriter(ctx)
+		if _, err := io.Copy(w, r); err != nil {
+			cancel()
+			w.Close()
+			return err
+		}
+		return w.Close()
+	}), nil
+}
+
+// newS3Client returns a client for the bucket, with the credentials and the region
+// found the way the AWS CLI finds them.
+func newS3Client(ctx context.Context, bucket string) (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, clierr.Wrap(clierr.Validation, fmt.Errorf("loading the AWS config: %w", err))
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		// The certificates of the virtual hosts don't cover the buckets with a dot, and
+		// the S3 compatible stores are addressed by path
+		o.UsePathStyle = strings.Contains(bucket, ".") || o.BaseEndpoint != nil
+	}), nil
+}
+
+// newGCSClient returns a client with the application default credentials, or
+// GOOGLE_OAUTH_ACCESS_TOKEN when it's set.
+func newGCSClient(ctx context.Context) (*storage.Client, error) {
+	var opts []option.ClientOption
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		opts = append(opts, option.WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})))
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, clierr.Wrap(clierr.Validation, fmt.Errorf("creating the GCS client: %w", err))
+	}
+	return client, nil
+}
+
+// remoteError is the error of a call to the object storage, a network one.
+func remoteError(location string, err error) error {
+	return clierr.Wrap(clierr.Network, fmt.Errorf("%s: %w", location, err))
+}
+
+// gcsReader closes the client along with the object.
+type gcsReader struct {
+	*storage.Reader
+	client *storage.Client
+}
+
+func (r *gcsReader) Close() error {
+	err := r.Reader.Close()
+	r.client.Close()
+	return err
+}
+
+// errUploadAborted is the error of the uploads still running when csv2json exits.
+var errUploadAborted = errors.New("upload aborted")
+
+// remoteUpload streams what's written to an upload running in its own goroutine,
+// which reads it from a pipe. The upload only completes when the pipe is closed
+// without an error, so a failed Write, or an upload aborted on exit, never leaves an
+// object holding part of the output.
+type remoteUpload struct {
+	location string
+	pipe     *io.PipeWriter
+	result   chan error
+	err      error // the first error of Write, failing the upload for good
+}
+
+// uploads are the remote uploads in progress, aborted by exitGracefully.
+var uploads = struct {
+	sync.Mutex
+	running map[*remoteUpload]bool
+}{running: map[*remoteUpload]bool{}}
+
+func startUpload(location string, upload func(ctx context.Context, r io.Reader) error) *remoteUpload {
+	reader, writer := io.Pipe()
+	u := &remoteUpload{location: location, pipe: writer, result: make(chan error, 1)}
+	go func() {
+		err := upload(context.Background(), reader)
+		// the writes waiting for the upload fail with its error
+		reader.CloseWithError(err)
+		u.result <- err
+	}()
+	uploads.Lock()
+	uploads.running[u] = true
+	uploads.Unlock()
+	return u
+}
+
+func (u *remoteUpload) Write(p []byte) (int, error) {
+	if u.err != nil {
+		return 0, u.err
+	}
+	n, err := u.pipe.Write(p)
+	if err != nil {
+		u.err = remoteError(u.location, err)
+	}
+	return n, u.err
+}
+
+// Close completes the upload, unless a Write failed.
+func (u *remoteUpload) Close() error {
+	uploads.Lock()
+	delete(uploads.running, u)
+	uploads.Unlock()
+	if u.err != nil {
+		u.pipe.CloseWithError(u.err)
+		<-u.result
+		return u.err
+	}
+	u.pipe.Close()
+	if err := <-u.result; err != nil {
+		return remoteError(u.location, err)
+	}
+	return nil
+}
+
+// abortUploads aborts the uploads in progress, the multipart uploads being dropped
+// instead of leaving their parts behind.
+func abortUploads() {
+	uploads.Lock()
+	defer uploads.Unlock()
+	for u := range uploads.running {
+		u.pipe.CloseWithError(errUploadAborted)
+		<-u.result
+		delete(uploads.running, u)
+	}
+}
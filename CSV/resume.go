@@ -0,0 +1,188 @@
+package csv2json
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"clikit/clierr"
+)
+
+// checkpointEvery is the number of CSV lines converted between two checkpoints.
+var checkpointEvery = 10000
+
+// resumeState is persisted next to the JSON file while a --resume conversion runs.
+// It holds everything needed to continue the conversion after an interruption.
+type resumeState struct {
+	Input         string `json:"input"`          // absolute path of the CSV file
+	InputOffset   int64  `json:"input_offset"`   // byte offset of the next CSV line to read
+	Line          int    `json:"line"`           // number of CSV lines before InputOffset
+	Records       int64  `json:"records"`        // number of records already written
+	OutputOffset  int64  `json:"output_offset"`  // size of the JSON file at the checkpoint
+	RejectsOffset int64  `json:"rejects_offset"` // size of the rejects file at the checkpoint
+}
+
+func loadResumeState(path string) (*resumeState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) { // Nothing to resume, so we start from scratch
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	state := &resumeState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("state file %s is corrupted: %w", path, err)
+	}
+	return state, nil
+}
+
+// save writes the state to a temporary file first, so an interruption while
+// saving never leaves a half written state behind.
+func (s *resumeState) save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// openForResume opens a file for writing, dropping everything written after offset.
+func openForResume(path string, offset int64) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func fileOffset(f *os.File) int64 {
+	offset, err := f.Seek(0, io.SeekCurrent)
+	check(err)
+	return offset
+}
+
+// convertResumable converts the CSV file in a single loop, instead of using the reader
+// and writer go-routines, so the input and output offsets saved in every checkpoint
+// always match each other.
+func convertResumable(fileData inputFile) {
+	jsonPath := outputLocation(fileData)
+	statePath := jsonPath + ".state"
+
+	input, err := filepath.Abs(fileData.filepath)
+	check(err)
+	state, err := loadResumeState(statePath)
+	check(err)
+	if state != nil && state.Input != input {
+		exitGracefully(clierr.Errorf(clierr.Validation, "state file %s belongs to %s, not %s", statePath, state.Input, input))
+	}
+
+	file, err := os.Open(fileData.filepath)
+	check(err)
+	defer file.Close()
+
+	// The headers are always at the top of the file, even when resuming
+	headerReader := newCsvReader(fileData, file)
+	headers, err := headerReader.Read()
+	check(err)
+	headers = prepareHeaders(fileData, headers)
+	fileData.counters.setHeaders(headers)
+
+	encoder, err := getEncoder(fileData)
+	check(err)
+	if state == nil {
+		slog.Info("writing the file", "format", encoder.name)
+		headerLine, _ := headerReader.FieldPos(len(headers) - 1)
+		state = &resumeState{Input: input, InputOffset: headerReader.InputOffset(), Line: headerLine}
+	} else {
+		slog.Info("resuming the file", "format", encoder.name, "records", state.Records)
+	}
+
+	output, err := openForResume(jsonPath, state.OutputOffset)
+	check(err)
+	defer output.Close()
+	if state.OutputOffset == 0 {
+		_, err = output.WriteString(encoder.header)
+		check(err)
+	}
+
+	rejects := &rejectWriter{}
+	if fileData.rejectsPath != "" {
+		f, err := openForResume(fileData.rejectsPath, state.RejectsOffset)
+		check(err)
+		rejects = &rejectWriter{file: f, encoder: json.NewEncoder(f)}
+	}
+	defer rejects.close()
+
+	// Continuing from the last checkpoint, offsets and lines given by the reader are relative to it
+	_, err = file.Seek(state.InputOffset, io.SeekStart)
+	check(err)
+	reader := newCsvReader(fileData, file)
+	startOffset, startLine := state.InputOffset, state.Line
+
+	checkpoint := func(lastLine int) {
+		check(output.Sync())
+		state.OutputOffset = fileOffset(output)
+		if rejects.file != nil {
+			check(rejects.file.Sync())
+			state.RejectsOffset = fileOffset(rejects.file)
+		}
+		state.InputOffset = startOffset + reader.InputOffset()
+		state.Line = startLine + lastLine
+		check(state.save(statePath))
+	}
+
+	pending := 0
+	for {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			exitGracefully(err)
+		}
+		lineNumber, _ := reader.FieldPos(0)
+		record, ok := convertLine(fileData, headers, line, startLine+lineNumber, rejects)
+		if ok {
+			if state.Records > 0 {
+				_, err = output.WriteString(encoder.separator)
+				check(err)
+			}
+			data, err := encoder.encode(record)
+			check(err)
+			_, err = output.WriteString(data)
+			check(err)
+			state.Records++
+			fileData.counters.wrote()
+		}
+
+		if pending++; pending >= checkpointEvery {
+			lastLine, _ := reader.FieldPos(len(line) - 1)
+			checkpoint(lastLine)
+			pending = 0
+		}
+	}
+
+	_, err = output.WriteString(encoder.footer)
+	check(err)
+	fileData.counters.addBytes(reader.InputOffset())
+	// The conversion is complete, there is nothing left to resume
+	if err := os.Remove(statePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		exitGracefully(err)
+	}
+	slog.Info("completed")
+}
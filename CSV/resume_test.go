@@ -0,0 +1,57 @@
+package csv2json
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_convertResumable(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "test.csv")
+	jsonPath := filepath.Join(dir, "test.json")
+	check(os.WriteFile(csvPath, []byte("COL1,COL2\n1,2\n3,4\n5,6\n"), 0644))
+	input, err := filepath.Abs(csvPath)
+	check(err)
+
+	want := `[{"COL1":"1","COL2":"2"},{"COL1":"3","COL2":"4"},{"COL1":"5","COL2":"6"}]`
+
+	tests := []struct {
+		name   string
+		output string       // What the JSON file had when the previous run stopped
+		state  *resumeState // The state left by the previous run, if any
+	}{
+		{"Fresh conversion", "", nil},
+		{"Interrupted after the first record", `[{"COL1":"1","COL2":"2"},{"COL1":"3"`, &resumeState{
+			Input:        input,
+			InputOffset:  14,
+			Line:         2,
+			Records:      1,
+			OutputOffset: int64(len(`[{"COL1":"1","COL2":"2"}`)),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(every int) { checkpointEvery = every }(checkpointEvery)
+			checkpointEvery = 1
+			os.Remove(jsonPath)
+			if tt.state != nil {
+				check(os.WriteFile(jsonPath, []byte(tt.output), 0644))
+				check(tt.state.save(jsonPath + ".state"))
+			}
+
+			convertResumable(inputFile{filepath: csvPath, separator: "comma", resume: true})
+
+			got, err := os.ReadFile(jsonPath)
+			if err != nil {
+				t.Fatalf("convertResumable(), Output file got error: %v", err)
+			}
+			if string(got) != want {
+				t.Errorf("convertResumable() = %v, want %v", string(got), want)
+			}
+			if _, err := os.Stat(jsonPath + ".state"); !os.IsNotExist(err) {
+				t.Errorf("convertResumable() left the state file behind")
+			}
+		})
+	}
+}
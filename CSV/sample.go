@@ -0,0 +1,52 @@
+package csv2json
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// newSampleRand returns the random source of the sampling, seeded with the given seed
+// so a sample can be reproduced, or with the current time when the seed is 0.
+func newSampleRand(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// sampleFraction keeps every record with the given probability, so about that fraction
+// of the records goes through.
+func sampleFraction(in <-chan map[string]string, out chan<- map[string]string, fraction float64, random *rand.Rand) {
+	defer close(out)
+	for record := range in {
+		if random.Float64() < fraction {
+			out <- record
+		}
+	}
+}
+
+// sampleRows keeps a uniform sample of size records with reservoir sampling, so the
+// number of records doesn't have to be known in advance. The sample is sent once the
+// input is over, in the order the records were read.
+func sampleRows(in <-chan map[string]string, out chan<- map[string]string, size int, random *rand.Rand) {
+	defer close(out)
+	type sampled struct {
+		index  int
+		record map[string]string
+	}
+	reservoir := make([]sampled, 0, size)
+	index := 0
+	for record := range in {
+		if len(reservoir) < size {
+			reservoir = append(reservoir, sampled{index, record})
+		} else if j := random.Intn(index + 1); j < size {
+			reservoir[j] = sampled{index, record}
+		}
+		index++
+	}
+	sort.Slice(reservoir, func(i, j int) bool { return reservoir[i].index < reservoir[j].index })
+	for _, s := range reservoir {
+		out <- s.record
+	}
+}
@@ -0,0 +1,64 @@
+package csv2json
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func sampleInput(n int) <-chan map[string]string {
+	in := make(chan map[string]string)
+	go func() {
+		defer close(in)
+		for i := 0; i < n; i++ {
+			in <- map[string]string{"id": strconv.Itoa(i)}
+		}
+	}()
+	return in
+}
+
+func collectIDs(out <-chan map[string]string) []int {
+	var ids []int
+	for record := range out {
+		id, _ := strconv.Atoi(record["id"])
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func Test_sampleRows(t *testing.T) {
+	out := make(chan map[string]string)
+	go sampleRows(sampleInput(1000), out, 10, newSampleRand(1))
+	ids := collectIDs(out)
+	if len(ids) != 10 {
+		t.Fatalf("sampleRows() kept %d records, want 10", len(ids))
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Errorf("sampleRows() = %v, want the records in the order they were read", ids)
+			break
+		}
+	}
+
+	// The same seed gives the same sample
+	again := make(chan map[string]string)
+	go sampleRows(sampleInput(1000), again, 10, newSampleRand(1))
+	if got := collectIDs(again); !reflect.DeepEqual(got, ids) {
+		t.Errorf("sampleRows() = %v with the same seed, want %v", got, ids)
+	}
+
+	// Fewer records than the sample size are all kept
+	small := make(chan map[string]string)
+	go sampleRows(sampleInput(3), small, 10, newSampleRand(1))
+	if got := collectIDs(small); !reflect.DeepEqual(got, []int{0, 1, 2}) {
+		t.Errorf("sampleRows() = %v, want [0 1 2]", got)
+	}
+}
+
+func Test_sampleFraction(t *testing.T) {
+	out := make(chan map[string]string)
+	go sampleFraction(sampleInput(10000), out, 0.1, newSampleRand(1))
+	if n := len(collectIDs(out)); n < 900 || n > 1100 {
+		t.Errorf("sampleFraction() kept %d records, want about 1000", n)
+	}
+}
@@ -0,0 +1,190 @@
+package csv2json
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The values of the records are always written as JSON strings, so the inferred
+// types are described with patterns and formats the strings have to match.
+var (
+	integerPattern = `-?[0-9]+`
+	numberPattern  = `-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?`
+	booleanPattern = `true|false`
+
+	integerRegex = regexp.MustCompile(`^(` + integerPattern + `)$`)
+	numberRegex  = regexp.MustCompile(`^(` + numberPattern + `)$`)
+	booleanRegex = regexp.MustCompile(`^(` + booleanPattern + `)$`)
+)
+
+// columnProfile keeps what was seen in the values of one column.
+type columnProfile struct {
+	present  int64 // records having the column
+	empty    int64 // records where the column is empty
+	integer  bool  // whether every non empty value is an integer
+	number   bool  // whether every non empty value is a number
+	boolean  bool  // whether every non empty value is true or false
+	dateTime bool  // whether every non empty value is an RFC3339 date
+}
+
+func (c *columnProfile) observe(value string) {
+	c.present++
+	if value == "" {
+		c.empty++
+		return
+	}
+	c.integer = c.integer && integerRegex.MatchString(value)
+	c.number = c.number && numberRegex.MatchString(value)
+	c.boolean = c.boolean && booleanRegex.MatchString(value)
+	if c.dateTime {
+		_, err := time.Parse(time.RFC3339, value)
+		c.dateTime = err == nil
+	}
+}
+
+// inferredType is the most specific type matching every non empty value.
+func (c *columnProfile) inferredType() string {
+	switch {
+	case c.present == c.empty:
+		return "string"
+	case c.integer:
+		return "integer"
+	case c.number:
+		return "number"
+	case c.boolean:
+		return "boolean"
+	case c.dateTime:
+		return "date-time"
+	}
+	return "string"
+}
+
+// schemaBuilder infers a JSON Schema from the records written to the output.
+type schemaBuilder struct {
+	records int64
+	columns map[string]*columnProfile
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{columns: map[string]*columnProfile{}}
+}
+
+func (b *schemaBuilder) observe(record map[string]string) {
+	b.records++
+	for column, value := range record {
+		profile, ok := b.columns[column]
+		if !ok {
+			profile = &columnProfile{integer: true, number: true, boolean: true, dateTime: true}
+			b.columns[column] = profile
+		}
+		profile.observe(value)
+	}
+}
+
+// collectSchema passes the records through, observing them on their way to the writer.
+func collectSchema(b *schemaBuilder, records <-chan map[string]string) <-chan map[string]string {
+	if b == nil {
+		return records
+	}
+	observed := make(chan map[string]string)
+	go func() {
+		defer close(observed)
+		for record := range records {
+			b.observe(record)
+			observed <- record
+		}
+	}()
+	return observed
+}
+
+// schema returns the JSON Schema of a record, or of the array of records when array is set.
+// Columns present in every record are required, and the ones that were never empty
+// can't be empty strings.
+func (b *schemaBuilder) schema(array bool) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for column, profile := range b.columns {
+		properties[column] = columnSchema(profile)
+		if profile.present == b.records {
+			required = append(required, column)
+		}
+	}
+	slices.Sort(required)
+	record := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+	schema := record
+	if array {
+		schema = map[string]interface{}{"type": "array", "items": record}
+	}
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	return schema
+}
+
+func columnSchema(profile *columnProfile) map[string]interface{} {
+	nullable := profile.empty > 0
+	inferred := profile.inferredType()
+	description := "inferred type: " + inferred
+	if nullable {
+		description += ", may be empty"
+	}
+	schema := map[string]interface{}{"type": "string", "description": description}
+	pattern := ""
+	switch inferred {
+	case "integer":
+		pattern = integerPattern
+	case "number":
+		pattern = numberPattern
+	case "boolean":
+		pattern = booleanPattern
+	case "date-time":
+		if nullable {
+			schema["anyOf"] = []interface{}{
+				map[string]interface{}{"format": "date-time"},
+				map[string]interface{}{"const": ""},
+			}
+		} else {
+			schema["format"] = "date-time"
+		}
+	default:
+		if !nullable {
+			schema["minLength"] = 1
+		}
+	}
+	if pattern != "" {
+		if nullable {
+			schema["pattern"] = "^(" + pattern + ")?$"
+		} else {
+			schema["pattern"] = "^(" + pattern + ")$"
+		}
+	}
+	return schema
+}
+
+// schemaLocation is where the schema of an output is written, like data.schema.json for data.json.
+func schemaLocation(location string) string {
+	return strings.TrimSuffix(location, filepath.Ext(location)) + ".schema.json"
+}
+
+// writeSchema writes the schema next to the output, which may be a remote location.
+func writeSchema(b *schemaBuilder, location string, array bool) error {
+	data, err := json.MarshalIndent(b.schema(array), "", "   ")
+	if err != nil {
+		return err
+	}
+	f, err := openOutput(schemaLocation(location))
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
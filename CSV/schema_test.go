@@ -0,0 +1,80 @@
+package csv2json
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_columnProfile(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   string
+	}{
+		{"Integers", []string{"1", "-20", ""}, "integer"},
+		{"Numbers", []string{"1", "2.5", "1e3"}, "number"},
+		{"Booleans", []string{"true", "false"}, "boolean"},
+		{"Dates", []string{"2009-01-02T06:17:00Z", "2009-01-02T04:53:00+01:00"}, "date-time"},
+		{"Mixed", []string{"1", "one"}, "string"},
+		{"Always empty", []string{"", ""}, "string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newSchemaBuilder()
+			for _, value := range tt.values {
+				b.observe(map[string]string{"col": value})
+			}
+			if got := b.columns["col"].inferredType(); got != tt.want {
+				t.Errorf("inferredType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_schemaBuilder(t *testing.T) {
+	b := newSchemaBuilder()
+	b.observe(map[string]string{"id": "1", "name": "Ann", "score": ""})
+	b.observe(map[string]string{"id": "2", "name": "Bob", "score": "7.5", "extra": "x"})
+
+	got := b.schema(false)
+	want := map[string]interface{}{
+		"$schema":  "https://json-schema.org/draft/2020-12/schema",
+		"type":     "object",
+		"required": []string{"id", "name", "score"},
+		"properties": map[string]interface{}{
+			"id":    map[string]interface{}{"type": "string", "description": "inferred type: integer", "pattern": "^(-?[0-9]+)$"},
+			"name":  map[string]interface{}{"type": "string", "description": "inferred type: string", "minLength": 1},
+			"score": map[string]interface{}{"type": "string", "description": "inferred type: number, may be empty", "pattern": `^(-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?)?$`},
+			"extra": map[string]interface{}{"type": "string", "description": "inferred type: string", "minLength": 1},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("schema() = %v, want %v", got, want)
+	}
+	if array := b.schema(true); array["type"] != "array" || array["items"] == nil {
+		t.Errorf("schema(true) = %v, want an array of records", array)
+	}
+}
+
+func Test_writeSchema(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "data.json")
+	b := newSchemaBuilder()
+	b.observe(map[string]string{"id": "1"})
+	if err := writeSchema(b, output, true); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(output), "data.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	if schema["type"] != "array" {
+		t.Errorf("schema type = %v, want array", schema["type"])
+	}
+}
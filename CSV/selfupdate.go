@@ -0,0 +1,46 @@
+package csv2json
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"clikit/clierr"
+	"clikit/logging"
+	"clikit/selfupdate"
+)
+
+// SelfUpdater updates the executable running csv2json, the one of goclitool when it's
+// run by goclitool convert csv2json.
+var SelfUpdater = selfupdate.Updater{Tool: "csv2json", Version: selfupdate.CurrentVersion()}
+
+// runSelfUpdate implements "csv2json selfupdate [--check-only] [--channel=beta]".
+func runSelfUpdate(args []string) error {
+	flags := flag.NewFlagSet("selfupdate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Printf("Usage: %s selfupdate [options]\nReplaces csv2json with its build in the latest release\nOptions:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	var options selfupdate.Options
+	selfupdate.AddFlags(flags, &options)
+	logging.AddFlags(flags, &logOptions)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	// the flags not given come from the environment or the [selfupdate] section of the config file
+	if _, err := applyConfig(flags, "selfupdate", ""); err != nil {
+		return clierr.Wrap(clierr.Usage, err)
+	}
+	if err := logging.Setup(os.Stderr, logOptions, slog.LevelInfo); err != nil {
+		return clierr.Wrap(clierr.Usage, err)
+	}
+	if flags.NArg() > 0 {
+		return clierr.Errorf(clierr.Usage, "selfupdate doesn't take any argument")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return SelfUpdater.Run(ctx, options)
+}
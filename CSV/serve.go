@@ -0,0 +1,230 @@
+package csv2json
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// serve exposes the converter over HTTP. Every POST to /convert runs its own
+// reader, stages and writer, so requests don't share anything but the flags.
+func serve(fileData inputFile) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/convert", func(w http.ResponseWriter, r *http.Request) {
+		handleConvert(fileData, w, r)
+	})
+	slog.Info("serving the conversions", "addr", fileData.serveAddr, "path", "/convert")
+	check(http.ListenAndServe(fileData.serveAddr, mux))
+}
+
+// handleConvert converts the CSV sent in the body, either raw or as the "file" field of a
+// multipart form, and streams the records back. The separator, pretty and format query
+// parameters override the flags the server was started with.
+//
+// Once the response has started, a failure can't change its status: an NDJSON response
+// ends with an {"error": ...} record, and the connection is aborted so the client
+// doesn't take the records it got for all of them.
+func handleConvert(fileData inputFile, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	if separator := query.Get("separator"); separator != "" {
+		if separator != "comma" && separator != "semicolon" {
+			http.Error(w, "separator has to be either comma or semicolon", http.StatusBadRequest)
+			return
+		}
+		fileData.separator = separator
+	}
+	if pretty := query.Get("pretty"); pretty != "" {
+		value, err := strconv.ParseBool(pretty)
+		if err != nil {
+			http.Error(w, "pretty has to be true or false", http.StatusBadRequest)
+			return
+		}
+		fileData.pretty = value
+	}
+	ndjson := fileData.format == "ndjson" || r.Header.Get("Accept") == "application/x-ndjson"
+	switch query.Get("format") {
+	case "":
+	case "json":
+		ndjson = false
+	case "ndjson":
+		ndjson = true
+	default:
+		http.Error(w, "format has to be either json or ndjson", http.StatusBadRequest)
+		return
+	}
+
+	body, err := requestCSV(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The response starts streaming while the request body is still being read
+	http.NewResponseController(w).EnableFullDuplex()
+
+	// Rejected lines of a request are only logged, the rejects file belongs to the command line
+	writerChannel := make(chan map[string]string)
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- readCsv(fileData, body, &rejectWriter{}, writerChannel)
+	}()
+	records, stageErr := addStages(fileData, writerChannel)
+
+	contentType := "application/json"
+	if ndjson {
+		contentType = "application/x-ndjson"
+	}
+	out := &responseStream{w: w, contentType: contentType}
+	count, err := streamRecords(out, records, fileData.pretty, ndjson)
+	if err != nil {
+		// The client went away, so we drain the pipeline to let the reader finish
+		go func() {
+			for range records {
+			}
+		}()
+		slog.Error("converting the request", "remote", r.RemoteAddr, "err", err)
+		return
+	}
+	status := http.StatusBadRequest
+	err = <-readErr
+	if err != nil {
+		err = fmt.Errorf("reading CSV: %w", err)
+	} else if err = <-stageErr; err != nil {
+		status = http.StatusInternalServerError
+	}
+	if err != nil {
+		slog.Error("converting the request", "remote", r.RemoteAddr, "err", err)
+		if !out.started {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if ndjson {
+			jsonFunc, _ := getJSONFunc(false)
+			io.WriteString(out, jsonFunc(map[string]string{"error": err.Error()})+"\n")
+		}
+		out.flush()
+		panic(http.ErrAbortHandler)
+	}
+	// The end of the JSON array waits for the CSV data to be read without error
+	if err := endRecords(out, count, fileData.pretty, ndjson); err != nil {
+		slog.Error("converting the request", "remote", r.RemoteAddr, "err", err)
+		return
+	}
+	if err := out.flush(); err != nil {
+		slog.Error("converting the request", "remote", r.RemoteAddr, "err", err)
+	}
+}
+
+// requestCSV returns the CSV data of the request without reading it into memory.
+func requestCSV(r *http.Request) (io.Reader, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+	parts, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			return nil, errors.New(`multipart form has no "file" field`)
+		} else if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
+
+// responseStream delays writing the response headers until the first byte of the body,
+// so errors found before that can still be sent as a proper error response.
+type responseStream struct {
+	w           http.ResponseWriter
+	contentType string
+	buffer      *bufio.Writer
+	started     bool
+}
+
+func (s *responseStream) Write(p []byte) (int, error) {
+	if !s.started {
+		s.w.Header().Set("Content-Type", s.contentType)
+		s.buffer = bufio.NewWriter(s.w)
+		s.started = true
+	}
+	return s.buffer.Write(p)
+}
+
+func (s *responseStream) flush() error {
+	if !s.started {
+		return nil
+	}
+	if err := s.buffer.Flush(); err != nil {
+		return err
+	}
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// streamRecords writes the records as the items of a JSON array, or one JSON record per
+// line for NDJSON, and returns how many there were. The output is flushed every
+// flushEvery records so clients receive it while it's converted.
+func streamRecords(out *responseStream, records <-chan map[string]string, pretty, ndjson bool) (int, error) {
+	const flushEvery = 100
+	jsonFunc, breakLine := getJSONFunc(pretty && !ndjson)
+
+	count := 0
+	write := func(data string) error {
+		_, err := io.WriteString(out, data)
+		return err
+	}
+	for record := range records {
+		var err error
+		switch {
+		case ndjson:
+			err = write(jsonFunc(record) + "\n")
+		case count == 0:
+			err = write("[" + breakLine + jsonFunc(record))
+		default:
+			err = write("," + breakLine + jsonFunc(record))
+		}
+		if err != nil {
+			return count, err
+		}
+		if count++; count%flushEvery == 0 {
+			if err := out.flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, nil
+}
+
+// endRecords ends the JSON array of the count records streamRecords wrote, an NDJSON
+// response having no end.
+func endRecords(out *responseStream, count int, pretty, ndjson bool) error {
+	if ndjson {
+		return nil
+	}
+	end := "[]"
+	if count > 0 {
+		_, breakLine := getJSONFunc(pretty)
+		end = breakLine + "]"
+	}
+	_, err := io.WriteString(out, end)
+	return err
+}
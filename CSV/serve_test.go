@@ -0,0 +1,116 @@
+package csv2json
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_handleConvert(t *testing.T) {
+	csvString := "COL1,COL2\n1,2\n3,4\n"
+
+	// Building a multipart upload of the same CSV data
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, err := writer.CreateFormFile("file", "test.csv")
+	check(err)
+	part.Write([]byte(csvString))
+	writer.Close()
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{"Raw body", "/convert", "text/csv", csvString, http.StatusOK, `[{"COL1":"1","COL2":"2"},{"COL1":"3","COL2":"4"}]`},
+		{"NDJSON", "/convert?format=ndjson", "text/csv", csvString, http.StatusOK, "{\"COL1\":\"1\",\"COL2\":\"2\"}\n{\"COL1\":\"3\",\"COL2\":\"4\"}\n"},
+		{"Multipart upload", "/convert", writer.FormDataContentType(), form.String(), http.StatusOK, `[{"COL1":"1","COL2":"2"},{"COL1":"3","COL2":"4"}]`},
+		{"Headers only", "/convert", "text/csv", "COL1,COL2\n", http.StatusOK, "[]"},
+		{"Empty body", "/convert", "text/csv", "", http.StatusBadRequest, "reading CSV: EOF\n"},
+		{"Format not identified", "/convert?format=xml", "text/csv", csvString, http.StatusBadRequest, "format has to be either json or ndjson\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", tt.contentType)
+			response := httptest.NewRecorder()
+
+			handleConvert(inputFile{separator: "comma"}, response, request)
+
+			if response.Code != tt.wantStatus {
+				t.Errorf("handleConvert() status = %d, want %d", response.Code, tt.wantStatus)
+			}
+			if response.Body.String() != tt.wantBody {
+				t.Errorf("handleConvert() = %q, want %q", response.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func Test_handleConvert_failure(t *testing.T) {
+	defer func(size int) { sortChunkSize = size }(sortChunkSize)
+	sortChunkSize = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fileData := inputFile{separator: "comma"}
+		if r.URL.Query().Has("sort") {
+			fileData.sortBy = "COL1"
+		}
+		handleConvert(fileData, w, r)
+	}))
+	defer server.Close()
+	// the quote opened on the last line is never closed
+	broken := "COL1,COL2\n1,2\n3,\"4\n"
+
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		wantStatus int
+		wantBody   string // what the client reads before the connection is aborted
+	}{
+		{"JSON", "/convert", broken, http.StatusOK, `[{"COL1":"1","COL2":"2"}`},
+		{"NDJSON", "/convert?format=ndjson", broken, http.StatusOK, "{\"COL1\":\"1\",\"COL2\":\"2\"}\n{\"error\":\"reading CSV: parse error on line 3, column 6: extraneous or missing \\\" in quoted-field\"}\n"},
+		{"Sort failure", "/convert?sort", "COL1,COL2\n1,2\n3,4\n", http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "Sort failure" {
+				// the chunks can't be spilled
+				t.Setenv("TMPDIR", filepath.Join(t.TempDir(), "missing"))
+			}
+			response, err := http.Post(server.URL+tt.target, "text/csv", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+			body, err := io.ReadAll(response.Body)
+			if response.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", response.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if err == nil {
+				t.Error("the response ended normally")
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+
+	// the server is still there for the next requests
+	response, err := http.Post(server.URL+"/convert", "text/csv", strings.NewReader("COL1\n1\n"))
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("next request = %v, %v", response, err)
+	}
+	response.Body.Close()
+}
@@ -0,0 +1,237 @@
+package csv2json
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sortChunkSize is the number of records kept in memory while sorting. Once a
+// chunk is full it is sorted and spilled to a temporary file, and all the chunks
+// are merged back together at the end (an external merge sort).
+var sortChunkSize = 100000
+
+func parseSortBy(value string) (string, bool, error) {
+	if value == "" {
+		return "", false, nil
+	}
+	// The direction is optional, so "COL" and "COL:asc" mean the same thing
+	column, direction := value, "asc"
+	if i := strings.LastIndex(value, ":"); i != -1 {
+		column, direction = value[:i], strings.ToLower(value[i+1:])
+	}
+	if column == "" {
+		return "", false, fmt.Errorf("sort-by %q has no column", value)
+	}
+	switch direction {
+	case "asc":
+		return column, false, nil
+	case "desc":
+		return column, true, nil
+	}
+	return "", false, fmt.Errorf("sort direction has to be either asc or desc, got %q", direction)
+}
+
+// compareValues orders two column values. Numbers are compared numerically and come
+// before the other values, which are compared as plain strings, so a column mixing
+// both still has a total order and the chunks sorted apart merge the same way.
+func compareValues(a, b string) int {
+	fa, errA := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	fb, errB := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	switch {
+	case errA == nil && errB == nil:
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// spills are the temporary files holding the sorted chunks of the sorts in progress,
+// removed by exitGracefully when the conversion stops before a sort is done.
+var spills = struct {
+	sync.Mutex
+	names map[string]bool
+}{names: map[string]bool{}}
+
+func addSpill(name string) {
+	spills.Lock()
+	defer spills.Unlock()
+	spills.names[name] = true
+}
+
+// removeSpills removes the spilled chunks, all of them when names is empty.
+func removeSpills(names ...string) {
+	spills.Lock()
+	defer spills.Unlock()
+	if len(names) == 0 {
+		for name := range spills.names {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		os.Remove(name)
+		delete(spills.names, name)
+	}
+}
+
+// sortRecords sends the records of in to out, sorted by the column, and closes out. When
+// spilling or merging the chunks fails, the records left are dropped and the error
+// returned.
+func sortRecords(in <-chan map[string]string, out chan<- map[string]string, column string, desc bool) error {
+	defer close(out)
+	less := func(a, b map[string]string) bool {
+		if desc {
+			return compareValues(a[column], b[column]) > 0
+		}
+		return compareValues(a[column], b[column]) < 0
+	}
+
+	var chunk []map[string]string
+	var chunks []string // The temporary files holding the sorted chunks
+	defer func() { removeSpills(chunks...) }()
+	// The stages before keep sending until their input is over
+	fail := func(err error) error {
+		for range in {
+		}
+		return err
+	}
+
+	for record := range in {
+		chunk = append(chunk, record)
+		if len(chunk) < sortChunkSize {
+			continue
+		}
+		name, err := spillChunk(chunk, less)
+		if name != "" {
+			chunks = append(chunks, name)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		chunk = nil
+	}
+
+	// Small inputs never leave memory, so we just sort them and we're done
+	if len(chunks) == 0 {
+		sort.SliceStable(chunk, func(i, j int) bool { return less(chunk[i], chunk[j]) })
+		for _, record := range chunk {
+			out <- record
+		}
+		return nil
+	}
+
+	if len(chunk) > 0 {
+		name, err := spillChunk(chunk, less)
+		if name != "" {
+			chunks = append(chunks, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return mergeChunks(chunks, out, less)
+}
+
+// spillChunk sorts the chunk and writes it to a temporary file, one JSON record per line.
+func spillChunk(chunk []map[string]string, less func(a, b map[string]string) bool) (string, error) {
+	sort.SliceStable(chunk, func(i, j int) bool { return less(chunk[i], chunk[j]) })
+
+	f, err := os.CreateTemp("", "csv2json-sort-*.ndjson")
+	if err != nil {
+		return "", err
+	}
+	addSpill(f.Name())
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+	for _, record := range chunk {
+		if err := encoder.Encode(record); err != nil {
+			return f.Name(), err
+		}
+	}
+	return f.Name(), w.Flush()
+}
+
+// chunkReader is the head of one of the sorted chunks while they are being merged.
+type chunkReader struct {
+	index   int // position of the chunk, used to keep the sort stable
+	record  map[string]string
+	decoder *json.Decoder
+}
+
+type chunkHeap struct {
+	readers []*chunkReader
+	less    func(a, b map[string]string) bool
+}
+
+func (h chunkHeap) Len() int { return len(h.readers) }
+func (h chunkHeap) Less(i, j int) bool {
+	a, b := h.readers[i], h.readers[j]
+	if h.less(a.record, b.record) {
+		return true
+	}
+	if h.less(b.record, a.record) {
+		return false
+	}
+	return a.index < b.index
+}
+func (h chunkHeap) Swap(i, j int)       { h.readers[i], h.readers[j] = h.readers[j], h.readers[i] }
+func (h *chunkHeap) Push(x interface{}) { h.readers = append(h.readers, x.(*chunkReader)) }
+func (h *chunkHeap) Pop() interface{} {
+	last := h.readers[len(h.readers)-1]
+	h.readers = h.readers[:len(h.readers)-1]
+	return last
+}
+
+// mergeChunks does a k-way merge of the sorted chunk files into out.
+func mergeChunks(names []string, out chan<- map[string]string, less func(a, b map[string]string) bool) error {
+	h := &chunkHeap{less: less}
+	for i, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		reader := &chunkReader{index: i, decoder: json.NewDecoder(bufio.NewReader(f))}
+		if reader.decoder.More() {
+			if err := reader.decoder.Decode(&reader.record); err != nil {
+				return err
+			}
+			h.readers = append(h.readers, reader)
+		}
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		// The smallest record is always at the top of the heap
+		reader := h.readers[0]
+		out <- reader.record
+		if !reader.decoder.More() {
+			heap.Pop(h)
+			continue
+		}
+		reader.record = nil
+		if err := reader.decoder.Decode(&reader.record); err != nil {
+			return err
+		}
+		heap.Fix(h, 0)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package csv2json
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func Test_sortRecords(t *testing.T) {
+	records := []map[string]string{
+		{"NAME": "b", "AMOUNT": "10", "CODE": "9a"},
+		{"NAME": "a", "AMOUNT": "9", "CODE": "10"},
+		{"NAME": "c", "AMOUNT": "100", "CODE": "9"},
+		{"NAME": "d", "AMOUNT": "9", "CODE": "10a"},
+	}
+
+	tests := []struct {
+		name      string
+		column    string
+		desc      bool
+		chunkSize int      // Small chunk sizes force the external merge
+		want      []string // The expected order of the NAME column
+	}{
+		{"Strings ascending", "NAME", false, 100, []string{"a", "b", "c", "d"}},
+		{"Strings descending", "NAME", true, 100, []string{"d", "c", "b", "a"}},
+		{"Numbers ascending", "AMOUNT", false, 100, []string{"a", "d", "b", "c"}},
+		{"Numbers descending", "AMOUNT", true, 100, []string{"c", "b", "a", "d"}},
+		{"Merged chunks", "AMOUNT", false, 1, []string{"a", "d", "b", "c"}},
+		{"Numbers before text", "CODE", false, 100, []string{"c", "a", "d", "b"}},
+		{"Numbers before text merged", "CODE", false, 1, []string{"c", "a", "d", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(size int) { sortChunkSize = size }(sortChunkSize)
+			sortChunkSize = tt.chunkSize
+
+			in := make(chan map[string]string)
+			out := make(chan map[string]string)
+			go func() {
+				for _, record := range records {
+					in <- record
+				}
+				close(in)
+			}()
+			errs := make(chan error, 1)
+			go func() { errs <- sortRecords(in, out, tt.column, tt.desc) }()
+
+			var got []string
+			for record := range out {
+				got = append(got, record["NAME"])
+			}
+			if err := <-errs; err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortRecords() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_compareValues(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"9", "10", -1},
+		{" 2.5", "2.50", 0},
+		{"10", "9a", -1},
+		{"9a", "9", 1},
+		{"10a", "9a", -1},
+	}
+	for _, tt := range tests {
+		if got := compareValues(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareValues(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func Test_removeSpills(t *testing.T) {
+	less := func(a, b map[string]string) bool { return a["NAME"] < b["NAME"] }
+	name, err := spillChunk([]map[string]string{{"NAME": "a"}}, less)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// what exitGracefully does when a sort fails before it's done
+	removeSpills()
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("spilled chunk %s left behind", name)
+	}
+}
@@ -0,0 +1,174 @@
+package csv2json
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runStats counts what happened during a conversion, for --stats and --stats-out.
+// The reader and the writer update it from different go-routines. A nil *runStats
+// collects nothing, so the callers don't have to check whether stats are enabled.
+type runStats struct {
+	mu       sync.Mutex
+	start    time.Time
+	read     int64
+	written  int64
+	rejected int64
+	bytes    int64
+	columns  []string         // the headers, in order, to report the null counts
+	nulls    map[string]int64 // empty values per column
+}
+
+// statsSummary is the summary printed at the end of the run, or written as JSON.
+type statsSummary struct {
+	RowsRead       int64            `json:"rows_read"`
+	RowsWritten    int64            `json:"rows_written"`
+	RowsRejected   int64            `json:"rows_rejected"`
+	BytesProcessed int64            `json:"bytes_processed"`
+	NullCounts     map[string]int64 `json:"null_counts"`
+	ElapsedSeconds float64          `json:"elapsed_seconds"`
+	RowsPerSecond  float64          `json:"rows_per_second"`
+	BytesPerSecond float64          `json:"bytes_per_second"`
+}
+
+func newRunStats() *runStats {
+	return &runStats{start: time.Now(), nulls: map[string]int64{}}
+}
+
+// setHeaders records the columns whose empty values are counted. Merged files share
+// the headers of the first one, so only those are kept.
+func (s *runStats) setHeaders(headers []string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.columns != nil {
+		return
+	}
+	s.columns = headers
+	for _, column := range headers {
+		s.nulls[column] = 0
+	}
+}
+
+// readLine counts a CSV line, along with the empty values of its record. The record
+// is nil when the line couldn't be turned into one.
+func (s *runStats) readLine(record map[string]string, rejected bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.read++
+	if rejected {
+		s.rejected++
+	}
+	for column, value := range record {
+		if strings.TrimSpace(value) == "" {
+			s.nulls[column]++
+		}
+	}
+}
+
+// addBytes counts bytes of CSV data read.
+func (s *runStats) addBytes(n int64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bytes += n
+}
+
+func (s *runStats) wrote() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.written++
+}
+
+// countRecords passes the records through, counting them as written.
+func countRecords(stats *runStats, records <-chan map[string]string) <-chan map[string]string {
+	if stats == nil {
+		return records
+	}
+	counted := make(chan map[string]string)
+	go func() {
+		defer close(counted)
+		for record := range records {
+			stats.wrote()
+			counted <- record
+		}
+	}()
+	return counted
+}
+
+func (s *runStats) summary() statsSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elapsed := time.Since(s.start).Seconds()
+	summary := statsSummary{
+		RowsRead:       s.read,
+		RowsWritten:    s.written,
+		RowsRejected:   s.rejected,
+		BytesProcessed: s.bytes,
+		NullCounts:     map[string]int64{},
+		ElapsedSeconds: elapsed,
+	}
+	for column, count := range s.nulls {
+		summary.NullCounts[column] = count
+	}
+	if elapsed > 0 {
+		summary.RowsPerSecond = float64(s.read) / elapsed
+		summary.BytesPerSecond = float64(s.bytes) / elapsed
+	}
+	return summary
+}
+
+// printStats writes the summary as a human readable table.
+func printStats(w io.Writer, summary statsSummary, columns []string) {
+	fmt.Fprintf(w, "Rows read:       %d\n", summary.RowsRead)
+	fmt.Fprintf(w, "Rows written:    %d\n", summary.RowsWritten)
+	fmt.Fprintf(w, "Rows rejected:   %d\n", summary.RowsRejected)
+	fmt.Fprintf(w, "Bytes processed: %d\n", summary.BytesProcessed)
+	elapsed := time.Duration(summary.ElapsedSeconds * float64(time.Second)).Round(time.Millisecond)
+	fmt.Fprintf(w, "Elapsed:         %s (%.0f rows/s, %.2f MB/s)\n", elapsed, summary.RowsPerSecond, summary.BytesPerSecond/1e6)
+	if len(columns) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Empty values per column:")
+	for _, column := range columns {
+		fmt.Fprintf(w, "  %s: %d\n", column, summary.NullCounts[column])
+	}
+}
+
+// reportStats prints the summary when --stats is set and writes it as JSON to --stats-out.
+func reportStats(fileData inputFile) error {
+	stats := fileData.counters
+	if stats == nil {
+		return nil
+	}
+	summary := stats.summary()
+	if fileData.stats {
+		stats.mu.Lock()
+		columns := stats.columns
+		stats.mu.Unlock()
+		printStats(os.Stdout, summary, columns)
+	}
+	if fileData.statsOut == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(summary, "", "   ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileData.statsOut, append(data, '\n'), 0644)
+}
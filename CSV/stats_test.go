@@ -0,0 +1,48 @@
+package csv2json
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_runStats(t *testing.T) {
+	stats := newRunStats()
+	fileData := inputFile{separator: "comma", counters: stats}
+	data := "id,name,city\n1,Ann,\n2,,\n3,Bob\n4,Eve,Rome\n"
+	writerChannel := make(chan map[string]string)
+	go readCsv(fileData, strings.NewReader(data), &rejectWriter{}, writerChannel)
+	for range countRecords(stats, writerChannel) {
+	}
+
+	summary := stats.summary()
+	if summary.RowsRead != 4 || summary.RowsWritten != 3 || summary.RowsRejected != 1 {
+		t.Errorf("rows read, written, rejected = %d, %d, %d, want 4, 3, 1", summary.RowsRead, summary.RowsWritten, summary.RowsRejected)
+	}
+	if summary.BytesProcessed != int64(len(data)) {
+		t.Errorf("bytes processed = %d, want %d", summary.BytesProcessed, len(data))
+	}
+	want := map[string]int64{"id": 0, "name": 1, "city": 2}
+	for column, count := range want {
+		if summary.NullCounts[column] != count {
+			t.Errorf("null count of %s = %d, want %d", column, summary.NullCounts[column], count)
+		}
+	}
+
+	var out bytes.Buffer
+	printStats(&out, summary, stats.columns)
+	if !strings.Contains(out.String(), "Rows rejected:   1\n") || !strings.Contains(out.String(), "  city: 2\n") {
+		t.Errorf("printStats() = %q", out.String())
+	}
+}
+
+func Test_runStatsNil(t *testing.T) {
+	var stats *runStats
+	stats.setHeaders([]string{"id"})
+	stats.readLine(map[string]string{"id": ""}, false)
+	stats.wrote()
+	records := make(chan map[string]string)
+	if got := countRecords(stats, records); got != (<-chan map[string]string)(records) {
+		t.Errorf("countRecords() with nil stats should return the channel as is")
+	}
+}
@@ -0,0 +1,212 @@
+package csv2json
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// validationRule is a single check applied to one column of every record.
+// Rules are written as column:rule, where rule is one of
+//
+//	required       the value can't be empty
+//	regex=EXPR     the value has to match the regular expression
+//	min=N, max=N   the value has to be a number within the bound
+//	enum=a|b|c     the value has to be one of the listed values
+type validationRule struct {
+	column string
+	kind   string
+	regex  *regexp.Regexp
+	number float64
+	values []string
+}
+
+// fieldError describes why a column of a record was rejected.
+type fieldError struct {
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// parseValidation reads the rules given with --validate. Rules are separated by
+// semicolons, or if the value starts with @ they are read from that file, one per line.
+func parseValidation(value string) ([]validationRule, error) {
+	var items []string
+	if strings.HasPrefix(value, "@") {
+		f, err := os.Open(value[1:])
+		if err != nil {
+			return nil, fmt.Errorf("reading validation rules: %w", err)
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") { // Skipping blank lines and comments
+				continue
+			}
+			items = append(items, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading validation rules: %w", err)
+		}
+	} else {
+		for _, item := range strings.Split(value, ";") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+
+	var rules []validationRule
+	for _, item := range items {
+		rule, err := parseRule(item)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseRule(item string) (validationRule, error) {
+	i := strings.Index(item, ":")
+	if i < 1 {
+		return validationRule{}, fmt.Errorf("validation rule %q has to be written as column:rule", item)
+	}
+	rule := validationRule{column: strings.TrimSpace(item[:i])}
+	kind, arg, hasArg := strings.Cut(strings.TrimSpace(item[i+1:]), "=")
+	rule.kind = strings.TrimSpace(kind)
+
+	switch rule.kind {
+	case "required":
+		if hasArg {
+			return validationRule{}, fmt.Errorf("validation rule %q: required takes no value", item)
+		}
+	case "regex":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return validationRule{}, fmt.Errorf("validation rule %q: %w", item, err)
+		}
+		rule.regex = re
+	case "min", "max":
+		number, err := strconv.ParseFloat(strings.TrimSpace(arg), 64)
+		if err != nil {
+			return validationRule{}, fmt.Errorf("validation rule %q: %s needs a number", item, rule.kind)
+		}
+		rule.number = number
+	case "enum":
+		rule.values = strings.Split(arg, "|")
+	default:
+		return validationRule{}, fmt.Errorf("validation rule %q: unknown rule %q", item, rule.kind)
+	}
+	if rule.kind != "required" && !hasArg {
+		return validationRule{}, fmt.Errorf("validation rule %q: %s needs a value", item, rule.kind)
+	}
+	return rule, nil
+}
+
+// validateRecord checks the record against every rule and returns all the violations.
+// Apart from required, rules don't apply to empty values.
+func validateRecord(rules []validationRule, record map[string]string) []fieldError {
+	var errs []fieldError
+	for _, rule := range rules {
+		value, ok := record[rule.column]
+		if !ok {
+			errs = append(errs, fieldError{rule.column, "column does not exist"})
+			continue
+		}
+		if strings.TrimSpace(value) == "" {
+			if rule.kind == "required" {
+				errs = append(errs, fieldError{rule.column, "value is required"})
+			}
+			continue
+		}
+
+		switch rule.kind {
+		case "regex":
+			if !rule.regex.MatchString(value) {
+				errs = append(errs, fieldError{rule.column, fmt.Sprintf("value %q does not match %s", value, rule.regex)})
+			}
+		case "min", "max":
+			number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			switch {
+			case err != nil:
+				errs = append(errs, fieldError{rule.column, fmt.Sprintf("value %q is not a number", value)})
+			case rule.kind == "min" && number < rule.number:
+				errs = append(errs, fieldError{rule.column, fmt.Sprintf("value %s is lower than %s", value, formatNumber(rule.number))})
+			case rule.kind == "max" && number > rule.number:
+				errs = append(errs, fieldError{rule.column, fmt.Sprintf("value %s is greater than %s", value, formatNumber(rule.number))})
+			}
+		case "enum":
+			found := false
+			for _, allowed := range rule.values {
+				if value == allowed {
+					found = true
+					break
+				}
+			}
+			if !found {
+				errs = append(errs, fieldError{rule.column, fmt.Sprintf("value %q is not one of %s", value, strings.Join(rule.values, ", "))})
+			}
+		}
+	}
+	return errs
+}
+
+// rejectWriter records the lines that didn't make it into the output. Without a
+// rejects path they are just logged like any other malformed line.
+type rejectWriter struct {
+	file    *os.File
+	encoder *json.Encoder
+	source  string // file the rejected lines come from, when several files are merged
+}
+
+// rejectedLine is the shape of each line of the rejects file.
+type rejectedLine struct {
+	File   string            `json:"file,omitempty"`
+	Line   int               `json:"line"`
+	Fields []string          `json:"fields"`
+	Record map[string]string `json:"record,omitempty"`
+	Errors []fieldError      `json:"errors"`
+}
+
+func newRejectWriter(path string) (*rejectWriter, error) {
+	if path == "" {
+		return &rejectWriter{}, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &rejectWriter{file: f, encoder: json.NewEncoder(f)}, nil
+}
+
+func (r *rejectWriter) reject(line int, fields []string, record map[string]string, errs []fieldError) {
+	if r.encoder == nil {
+		messages := make([]string, len(errs))
+		for i, e := range errs {
+			messages[i] = e.Message
+			if e.Column != "" {
+				messages[i] = e.Column + ": " + e.Message
+			}
+		}
+		attrs := []any{"line", line, "fields", fields, "errors", strings.Join(messages, "; ")}
+		if r.source != "" {
+			attrs = append([]any{"file", r.source}, attrs...)
+		}
+		slog.Warn("rejected a line", attrs...)
+		return
+	}
+	check(r.encoder.Encode(rejectedLine{r.source, line, fields, record, errs}))
+}
+
+func (r *rejectWriter) close() {
+	if r.file != nil {
+		check(r.file.Close())
+	}
+}
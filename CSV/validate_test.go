@@ -0,0 +1,65 @@
+package csv2json
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_validateRecord(t *testing.T) {
+	rules, err := parseValidation(`EMAIL:required;EMAIL:regex=^[^@]+@[^@]+$;AMOUNT:min=0;AMOUNT:max=100;STATUS:enum=open|closed`)
+	if err != nil {
+		t.Fatalf("parseValidation() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		record map[string]string
+		want   []fieldError
+	}{
+		{"Valid record", map[string]string{"EMAIL": "a@b.c", "AMOUNT": "10", "STATUS": "open"}, nil},
+		{"Empty optional values", map[string]string{"EMAIL": "a@b.c", "AMOUNT": "", "STATUS": ""}, nil},
+		{"Missing required value", map[string]string{"EMAIL": "", "AMOUNT": "10", "STATUS": "open"}, []fieldError{
+			{"EMAIL", "value is required"},
+		}},
+		{"Every rule broken", map[string]string{"EMAIL": "nope", "AMOUNT": "-1", "STATUS": "pending"}, []fieldError{
+			{"EMAIL", `value "nope" does not match ^[^@]+@[^@]+$`},
+			{"AMOUNT", "value -1 is lower than 0"},
+			{"STATUS", `value "pending" is not one of open, closed`},
+		}},
+		{"Not a number", map[string]string{"EMAIL": "a@b.c", "AMOUNT": "ten", "STATUS": "open"}, []fieldError{
+			{"AMOUNT", `value "ten" is not a number`},
+			{"AMOUNT", `value "ten" is not a number`},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateRecord(rules, tt.record); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateRecord() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"No rules", "", false},
+		{"Several rules", "A:required; B:enum=x|y", false},
+		{"Missing column", "required", true},
+		{"Unknown rule", "A:unique", true},
+		{"Bad regex", "A:regex=(", true},
+		{"Bad bound", "A:min=low", true},
+		{"Missing value", "A:enum", true},
+		{"Missing rules file", "@nowhere/rules.txt", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseValidation(tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("parseValidation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
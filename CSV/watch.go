@@ -0,0 +1,171 @@
+package csv2json
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"clikit/clierr"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// debouncer tells when a file stayed unchanged for a whole interval since it was last
+// created or written, so we don't convert files that are still being written.
+type debouncer struct {
+	interval time.Duration
+	ready    chan string // the files whose interval elapsed, to be checked with settled
+	timers   map[string]*time.Timer
+	changed  map[string]time.Time
+}
+
+func newDebouncer(interval time.Duration) *debouncer {
+	return &debouncer{
+		interval: interval,
+		ready:    make(chan string),
+		timers:   make(map[string]*time.Timer),
+		changed:  make(map[string]time.Time),
+	}
+}
+
+// touch records that the file changed, starting its interval again.
+func (d *debouncer) touch(name string) {
+	d.changed[name] = time.Now()
+	if timer, ok := d.timers[name]; ok {
+		timer.Reset(d.interval)
+		return
+	}
+	d.timers[name] = time.AfterFunc(d.interval, func() { d.ready <- name })
+}
+
+// forget stops waiting for the file, removed or renamed.
+func (d *debouncer) forget(name string) {
+	if timer, ok := d.timers[name]; ok {
+		timer.Stop()
+	}
+	delete(d.timers, name)
+	delete(d.changed, name)
+}
+
+// settled tells if the file received from ready didn't change for the whole interval,
+// and forgets it then. A timer that fired before being started again sends the file
+// too early, or after it was forgotten, which settled ignores.
+func (d *debouncer) settled(name string) bool {
+	changed, ok := d.changed[name]
+	if !ok || time.Since(changed) < d.interval {
+		return false
+	}
+	d.forget(name)
+	return true
+}
+
+func checkIfValidDir(dirname string) error {
+	info, err := os.Stat(dirname)
+	if err != nil && os.IsNotExist(err) {
+		return clierr.Errorf(clierr.IO, "directory %s does not exist", dirname)
+	} else if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return clierr.Errorf(clierr.Usage, "%s is not a directory", dirname)
+	}
+	return nil
+}
+
+// childArgs returns the flags given on the command line, without the watch ones and
+// after the Subcommand, so every file is converted exactly like a single file
+// conversion would do it.
+func childArgs() []string {
+	args := slices.Clone(Subcommand)
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "watch" || f.Name == "watch-interval" {
+			return
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", f.Name, f.Value))
+	})
+	return args
+}
+
+// watchDirectory converts the CSV files created or modified in the directory, the
+// ones already there included. A file is only converted once no change was notified
+// for a whole interval, so we don't convert files that are still being written.
+// Converted files are moved into done/, and the ones that failed into failed/.
+func watchDirectory(fileData inputFile) {
+	dir := fileData.filepath
+	doneDir := filepath.Join(dir, "done")
+	failedDir := filepath.Join(dir, "failed")
+	check(os.MkdirAll(doneDir, 0755))
+	check(os.MkdirAll(failedDir, 0755))
+
+	executable, err := os.Executable()
+	check(err)
+	args := childArgs()
+
+	watcher, err := fsnotify.NewWatcher()
+	check(err)
+	defer watcher.Close()
+	check(watcher.Add(dir))
+
+	slog.Info("watching for CSV files", "dir", dir)
+	files := newDebouncer(fileData.watchInterval)
+	entries, err := os.ReadDir(dir)
+	check(err)
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && filepath.Ext(entry.Name()) == ".csv" {
+			files.touch(entry.Name())
+		}
+	}
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			name := filepath.Base(event.Name)
+			if filepath.Ext(name) != ".csv" {
+				continue
+			}
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+				files.touch(name)
+			} else if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				files.forget(name)
+			}
+		case name := <-files.ready:
+			if files.settled(name) {
+				convertWatched(executable, args, dir, name, doneDir, failedDir)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			slog.Error("watching", "dir", dir, "err", err)
+		}
+	}
+}
+
+// convertWatched converts the file by running csv2json again, so a broken file can't
+// stop the watcher, and moves it into doneDir or failedDir.
+func convertWatched(executable string, args []string, dir, name, doneDir, failedDir string) {
+	path := filepath.Join(dir, name)
+	// a directory named like a CSV file, or a file removed since it settled
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return
+	}
+	slog.Info("converting", "path", path)
+	cmd := exec.Command(executable, append(args, path)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	target := doneDir
+	if err := cmd.Run(); err != nil {
+		slog.Error("converting", "path", path, "err", err)
+		target = failedDir
+	}
+	if err := os.Rename(path, filepath.Join(target, name)); err != nil {
+		slog.Error("moving", "path", path, "dir", target, "err", err)
+	}
+}
@@ -0,0 +1,37 @@
+package csv2json
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_debouncer(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	d := newDebouncer(interval)
+	start := time.Now()
+	d.touch("a.csv")
+	d.touch("b.csv")
+	d.forget("b.csv")
+	// a write in the middle of the interval starts it again
+	time.Sleep(interval / 2)
+	d.touch("a.csv")
+
+	timeout := time.After(10 * interval)
+	for {
+		select {
+		case name := <-d.ready:
+			if !d.settled(name) {
+				continue
+			}
+			if name != "a.csv" {
+				t.Fatalf("%s settled, it was forgotten", name)
+			}
+			if elapsed := time.Since(start); elapsed < interval+interval/2 {
+				t.Errorf("a.csv settled after %v, before its last write was %v old", elapsed, interval)
+			}
+			return
+		case <-timeout:
+			t.Fatal("a.csv never settled")
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package clierr sorts the errors of the tools in categories, each exiting with its
+// own code so the scripts running them can tell why they failed:
+//
+//	1  internal    anything else, like a bug
+//	2  usage       the command line is wrong: an unknown command or flag, a missing
+//	               argument, a value out of range or flags that can't be combined
+//	3  validation  the input is invalid, like a malformed CSV or config file
+//	4  io          a file can't be read or written
+//	5  network     the server can't be reached, or answered with an error
+//
+// 2 is also the code the flag package exits with on an unknown flag. When the logs are
+// JSON lines, see package logging, the error is one of them with its category and its
+// exit code, like {"level":"ERROR","msg":"...","category":"io","exit_code":4}.
+package clierr
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+)
+
+// Category is the kind of an error.
+type Category int
+
+const (
+	Internal Category = iota
+	Usage
+	Validation
+	IO
+	Network
+)
+
+func (c Category) String() string {
+	switch c {
+	case Usage:
+		return "usage"
+	case Validation:
+		return "validation"
+	case IO:
+		return "io"
+	case Network:
+		return "network"
+	}
+	return "internal"
+}
+
+// ExitCode returns the code the tools exit with on an error of the category.
+func (c Category) ExitCode() int {
+	return int(c) + 1
+}
+
+// Error is an error of a category.
+type Error struct {
+	Category Category
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns err in the category, unless it's already in one. It returns nil when
+// err is nil.
+func Wrap(c Category, err error) error {
+	var e *Error
+	if err == nil || errors.As(err, &e) {
+		return err
+	}
+	return &Error{c, err}
+}
+
+// Errorf returns an error of the category, formatted like fmt.Errorf does.
+func Errorf(c Category, format string, args ...any) error {
+	return &Error{c, fmt.Errorf(format, args...)}
+}
+
+// CategoryOf returns the category err is in, else the one guessed from the errors it
+// wraps: the file system errors are io, the network ones network, and the parsing
+// ones validation. It's internal when there's no telling.
+func CategoryOf(err error) Category {
+	var e *Error
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var netErr net.Error
+	var csvErr *csv.ParseError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var numErr *strconv.NumError
+	switch {
+	case errors.As(err, &e):
+		return e.Category
+	// before the network errors, the path errors having a Timeout method too
+	case errors.As(err, &pathErr), errors.As(err, &linkErr), errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrPermission):
+		return IO
+	case errors.As(err, &netErr):
+		return Network
+	case errors.As(err, &csvErr), errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.As(err, &numErr):
+		return Validation
+	}
+	return Internal
+}
+
+// Exit logs the error and exits with the code of its category.
+func Exit(err error) {
+	c := CategoryOf(err)
+	if _, ok := slog.Default().Handler().(*slog.JSONHandler); ok {
+		slog.Error(err.Error(), "category", c.String(), "exit_code", c.ExitCode())
+	} else {
+		slog.Error(err.Error())
+	}
+	os.Exit(c.ExitCode())
+}
@@ -0,0 +1,61 @@
+package clierr
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestCategoryOf(t *testing.T) {
+	_, openErr := os.Open("does-not-exist")
+	_, numErr := strconv.Atoi("x")
+	tests := []struct {
+		name string
+		err  error
+		want Category
+	}{
+		{"Categorized", Errorf(Usage, "separator has to be either comma or semicolon"), Usage},
+		{"Wrapped categorized", fmt.Errorf("reading: %w", Wrap(Validation, errors.New("bad"))), Validation},
+		{"Path error", openErr, IO},
+		{"Not exist", fmt.Errorf("x: %w", fs.ErrNotExist), IO},
+		{"Network", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, Network},
+		{"CSV", &csv.ParseError{Line: 2, Err: csv.ErrFieldCount}, Validation},
+		{"Number", numErr, Validation},
+		{"Anything else", errors.New("boom"), Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CategoryOf(tt.err); got != tt.want {
+				t.Errorf("CategoryOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	if Wrap(IO, nil) != nil {
+		t.Error("Wrap(nil) isn't nil")
+	}
+	err := Wrap(Usage, Errorf(Network, "unreachable"))
+	if got := CategoryOf(err); got != Network {
+		t.Errorf("Wrap() changed the category to %v", got)
+	}
+	base := errors.New("boom")
+	if err := Wrap(IO, base); !errors.Is(err, base) || CategoryOf(err) != IO {
+		t.Errorf("Wrap() = %v", err)
+	}
+}
+
+func TestCategory_ExitCode(t *testing.T) {
+	want := map[Category]int{Internal: 1, Usage: 2, Validation: 3, IO: 4, Network: 5}
+	for c, code := range want {
+		if got := c.ExitCode(); got != code {
+			t.Errorf("%v.ExitCode() = %d, want %d", c, got, code)
+		}
+	}
+}
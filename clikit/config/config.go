@@ -0,0 +1,432 @@
+// Package config gives the flags of the tools their values from, in increasing
+// precedence:
+//
+//  1. the default value of the flag
+//  2. the config file
+//  3. the environment, as the prefix of the tool followed by the name of the flag in
+//     upper case, like QOTD_AUTH_TOKEN for --auth-token
+//  4. the command line
+//
+// So a flag given on the command line always wins, and the environment wins over the
+// config file. It works with the flags of the flag package and of pflag alike.
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source is where the value of a setting comes from, the later ones winning.
+type Source int
+
+const (
+	SourceDefault Source = iota
+	SourceFile
+	SourceEnv
+	SourceFlag
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceFile:
+		return "file"
+	case SourceEnv:
+		return "env"
+	case SourceFlag:
+		return "flag"
+	}
+	return "default"
+}
+
+// Setting is the effective value of a flag.
+type Setting struct {
+	Name   string
+	Value  string
+	Source Source
+	Origin string // file and line, or environment variable, the value comes from
+}
+
+// Flag is a flag the settings are applied to.
+type Flag struct {
+	Name    string
+	Value   string // current value, the default one until it's set
+	Changed bool   // whether it's set on the command line
+	Set     func(value string) error
+}
+
+// Flags returns the flags of fs, the ones given on the command line being Changed
+// once it's parsed.
+func Flags(fs *flag.FlagSet) []Flag {
+	changed := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		changed[f.Name] = true
+	})
+	var flags []Flag
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, Flag{Name: f.Name, Value: f.Value.String(), Changed: changed[f.Name], Set: f.Value.Set})
+	})
+	return flags
+}
+
+// Config is where a tool reads its settings from.
+type Config struct {
+	File      *File
+	EnvPrefix string // like QOTD, QOTD_ADDR then setting --addr
+	// EnvAliases are the other environment variables of a flag, by name, used when
+	// the one of the prefix isn't set
+	EnvAliases map[string][]string
+}
+
+// EnvVar returns the environment variable of the flag, like QOTD_AUTH_TOKEN.
+func (c *Config) EnvVar(name string) string {
+	return c.EnvPrefix + "_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// lookupEnv returns the environment variable of the flag set, and its value.
+func (c *Config) lookupEnv(name string) (string, string, bool) {
+	for _, key := range append([]string{c.EnvVar(name)}, c.EnvAliases[name]...) {
+		if value, ok := os.LookupEnv(key); ok {
+			return key, value, true
+		}
+	}
+	return "", "", false
+}
+
+// Apply sets the flags not given on the command line from the environment, else from
+// the config file, where the values of the section apply to them too, winning over the
+// ones before any section. It returns the settings of all the flags.
+func (c *Config) Apply(section string, flags []Flag) ([]Setting, error) {
+	settings := make([]Setting, 0, len(flags))
+	for _, f := range flags {
+		setting := Setting{Name: f.Name, Value: f.Value, Source: SourceDefault}
+		if f.Changed {
+			setting.Source = SourceFlag
+			settings = append(settings, setting)
+			continue
+		}
+		if key, value, ok := c.lookupEnv(f.Name); ok {
+			if err := f.Set(value); err != nil {
+				return nil, fmt.Errorf("$%s: %w", key, err)
+			}
+			setting = Setting{Name: f.Name, Value: value, Source: SourceEnv, Origin: key}
+		} else if e, ok := c.File.lookup(section, f.Name); ok {
+			if err := f.Set(e.value); err != nil {
+				return nil, fmt.Errorf("%s:%d: %s: %w", c.File.Path, e.line, f.Name, err)
+			}
+			setting = Setting{Name: f.Name, Value: e.value, Source: SourceFile, Origin: fmt.Sprintf("%s:%d", c.File.Path, e.line)}
+		}
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
+
+// Print writes the settings as a table, under NAME, VALUE and SOURCE. The values of the
+// flags named in hidden are hidden, like the tokens.
+func Print(w io.Writer, settings []Setting, hidden ...string) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tVALUE\tSOURCE")
+	for _, s := range settings {
+		value := s.Value
+		for _, name := range hidden {
+			if s.Name == name && value != "" {
+				value = "<hidden>"
+			}
+		}
+		source := s.Source.String()
+		if s.Origin != "" {
+			source += " (" + s.Origin + ")"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", s.Name, value, source)
+	}
+	return table.Flush()
+}
+
+// entry is a key = value line of a config file.
+type entry struct {
+	key, value string
+	line       int
+}
+
+// File is a config file, of key = value lines, or a YAML mapping of key: value for
+// the .yaml and .yml files, one per flag. In the first, the lines after a [name] line
+// are the section of the command name, the blank ones and the ones starting with # or
+// ; are skipped. In YAML, the section is a mapping under the name of the command, and
+// a list is the same as its values separated by commas.
+type File struct {
+	Path     string
+	Found    bool // false when an optional file is missing
+	yaml     *yaml.Node
+	lines    []string
+	sections map[string][]entry
+}
+
+// ReadFile reads the config file at path. A missing file is the same as an empty
+// one when it's optional, like the one read by default.
+func ReadFile(path string, optional bool) (*File, error) {
+	ext := filepath.Ext(path)
+	f := &File{Path: path, sections: map[string][]entry{}}
+	if ext == ".yaml" || ext == ".yml" {
+		f.yaml = &yaml.Node{}
+	}
+	data, err := os.ReadFile(path)
+	if optional && errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f.Found = true
+	if f.yaml != nil {
+		if err := yaml.Unmarshal(data, f.yaml); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := f.readYAML(); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		f.lines = append(f.lines, scanner.Text())
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, ";"):
+		case strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]"):
+			section = strings.TrimSpace(text[1 : len(text)-1])
+		default:
+			key, value, ok := strings.Cut(text, "=")
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: expected key = value", path, line)
+			}
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+			f.sections[section] = append(f.sections[section], entry{strings.TrimSpace(key), value, line})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// readYAML reads the entries of the decoded YAML file.
+func (f *File) readYAML() error {
+	// an empty file has no document
+	if len(f.yaml.Content) == 0 {
+		return nil
+	}
+	root := f.yaml.Content[0]
+	if root.Kind == yaml.ScalarNode && root.Tag == "!!null" {
+		return nil
+	}
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s:%d: expected key: value pairs", f.Path, root.Line)
+	}
+	for i := 0; i < len(root.Content); i += 2 {
+		key, value := root.Content[i], resolveAlias(root.Content[i+1])
+		if value.Kind != yaml.MappingNode {
+			if err := f.addYAML("", key, value); err != nil {
+				return err
+			}
+			continue
+		}
+		for j := 0; j < len(value.Content); j += 2 {
+			if err := f.addYAML(key.Value, value.Content[j], resolveAlias(value.Content[j+1])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addYAML adds the entry of a YAML key and its value to the section.
+func (f *File) addYAML(section string, key, value *yaml.Node) error {
+	var text string
+	switch value.Kind {
+	case yaml.ScalarNode:
+		if value.Tag != "!!null" {
+			text = value.Value
+		}
+	case yaml.SequenceNode:
+		values := make([]string, len(value.Content))
+		for i, item := range value.Content {
+			if item = resolveAlias(item); item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("%s:%d: %s: expected a list of values", f.Path, item.Line, key.Value)
+			}
+			values[i] = item.Value
+		}
+		text = strings.Join(values, ",")
+	default:
+		if section != "" {
+			return fmt.Errorf("%s:%d: %s: expected a value, only the sections of the commands are mappings", f.Path, value.Line, key.Value)
+		}
+		return fmt.Errorf("%s:%d: %s: expected a value", f.Path, value.Line, key.Value)
+	}
+	f.sections[section] = append(f.sections[section], entry{key.Value, text, key.Line})
+	return nil
+}
+
+// resolveAlias returns the node an alias like *name stands for.
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// lookup returns the last value of the key in the section, else before any section.
+func (f *File) lookup(section, key string) (entry, bool) {
+	if f == nil {
+		return entry{}, false
+	}
+	if e, ok := f.lookupSection(section, key); ok && section != "" {
+		return e, true
+	}
+	return f.lookupSection("", key)
+}
+
+// Set writes the value of the key in the section, before any section for "",
+// replacing its line or adding one, and keeping the other lines and the comments as
+// they are.
+func (f *File) Set(section, key, value string) error {
+	var data []byte
+	if f.yaml != nil {
+		var err error
+		if data, err = f.setYAML(section, key, value); err != nil {
+			return err
+		}
+	} else {
+		data = []byte(strings.Join(f.setLine(section, key, value), "\n") + "\n")
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(f.Path, data, 0o600); err != nil {
+		return err
+	}
+	updated, err := ReadFile(f.Path, false)
+	if err != nil {
+		return err
+	}
+	*f = *updated
+	return nil
+}
+
+// setLine returns the lines of the file with the key = value line of the key set in
+// the section, which is added at the end when it's missing.
+func (f *File) setLine(section, key, value string) []string {
+	line := key + " = " + value
+	lines := f.lines
+	if e, ok := f.lookupSection(section, key); ok {
+		lines[e.line-1] = line
+		return lines
+	}
+	// the lines of a section go until the next one, the blank lines ending it aside
+	start, at := 0, len(lines)
+	if section != "" {
+		start = -1
+	}
+	for i, l := range lines {
+		t := strings.TrimSpace(l)
+		if !strings.HasPrefix(t, "[") || !strings.HasSuffix(t, "]") {
+			continue
+		}
+		if start >= 0 {
+			at = i
+			break
+		}
+		if strings.TrimSpace(t[1:len(t)-1]) == section {
+			start = i + 1
+		}
+	}
+	if start < 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		return append(lines, "["+section+"]", line)
+	}
+	for at > start && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+	return append(lines[:at], append([]string{line}, lines[at:]...)...)
+}
+
+// setYAML returns the YAML file with the value of the key set in the section, before
+// the sections when it's added to none. The comments are kept, the rest being written
+// by the encoder.
+func (f *File) setYAML(section, key, value string) ([]byte, error) {
+	if len(f.yaml.Content) == 0 || f.yaml.Content[0].Kind != yaml.MappingNode {
+		f.yaml = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	mapping := f.yaml.Content[0]
+	if section != "" {
+		mapping = yamlSection(mapping, section)
+	}
+	// the value is read back as text, it's only quoted when it has to be
+	scalar := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	if value == "" || value == "~" || strings.EqualFold(value, "null") {
+		scalar.Tag = "!!str"
+	}
+	found, at := -1, len(mapping.Content)
+	for i := 0; i < len(mapping.Content); i += 2 {
+		switch {
+		case resolveAlias(mapping.Content[i+1]).Kind == yaml.MappingNode:
+			at = min(at, i)
+		case mapping.Content[i].Value == key:
+			found = i
+		}
+	}
+	if found >= 0 {
+		old := mapping.Content[found+1]
+		scalar.LineComment, scalar.FootComment = old.LineComment, old.FootComment
+		mapping.Content[found+1] = scalar
+	} else {
+		mapping.Content = append(mapping.Content[:at], append([]*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, scalar}, mapping.Content[at:]...)...)
+	}
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(f.yaml); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// yamlSection returns the mapping of the section in root, the last one when it's
+// repeated, adding it at the end when it's missing.
+func yamlSection(root *yaml.Node, section string) *yaml.Node {
+	for i := len(root.Content) - 2; i >= 0; i -= 2 {
+		if value := resolveAlias(root.Content[i+1]); root.Content[i].Value == section && value.Kind == yaml.MappingNode {
+			return value
+		}
+	}
+	mapping := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: section}, mapping)
+	return mapping
+}
+
+// lookupSection returns the last value of the key in the section alone.
+func (f *File) lookupSection(section, key string) (entry, bool) {
+	entries := f.sections[section]
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].key == key {
+			return entries[i], true
+		}
+	}
+	return entry{}, false
+}
@@ -0,0 +1,250 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfig_Apply(t *testing.T) {
+	path := writeConfig(t, "toolrc", "# comment\naddr = 10.0.0.1:80\nretries = 3\npretty = true\n\n[get]\nretries = 5\n")
+	file, err := ReadFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TOOL_RETRIES", "7")
+	t.Setenv("TOOL_AUTH_TOKEN", "secret")
+
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:80", "")
+	retries := fs.Int("retries", 2, "")
+	pretty := fs.Bool("pretty", false, "")
+	token := fs.String("auth-token", "", "")
+	timeout := fs.String("timeout", "2s", "")
+	if err := fs.Parse([]string{"--pretty=false"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Config{File: file, EnvPrefix: "TOOL"}
+	settings, err := c.Apply("get", Flags(fs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *addr != "10.0.0.1:80" || *retries != 7 || *pretty || *token != "secret" || *timeout != "2s" {
+		t.Errorf("Apply() set addr=%s retries=%d pretty=%t auth-token=%s timeout=%s", *addr, *retries, *pretty, *token, *timeout)
+	}
+	want := []Setting{
+		{"addr", "10.0.0.1:80", SourceFile, path + ":2"},
+		{"auth-token", "secret", SourceEnv, "TOOL_AUTH_TOKEN"},
+		{"pretty", "false", SourceFlag, ""},
+		{"retries", "7", SourceEnv, "TOOL_RETRIES"},
+		{"timeout", "2s", SourceDefault, ""},
+	}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("Apply() = %v, want %v", settings, want)
+	}
+}
+
+func TestConfig_ApplySection(t *testing.T) {
+	path := writeConfig(t, "toolrc", "retries = 3\n[get]\nretries = 5\n")
+	file, err := ReadFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		section string
+		want    string
+	}{
+		{"get", "5"},
+		{"watch", "3"},
+		{"", "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.section, func(t *testing.T) {
+			fs := flag.NewFlagSet(tt.section, flag.ContinueOnError)
+			retries := fs.String("retries", "2", "")
+			if _, err := (&Config{File: file, EnvPrefix: "TOOL"}).Apply(tt.section, Flags(fs)); err != nil {
+				t.Fatal(err)
+			}
+			if *retries != tt.want {
+				t.Errorf("retries = %s, want %s", *retries, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_EnvAliases(t *testing.T) {
+	t.Setenv("TOOL_TOKEN", "old")
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	token := fs.String("auth-token", "", "")
+	c := &Config{EnvPrefix: "TOOL", EnvAliases: map[string][]string{"auth-token": {"TOOL_TOKEN"}}}
+	if _, err := c.Apply("", Flags(fs)); err != nil {
+		t.Fatal(err)
+	}
+	if *token != "old" {
+		t.Errorf("auth-token = %s, want old", *token)
+	}
+	t.Setenv("TOOL_AUTH_TOKEN", "new")
+	if _, err := c.Apply("", Flags(fs)); err != nil {
+		t.Fatal(err)
+	}
+	if *token != "new" {
+		t.Errorf("auth-token = %s, want new", *token)
+	}
+}
+
+func TestConfig_ApplyInvalid(t *testing.T) {
+	path := writeConfig(t, "toolrc", "retries = many\n")
+	file, err := ReadFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	fs.Int("retries", 2, "")
+	if _, err := (&Config{File: file, EnvPrefix: "TOOL"}).Apply("", Flags(fs)); err == nil {
+		t.Error("Apply() didn't fail on an invalid value")
+	}
+}
+
+func TestReadFile_YAML(t *testing.T) {
+	path := writeConfig(t, "tool.yaml", `---
+addr: "a" # "b"
+server: 10.0.0.1:80 # the prod server
+name: 'it''s'
+quoted: "a: b"
+empty: ""
+unset:
+notify: [stdout, desktop]
+exclude:
+  - node_modules
+  - '*.tmp'
+get:
+  addr: "c" # the section of get
+`)
+	file, err := ReadFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		section, key string
+		want         string
+		line         int
+	}{
+		{"", "addr", "a", 2},
+		{"", "server", "10.0.0.1:80", 3},
+		{"", "name", "it's", 4},
+		{"", "quoted", "a: b", 5},
+		{"", "empty", "", 6},
+		{"", "unset", "", 7},
+		{"", "notify", "stdout,desktop", 8},
+		{"", "exclude", "node_modules,*.tmp", 9},
+		{"get", "addr", "c", 13},
+		{"watch", "addr", "a", 2},
+	}
+	for _, tt := range tests {
+		if e, ok := file.lookup(tt.section, tt.key); !ok || e.value != tt.want || e.line != tt.line {
+			t.Errorf("[%s] %s = %q at line %d, want %q at line %d", tt.section, tt.key, e.value, e.line, tt.want, tt.line)
+		}
+	}
+	// the keys of a section aren't flags of their own
+	if e, ok := file.lookup("", "get"); ok {
+		t.Errorf("get = %q, want no such flag", e.value)
+	}
+}
+
+func TestReadFile_YAMLInvalid(t *testing.T) {
+	tests := []struct {
+		data    string
+		wantErr string
+	}{
+		{"addr: \"a\n", "found unexpected end of stream"},
+		{"- a\n- b\n", ":1: expected key: value pairs"},
+		{"get:\n  tls:\n    ca: ca.pem\n", ":3: tls: expected a value, only the sections of the commands are mappings"},
+		{"notify:\n  - [a, b]\n", ":2: notify: expected a list of values"},
+	}
+	for _, tt := range tests {
+		path := writeConfig(t, "tool.yaml", tt.data)
+		if _, err := ReadFile(path, false); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ReadFile(%q) error = %v, want %q", tt.data, err, tt.wantErr)
+		}
+	}
+	if file, err := ReadFile(writeConfig(t, "tool.yaml", "# nothing set\n"), false); err != nil || len(file.sections) != 0 {
+		t.Errorf("ReadFile() of comments only = %v, %v", file, err)
+	}
+}
+
+func TestReadFile_Missing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "toolrc")
+	if _, err := ReadFile(path, false); err == nil {
+		t.Error("ReadFile() didn't fail on a missing file")
+	}
+	if _, err := ReadFile(path, true); err != nil {
+		t.Errorf("ReadFile() of an optional file = %v", err)
+	}
+}
+
+func TestFile_Set(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		section string
+		key     string
+		value   string
+		want    string
+	}{
+		{"Replaced", "# servers\naddr = a\n\n[get]\naddr = b\n", "", "addr", "c", "# servers\naddr = c\n\n[get]\naddr = b\n"},
+		{"Added before the sections", "addr = a\n\n[get]\naddr = b\n", "", "retries", "3", "addr = a\nretries = 3\n\n[get]\naddr = b\n"},
+		{"New file", "", "", "retries", "3", "retries = 3\n"},
+		{"Section replaced", "addr = a\n\n[get]\naddr = b\n", "get", "addr", "c", "addr = a\n\n[get]\naddr = c\n"},
+		{"Added to the section", "[get]\naddr = b\n\n[ping]\ncount = 2\n", "get", "retries", "3", "[get]\naddr = b\nretries = 3\n\n[ping]\ncount = 2\n"},
+		{"Section added", "addr = a\n", "get", "retries", "3", "addr = a\n\n[get]\nretries = 3\n"},
+		{"YAML", "addr: a\n", "", "author", "Ann: Smith", "addr: a\nauthor: 'Ann: Smith'\n"},
+		{"YAML replaced", "# servers\naddr: \"a\" # \"b\"\nget:\n  addr: b\n", "", "addr", "c", "# servers\naddr: c # \"b\"\nget:\n  addr: b\n"},
+		{"YAML added before the sections", "addr: a\nget:\n  addr: b\n", "", "retries", "3", "addr: a\nretries: 3\nget:\n  addr: b\n"},
+		{"YAML new file", "", "", "retries", "3", "retries: 3\n"},
+		{"YAML section replaced", "addr: a\nget:\n  addr: b # old\n", "get", "addr", "c", "addr: a\nget:\n  addr: c # old\n"},
+		{"YAML added to the section", "get:\n  addr: b\nping:\n  count: 2\n", "get", "retries", "3", "get:\n  addr: b\n  retries: 3\nping:\n  count: 2\n"},
+		{"YAML section added", "addr: a\n", "get", "retries", "3", "addr: a\nget:\n  retries: 3\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := "toolrc"
+			if strings.HasPrefix(tt.name, "YAML") {
+				name = "tool.yaml"
+			}
+			path := filepath.Join(t.TempDir(), name)
+			if tt.data != "" {
+				path = writeConfig(t, name, tt.data)
+			}
+			file, err := ReadFile(path, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := file.Set(tt.section, tt.key, tt.value); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("Set() wrote %q, want %q", b, tt.want)
+			}
+			if e, ok := file.lookupSection(tt.section, tt.key); !ok || e.value != tt.value {
+				t.Errorf("%s = %q after Set(), want %q", tt.key, e.value, tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+module clikit
+
+go 1.21.5
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,173 @@
+// Package logging sets up the diagnostics of the tools, written with log/slog to
+// stderr. They're text by default, the way the tools always wrote them, like
+// "warning: message key=value", or JSON lines to be shipped somewhere else. Every tool
+// has a default level, --verbose showing more and --quiet only the errors.
+package logging
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Options are the values of the logging flags.
+type Options struct {
+	Verbose int    // levels shown below the default one, -v being 1 and -vv 2
+	Quiet   bool   // only show the errors
+	Format  string // text or json
+}
+
+// Level returns the level shown from the default one of the tool, lowered by one for
+// every -v down to debug.
+func (o Options) Level(base slog.Level) slog.Level {
+	if o.Quiet {
+		return slog.LevelError
+	}
+	return max(slog.LevelDebug, base-slog.Level(4*o.Verbose))
+}
+
+// AddFlags adds -v/--verbose, that can be repeated, -q/--quiet and --log-format to
+// fs, setting o.
+func AddFlags(fs *flag.FlagSet, o *Options) {
+	o.Format = "text"
+	fs.Var((*count)(&o.Verbose), "v", "Log more of what is done, repeated to log everything")
+	fs.Var((*count)(&o.Verbose), "verbose", "Log more of what is done, repeated to log everything")
+	fs.BoolVar(&o.Quiet, "q", false, "Only log the errors")
+	fs.BoolVar(&o.Quiet, "quiet", false, "Only log the errors")
+	fs.StringVar(&o.Format, "log-format", "text", "Format of the logs written to stderr: text or json")
+}
+
+// count is a flag that's incremented every time it's given, or set to a number.
+type count int
+
+func (c *count) String() string {
+	if c == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*c))
+}
+
+func (c *count) Set(value string) error {
+	if value == "true" {
+		*c++
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return errors.New("has to be a positive number")
+	}
+	*c = count(n)
+	return nil
+}
+
+func (c *count) IsBoolFlag() bool {
+	return true
+}
+
+// Setup sets the default logger of slog, writing to w in the format of o from the
+// level of o, base being the default one of the tool.
+func Setup(w io.Writer, o Options, base slog.Level) error {
+	level := o.Level(base)
+	// the errors below are written in text whatever the format
+	slog.SetDefault(slog.New(NewTextHandler(w, level)))
+	if o.Quiet && o.Verbose > 0 {
+		return errors.New("quiet and verbose can't be combined")
+	}
+	switch o.Format {
+	case "text", "":
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})))
+	default:
+		return errors.New("log-format has to be either text or json")
+	}
+	return nil
+}
+
+// textHandler writes the records the way the tools always wrote their diagnostics,
+// like "warning: message", followed by the attributes as key=value.
+type textHandler struct {
+	w      io.Writer
+	level  slog.Level
+	attrs  string // formatted already
+	prefix string // of the keys, from the groups
+	mu     *sync.Mutex
+}
+
+// NewTextHandler returns a handler writing the records from level to w as text.
+func NewTextHandler(w io.Writer, level slog.Level) slog.Handler {
+	return &textHandler{w: w, level: level, mu: &sync.Mutex{}}
+}
+
+func (h *textHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(ctx context.Context, r slog.Record) error {
+	var b strings.Builder
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString("error: ")
+	case r.Level >= slog.LevelWarn:
+		b.WriteString("warning: ")
+	case r.Level >= slog.LevelInfo:
+		b.WriteString("info: ")
+	default:
+		b.WriteString("debug: ")
+	}
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&b, h.prefix, a)
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	for _, a := range attrs {
+		writeAttr(&b, h.prefix, a)
+	}
+	h2 := *h
+	h2.attrs += b.String()
+	return &h2
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.prefix += name + "."
+	return &h2
+}
+
+// writeAttr writes " key=value", quoting the value when it has to be.
+func writeAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			writeAttr(b, prefix, ga)
+		}
+		return
+	}
+	v := a.Value.String()
+	if v == "" || strings.ContainsAny(v, " =\"\t\n") {
+		v = strconv.Quote(v)
+	}
+	fmt.Fprintf(b, " %s%s=%s", prefix, a.Key, v)
+}
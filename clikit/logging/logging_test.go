@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"testing"
+)
+
+func TestOptions_Level(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		base    slog.Level
+		want    slog.Level
+	}{
+		{"Default", Options{}, slog.LevelWarn, slog.LevelWarn},
+		{"Verbose", Options{Verbose: 1}, slog.LevelWarn, slog.LevelInfo},
+		{"Very verbose", Options{Verbose: 2}, slog.LevelWarn, slog.LevelDebug},
+		{"Below debug", Options{Verbose: 3}, slog.LevelInfo, slog.LevelDebug},
+		{"Quiet", Options{Quiet: true}, slog.LevelInfo, slog.LevelError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.options.Level(tt.base); got != tt.want {
+				t.Errorf("Level() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddFlags(t *testing.T) {
+	var o Options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	AddFlags(fs, &o)
+	if err := fs.Parse([]string{"-v", "--verbose", "--log-format=json"}); err != nil {
+		t.Fatal(err)
+	}
+	if o != (Options{Verbose: 2, Format: "json"}) {
+		t.Errorf("parsed %+v", o)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+	}{
+		{"Text", Options{Format: "text"}, false},
+		{"JSON", Options{Format: "json"}, false},
+		{"Unknown format", Options{Format: "xml"}, true},
+		{"Quiet and verbose", Options{Quiet: true, Verbose: 1}, true},
+	}
+	defer slog.SetDefault(slog.Default())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Setup(&bytes.Buffer{}, tt.options, slog.LevelWarn); (err != nil) != tt.wantErr {
+				t.Errorf("Setup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTextHandler(t *testing.T) {
+	var b bytes.Buffer
+	logger := slog.New(NewTextHandler(&b, slog.LevelInfo)).With("server", "127.0.0.1:80").WithGroup("call")
+	logger.Debug("hidden")
+	logger.Info("retrying", "wait", "1s", "err", "connection refused")
+	logger.Error("failed", slog.Group("tls", "version", ""))
+	want := "info: retrying server=127.0.0.1:80 call.wait=1s call.err=\"connection refused\"\n" +
+		"error: failed server=127.0.0.1:80 call.tls.version=\"\"\n"
+	if b.String() != want {
+		t.Errorf("wrote %q, want %q", b.String(), want)
+	}
+}
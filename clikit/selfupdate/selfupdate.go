@@ -0,0 +1,420 @@
+// Package selfupdate replaces the running executable with its build in the latest
+// release of the project. The releases are read from the GitHub API, every one having
+// the builds of the tools as assets named after the tool and the platform, like
+// goclitool_linux_amd64 or simpleCli_windows_amd64.exe, and a checksums.txt listing
+// their SHA-256 the way sha256sum writes them.
+//
+// When the tools are built with a public key, checksums.txt has to be signed with its
+// private key too, checksums.txt.sig being the base64 ed25519 signature:
+//
+//	openssl genpkey -algorithm ed25519 -out release.pem
+//	openssl pkey -in release.pem -pubout -outform DER | tail -c 32 | base64   # PublicKey
+//	openssl pkeyutl -sign -inkey release.pem -rawin -in checksums.txt | base64 > checksums.txt.sig
+//
+// The stable channel only has the releases, the beta one the pre-releases too.
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"clikit/clierr"
+)
+
+// The build metadata, set when building a release with
+//
+//	go build -ldflags "-X clikit/selfupdate.Version=1.2.0 -X clikit/selfupdate.PublicKey=..."
+//
+// Version is only the one of the tools without their own, see CurrentVersion.
+var (
+	Version   string
+	PublicKey string // base64 ed25519 key checksums.txt is signed with, not checked when empty
+	Endpoint  = "https://api.github.com/repos/odogwuVal/GoCliWithFlags/releases"
+)
+
+// The channels of the releases.
+const (
+	Stable = "stable"
+	Beta   = "beta"
+)
+
+// Options are the values of the selfupdate flags.
+type Options struct {
+	Channel   string // stable or beta
+	CheckOnly bool   // only tell whether there's a newer release
+	Endpoint  string // URL of the list of the releases
+}
+
+// AddFlags adds --channel, --check-only and --endpoint to fs, setting o.
+func AddFlags(fs *flag.FlagSet, o *Options) {
+	fs.StringVar(&o.Channel, "channel", Stable, "Channel of the releases: stable, or beta for the pre-releases too")
+	fs.BoolVar(&o.CheckOnly, "check-only", false, "Only tell whether a newer release is available, without installing it")
+	fs.StringVar(&o.Endpoint, "endpoint", Endpoint, "URL of the list of the releases, in the format of the GitHub API")
+}
+
+// CurrentVersion returns the version of the running tool, Version else the module
+// version go install embeds, else "dev".
+func CurrentVersion() string {
+	if Version != "" {
+		return Version
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		return bi.Main.Version
+	}
+	return "dev"
+}
+
+// Release is a release of the project.
+type Release struct {
+	Version    string  `json:"tag_name"`
+	Prerelease bool    `json:"prerelease"`
+	Draft      bool    `json:"draft"`
+	Assets     []Asset `json:"assets"`
+}
+
+// Asset is a file of a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+func (r *Release) asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Updater updates a tool.
+type Updater struct {
+	Tool    string       // name of the tool in the assets, like goclitool
+	Version string       // version running, the releases being newer or not
+	Path    string       // executable replaced, the running one when empty
+	Client  *http.Client // http.DefaultClient when nil
+	Out     io.Writer    // where the result is printed, os.Stdout when nil
+}
+
+// Run checks the latest release of the channel and, unless o.CheckOnly, replaces the
+// executable with its build when it's newer than the running one.
+func (u *Updater) Run(ctx context.Context, o Options) error {
+	if o.Channel != Stable && o.Channel != Beta {
+		return clierr.Errorf(clierr.Usage, "channel has to be either %s or %s", Stable, Beta)
+	}
+	if o.Endpoint == "" {
+		o.Endpoint = Endpoint
+	}
+	out := u.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	release, err := u.latest(ctx, o.Endpoint, o.Channel)
+	if err != nil {
+		return err
+	}
+	if compareVersions(release.Version, u.Version) <= 0 {
+		fmt.Fprintf(out, "%s %s is up to date, %s is the latest %s release\n", u.Tool, u.Version, release.Version, o.Channel)
+		return nil
+	}
+	fmt.Fprintf(out, "%s %s is available, this is %s\n", u.Tool, release.Version, u.Version)
+	if o.CheckOnly {
+		return nil
+	}
+
+	name := fmt.Sprintf("%s_%s_%s", u.Tool, runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	asset, ok := release.asset(name)
+	if !ok {
+		return clierr.Errorf(clierr.Validation, "release %s has no %s build for %s/%s", release.Version, u.Tool, runtime.GOOS, runtime.GOARCH)
+	}
+	sum, err := u.checksum(ctx, release, name)
+	if err != nil {
+		return err
+	}
+	path, err := u.executable()
+	if err != nil {
+		return err
+	}
+	slog.Info("downloading", "url", asset.URL)
+	body, err := u.get(ctx, asset.URL)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if err := replace(path, body, sum); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s updated to %s\n", path, release.Version)
+	return nil
+}
+
+// latest returns the newest release of the channel.
+func (u *Updater) latest(ctx context.Context, endpoint, channel string) (*Release, error) {
+	body, err := u.get(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	var releases []Release
+	if err := json.NewDecoder(body).Decode(&releases); err != nil {
+		return nil, clierr.Errorf(clierr.Validation, "%s: %w", endpoint, err)
+	}
+	var latest *Release
+	for i, r := range releases {
+		if r.Draft || (r.Prerelease && channel != Beta) {
+			continue
+		}
+		if latest == nil || compareVersions(r.Version, latest.Version) > 0 {
+			latest = &releases[i]
+		}
+	}
+	if latest == nil {
+		return nil, clierr.Errorf(clierr.Validation, "no %s release found at %s", channel, endpoint)
+	}
+	return latest, nil
+}
+
+// checksum returns the SHA-256 of the asset listed by checksums.txt, once its
+// signature is verified.
+func (u *Updater) checksum(ctx context.Context, release *Release, name string) ([]byte, error) {
+	checksums, err := u.download(ctx, release, "checksums.txt")
+	if err != nil {
+		return nil, err
+	}
+	if PublicKey == "" {
+		slog.Warn("no public key built in, only the checksum of the release is verified")
+	} else {
+		signature, err := u.download(ctx, release, "checksums.txt.sig")
+		if err != nil {
+			return nil, err
+		}
+		if err := verify(checksums, signature); err != nil {
+			return nil, err
+		}
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		// like "<sum>  goclitool_linux_amd64", or "<sum> *..." for the binary mode
+		sum, file, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || strings.TrimLeft(file, " *") != name {
+			continue
+		}
+		b, err := hex.DecodeString(sum)
+		if err != nil || len(b) != sha256.Size {
+			return nil, clierr.Errorf(clierr.Validation, "checksums.txt: the checksum of %s isn't a SHA-256", name)
+		}
+		return b, nil
+	}
+	return nil, clierr.Errorf(clierr.Validation, "checksums.txt of %s has no checksum for %s", release.Version, name)
+}
+
+// verify checks the base64 ed25519 signature of data with PublicKey.
+func verify(data, signature []byte) error {
+	key, err := base64.StdEncoding.DecodeString(PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return clierr.Errorf(clierr.Internal, "the public key built in isn't a base64 ed25519 key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || !ed25519.Verify(key, data, sig) {
+		return clierr.Errorf(clierr.Validation, "the signature of checksums.txt doesn't match, the release isn't trusted")
+	}
+	return nil
+}
+
+// download returns the content of a small asset of the release.
+func (u *Updater) download(ctx context.Context, release *Release, name string) ([]byte, error) {
+	asset, ok := release.asset(name)
+	if !ok {
+		return nil, clierr.Errorf(clierr.Validation, "release %s has no %s", release.Version, name)
+	}
+	body, err := u.get(ctx, asset.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, clierr.Wrap(clierr.Network, err)
+	}
+	return b, nil
+}
+
+func (u *Updater) get(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, clierr.Wrap(clierr.Usage, err)
+	}
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, clierr.Wrap(clierr.Network, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, clierr.Errorf(clierr.Network, "%s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// executable returns the file replaced, the one a symbolic link like qotd points to.
+func (u *Updater) executable() (string, error) {
+	path := u.Path
+	if path == "" {
+		var err error
+		if path, err = os.Executable(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.EvalSymlinks(path)
+}
+
+// goos and rename are runtime.GOOS and os.Rename, but for the tests of replace.
+var (
+	goos   = runtime.GOOS
+	rename = os.Rename
+)
+
+// replace writes r next to path and renames it over path once its SHA-256 is sum, so
+// the executable is either the old one or the new one. A running executable can't be
+// replaced on Windows, it's renamed to path.old first, and back when the new one
+// can't take its place.
+func replace(path string, r io.Reader, sum []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".new-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+		tmp.Close()
+		return clierr.Wrap(clierr.Network, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if !bytes.Equal(h.Sum(nil), sum) {
+		return clierr.Errorf(clierr.Validation, "the checksum of the download doesn't match checksums.txt")
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	if goos != "windows" {
+		return rename(tmp.Name(), path)
+	}
+	old := path + ".old"
+	os.Remove(old)
+	if err := rename(path, old); err != nil {
+		return err
+	}
+	if err := rename(tmp.Name(), path); err != nil {
+		if restoreErr := rename(old, path); restoreErr != nil {
+			return fmt.Errorf("%w, and %s couldn't be restored: %v", err, path, restoreErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// compareVersions compares versions like v1.2.3 or 1.3.0-beta.1, the pre-releases
+// being older than their release. The ones that aren't, like dev, are older than
+// all of them.
+func compareVersions(a, b string) int {
+	va, aok := parseVersion(a)
+	vb, bok := parseVersion(b)
+	switch {
+	case !aok && !bok:
+		return 0
+	case !aok:
+		return -1
+	case !bok:
+		return 1
+	}
+	for i := range va.numbers {
+		if va.numbers[i] != vb.numbers[i] {
+			return cmp.Compare(va.numbers[i], vb.numbers[i])
+		}
+	}
+	switch {
+	case va.pre == vb.pre:
+		return 0
+	case va.pre == "":
+		return 1
+	case vb.pre == "":
+		return -1
+	}
+	return comparePrerelease(va.pre, vb.pre)
+}
+
+type version struct {
+	numbers [3]int
+	pre     string
+}
+
+func parseVersion(s string) (version, bool) {
+	var v version
+	s, _, _ = strings.Cut(strings.TrimPrefix(s, "v"), "+")
+	s, v.pre, _ = strings.Cut(s, "-")
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v.numbers[i] = n
+	}
+	return v, true
+}
+
+// comparePrerelease compares the dot separated identifiers the way semver does,
+// numerically when they're numbers, so beta.10 is newer than beta.9.
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		na, errA := strconv.Atoi(as[i])
+		nb, errB := strconv.Atoi(bs[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return cmp.Compare(na, nb)
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		case as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
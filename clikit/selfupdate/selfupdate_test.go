@@ -0,0 +1,200 @@
+package selfupdate
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"clikit/clierr"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "1.2.3", 0},
+		{"v1.10.0", "v1.9.0", 1},
+		{"v1.2.0", "v1.2.0-beta.1", 1},
+		{"v1.2.0-beta.10", "v1.2.0-beta.9", 1},
+		{"v1.2.0-alpha", "v1.2.0-beta", -1},
+		{"v0.1.0", "dev", 1},
+		{"dev", "dev", 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// releaseServer serves the releases of the GitHub API, v1.1.0 and the pre-release
+// v1.2.0-beta.1, having the build of the tool with its checksum, signed when
+// privateKey isn't nil.
+func releaseServer(t *testing.T, build []byte, privateKey ed25519.PrivateKey) *httptest.Server {
+	name := fmt.Sprintf("tool_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	sum := sha256.Sum256(build)
+	checksums := []byte(fmt.Sprintf("%x  other_linux_amd64\n%x  %s\n", sha256.Sum256(nil), sum, name))
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/releases", func(w http.ResponseWriter, r *http.Request) {
+		var releases []Release
+		for _, r := range []Release{{Version: "v1.2.0-beta.1", Prerelease: true}, {Version: "v1.1.0"}, {Version: "v2.0.0", Draft: true}} {
+			for _, asset := range []string{name, "checksums.txt", "checksums.txt.sig"} {
+				r.Assets = append(r.Assets, Asset{asset, server.URL + "/download/" + r.Version + "/" + asset})
+			}
+			releases = append(releases, r)
+		}
+		json.NewEncoder(w).Encode(releases)
+	})
+	mux.HandleFunc("/download/", func(w http.ResponseWriter, r *http.Request) {
+		switch filepath.Base(r.URL.Path) {
+		case name:
+			w.Write(build)
+		case "checksums.txt":
+			w.Write(checksums)
+		case "checksums.txt.sig":
+			if privateKey == nil {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintln(w, base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, checksums)))
+		}
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestUpdater_Run(t *testing.T) {
+	build := []byte("the new build")
+	server := releaseServer(t, build, nil)
+	tests := []struct {
+		name      string
+		version   string
+		options   Options
+		want      string
+		wantBuild bool
+	}{
+		{"Up to date", "v1.1.0", Options{Channel: Stable}, "tool v1.1.0 is up to date, v1.1.0 is the latest stable release\n", false},
+		{"Check only", "v1.0.0", Options{Channel: Stable, CheckOnly: true}, "tool v1.1.0 is available, this is v1.0.0\n", false},
+		{"Update", "v1.0.0", Options{Channel: Stable}, "tool v1.1.0 is available, this is v1.0.0\n", true},
+		{"Beta", "v1.1.0", Options{Channel: Beta}, "tool v1.2.0-beta.1 is available, this is v1.1.0\n", true},
+		{"Dev build", "dev", Options{Channel: Stable}, "tool v1.1.0 is available, this is dev\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tool")
+			if err := os.WriteFile(path, []byte("the old build"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			u := Updater{Tool: "tool", Version: tt.version, Path: path, Out: &out}
+			tt.options.Endpoint = server.URL + "/releases"
+			if err := u.Run(context.Background(), tt.options); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !strings.HasPrefix(out.String(), tt.want) {
+				t.Errorf("Run() printed %q, want %q", out.String(), tt.want)
+			}
+			got, _ := os.ReadFile(path)
+			if updated := bytes.Equal(got, build); updated != tt.wantBuild {
+				t.Errorf("executable is %q", got)
+			}
+			if info, _ := os.Stat(path); runtime.GOOS != "windows" && info.Mode().Perm() != 0755 {
+				t.Errorf("executable mode is %v", info.Mode())
+			}
+		})
+	}
+}
+
+func TestUpdater_Run_signature(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(nil)
+	_, otherPrivate, _ := ed25519.GenerateKey(nil)
+	defer func(key string) { PublicKey = key }(PublicKey)
+	PublicKey = base64.StdEncoding.EncodeToString(public)
+	tests := []struct {
+		name       string
+		privateKey ed25519.PrivateKey
+		wantErr    bool
+	}{
+		{"Signed", private, false},
+		{"Signed with another key", otherPrivate, true},
+		{"Not signed", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := releaseServer(t, []byte("the new build"), tt.privateKey)
+			path := filepath.Join(t.TempDir(), "tool")
+			if err := os.WriteFile(path, []byte("the old build"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			u := Updater{Tool: "tool", Version: "v1.0.0", Path: path, Out: &bytes.Buffer{}}
+			err := u.Run(context.Background(), Options{Channel: Stable, Endpoint: server.URL + "/releases"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got, _ := os.ReadFile(path); tt.wantErr && string(got) != "the old build" {
+				t.Errorf("executable replaced by %q", got)
+			}
+		})
+	}
+}
+
+func TestReplace_checksumMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(path, []byte("the old build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("the new build"))
+	err := replace(path, strings.NewReader("tampered"), sum[:])
+	if clierr.CategoryOf(err) != clierr.Validation {
+		t.Errorf("replace() error = %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "the old build" {
+		t.Errorf("executable replaced by %q", got)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("temporary file left: %v", entries)
+	}
+}
+
+func TestReplace_windowsRestored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool.exe")
+	if err := os.WriteFile(path, []byte("the old build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// the running executable moves aside, but the new one can't take its place
+	goos, rename = "windows", func(from, to string) error {
+		if to == path && !strings.HasSuffix(from, ".old") {
+			return errors.New("access denied")
+		}
+		return os.Rename(from, to)
+	}
+	t.Cleanup(func() { goos, rename = runtime.GOOS, os.Rename })
+	sum := sha256.Sum256([]byte("the new build"))
+	if err := replace(path, strings.NewReader("the new build"), sum[:]); err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("replace() error = %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "the old build" {
+		t.Errorf("executable left as %q", got)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("files left: %v", entries)
+	}
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"clikit/clierr"
+
+	"goclitool/output"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/status"
+)
+
+// benchResult is what bench prints.
+type benchResult struct {
+	Server      string
+	Concurrency int
+	Seconds     float64 // the bench lasted
+	Requests    int
+	Errors      int
+	Throughput  float64        // answered requests per second
+	LatencyMs   benchLatency   // of the answered requests, in milliseconds
+	ErrorCodes  map[string]int // number of errors by gRPC code
+}
+
+// benchLatency are the statistics of the latencies, in milliseconds.
+type benchLatency struct {
+	Min, Mean, P50, P90, P95, P99, Max float64
+}
+
+// benchCmd represents the bench command
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Load tests the QOTD server and reports its throughput and latencies",
+	Long: `This command calls the QOTD server from --concurrency workers for --duration,
+each making its next call as soon as the last one is answered, then reports the
+throughput, the percentiles of the latency of the answered calls, and the errors
+by gRPC code. Interrupting it with Ctrl-C reports what was measured so far.
+
+It connects like get, TLS, token and proxy included. Every call is made once with
+the deadline of --timeout, the retries are off so the errors are all counted.
+-o json prints the report on one line, for the dashboards.
+
+Example usage:
+qotd bench --concurrency=50 --duration=30s -o json
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		concurrency, duration := viper.GetInt("concurrency"), viper.GetDuration("duration")
+		if concurrency < 1 {
+			fatal(clierr.Errorf(clierr.Usage, "concurrency has to be at least 1"))
+		}
+		if duration <= 0 {
+			fatal(clierr.Errorf(clierr.Usage, "duration has to be positive"))
+		}
+		p, err := newPrinter()
+		if err != nil {
+			fatal(err)
+		}
+		c, err := newClient(cmd.Flags())
+		if err != nil {
+			fatal(err)
+		}
+		defer c.Close()
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		r := runBench(ctx, c, viper.GetString("author"), concurrency, duration)
+		if viper.GetString("output") == "text" {
+			err = printBench(os.Stdout, r)
+		} else {
+			err = p.Print(os.Stdout, r)
+		}
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+// runBench calls the server from concurrency workers until the duration is over or
+// ctx is done. The calls cut short by the end aren't counted.
+func runBench(ctx context.Context, c *qotdClient, author string, concurrency int, duration time.Duration) benchResult {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	var mu sync.Mutex
+	var latencies []time.Duration
+	codes := map[string]int{}
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every worker keeps its own latencies, merged at the end
+			var mine []time.Duration
+			myCodes := map[string]int{}
+			for ctx.Err() == nil {
+				callCtx, cancelCall := context.WithTimeout(ctx, c.timeout)
+				callStart := time.Now()
+				_, err := c.transport.getQOTD(callCtx, author)
+				latency := time.Since(callStart)
+				cancelCall()
+				if ctx.Err() != nil {
+					break
+				}
+				if err != nil {
+					myCodes[status.Code(err).String()]++
+					continue
+				}
+				mine = append(mine, latency)
+			}
+			mu.Lock()
+			latencies = append(latencies, mine...)
+			for code, n := range myCodes {
+				codes[code] += n
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	r := benchResult{
+		Server:      c.transport.server(),
+		Concurrency: concurrency,
+		Seconds:     elapsed.Seconds(),
+		Requests:    len(latencies),
+		Throughput:  float64(len(latencies)) / elapsed.Seconds(),
+		ErrorCodes:  codes,
+	}
+	for _, n := range codes {
+		r.Errors += n
+	}
+	r.Requests += r.Errors
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		var total time.Duration
+		for _, l := range latencies {
+			total += l
+		}
+		r.LatencyMs = benchLatency{
+			Min:  ms(latencies[0]),
+			Mean: ms(total / time.Duration(len(latencies))),
+			P50:  ms(percentile(latencies, 50)),
+			P90:  ms(percentile(latencies, 90)),
+			P95:  ms(percentile(latencies, 95)),
+			P99:  ms(percentile(latencies, 99)),
+			Max:  ms(latencies[len(latencies)-1]),
+		}
+	}
+	return r
+}
+
+// percentile returns the p-th percentile of the sorted latencies, by nearest rank.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
+
+// ms returns d in milliseconds, to the microsecond.
+func ms(d time.Duration) float64 {
+	return float64(d.Round(time.Microsecond)) / float64(time.Millisecond)
+}
+
+// printBench writes the report for a person to read.
+func printBench(w io.Writer, r benchResult) error {
+	fmt.Fprintf(w, "%s, %d workers, %.1fs\n", r.Server, r.Concurrency, r.Seconds)
+	fmt.Fprintf(w, "requests:    %d, %d answered, %d errors\n", r.Requests, r.Requests-r.Errors, r.Errors)
+	fmt.Fprintf(w, "throughput:  %.1f answered/s\n", r.Throughput)
+	if r.Requests > r.Errors {
+		l := r.LatencyMs
+		fmt.Fprintf(w, "latency ms:  min %.3f, mean %.3f, p50 %.3f, p90 %.3f, p95 %.3f, p99 %.3f, max %.3f\n", l.Min, l.Mean, l.P50, l.P90, l.P95, l.P99, l.Max)
+	}
+	codes := make([]string, 0, len(r.ErrorCodes))
+	for code := range r.ErrorCodes {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return r.ErrorCodes[codes[i]] > r.ErrorCodes[codes[j]] })
+	for _, code := range codes {
+		fmt.Fprintf(w, "errors:      %d %s\n", r.ErrorCodes[code], code)
+	}
+	return nil
+}
+
+func init() {
+	qotdCmd.AddCommand(benchCmd)
+
+	// Adds the flags of the connection to the server, see addConnFlags
+	// Adds a flag called --author that can be shortened to -a
+	// Adds a flag called --concurrency that defaults to 50
+	// Adds a flag called --duration that defaults to 30s
+	// Adds a flag called --output that can be shortened to -o and defaults to text
+	addConnFlags(benchCmd.Flags())
+	benchCmd.Flags().StringP("author", "a", "", "Specify the author to get quotes for")
+	benchCmd.Flags().Int("concurrency", 50, "Number of workers calling the server at the same time")
+	benchCmd.Flags().Duration("duration", 30*time.Second, "Time the bench lasts")
+	output.AddFlag(benchCmd.Flags())
+}
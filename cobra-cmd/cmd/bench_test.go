@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_percentile(t *testing.T) {
+	ten := make([]time.Duration, 10)
+	for i := range ten {
+		ten[i] = time.Duration(i+1) * time.Millisecond
+	}
+	tests := []struct {
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{ten, 50, 5 * time.Millisecond},
+		{ten, 90, 9 * time.Millisecond},
+		{ten, 95, 10 * time.Millisecond},
+		{ten, 99, 10 * time.Millisecond},
+		{ten, 100, 10 * time.Millisecond},
+		{ten, 0, time.Millisecond},
+		{ten, 10, time.Millisecond},
+		{ten, 11, 2 * time.Millisecond},
+		{[]time.Duration{7}, 50, 7},
+		{[]time.Duration{7}, 99, 7},
+		{[]time.Duration{1, 2}, 50, 1},
+		{[]time.Duration{1, 2}, 51, 2},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%d latencies, %v) = %v, want %v", len(tt.sorted), tt.p, got, tt.want)
+		}
+	}
+}
+
+func Test_ms(t *testing.T) {
+	if got := ms(1500*time.Microsecond + 400*time.Nanosecond); got != 1.5 {
+		t.Errorf("ms() = %v, want 1.5", got)
+	}
+}
+
+// benchTransport answers every call after a millisecond, failing every third one.
+type benchTransport struct {
+	fakeTransport
+	calls atomic.Int64
+}
+
+func (t *benchTransport) getQOTD(ctx context.Context, author string) (quote, error) {
+	time.Sleep(time.Millisecond)
+	if t.calls.Add(1)%3 == 0 {
+		return quote{}, status.Error(codes.ResourceExhausted, "slow down")
+	}
+	return quote{"a", "q"}, nil
+}
+
+func Test_runBench(t *testing.T) {
+	c := &qotdClient{transport: &benchTransport{}, timeout: time.Second}
+	r := runBench(context.Background(), c, "", 4, 200*time.Millisecond)
+	if r.Requests < 20 || r.Errors == 0 || r.Errors != r.ErrorCodes["ResourceExhausted"] || len(r.ErrorCodes) != 1 {
+		t.Fatalf("runBench() = %+v", r)
+	}
+	if answered := r.Requests - r.Errors; answered < r.Errors || answered > 3*r.Errors {
+		t.Errorf("runBench() counted %d answers and %d errors, want 2 answers per error", answered, r.Errors)
+	}
+	if r.Seconds < 0.2 || r.Seconds > 1 || r.Throughput <= 0 {
+		t.Errorf("runBench() lasted %vs, throughput %v", r.Seconds, r.Throughput)
+	}
+	l := r.LatencyMs
+	if l.Min < 1 || !(l.Min <= l.P50 && l.P50 <= l.P90 && l.P90 <= l.P95 && l.P95 <= l.P99 && l.P99 <= l.Max) || l.Mean < l.Min || l.Mean > l.Max {
+		t.Errorf("runBench() latencies = %+v", l)
+	}
+
+	var b strings.Builder
+	if err := printBench(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"fake, 4 workers", "answered/s", "latency ms:  min", "ResourceExhausted"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("printBench() = %q, want %q in it", b.String(), want)
+		}
+	}
+}
+
+func Test_printBench_noAnswer(t *testing.T) {
+	var b strings.Builder
+	printBench(&b, benchResult{Server: "s", Concurrency: 1, Requests: 2, Errors: 2, ErrorCodes: map[string]int{"Unavailable": 2}})
+	if strings.Contains(b.String(), "latency") || !strings.Contains(b.String(), "errors:      2 Unavailable") {
+		t.Errorf("printBench() = %q", b.String())
+	}
+}
@@ -0,0 +1,345 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"clikit/clierr"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/reflow/truncate"
+	"github.com/muesli/reflow/wordwrap"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// browseCmd represents the browse command
+var browseCmd = &cobra.Command{
+	Use:   "browse",
+	Short: "Browses the quotes fetched before in a terminal UI",
+	Long: `This command opens a terminal UI listing the authors on the left and their
+quotes on the right, from the cache and the history. The server can't list its
+authors, so r fetches a new quote of the selected author to add to them.
+
+Keys:
+  up/down, j/k   move in the list
+  tab, left/right switch between the authors and the quotes (also h/l)
+  /              search the authors and the quotes, enter to keep, esc to clear
+  f              mark or unmark the selected quote as a favorite
+  F              only show the favorites
+  r              fetch a new quote of the selected author from the server
+  q, ctrl+c      quit
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+			fatal(clierr.Errorf(clierr.Usage, "browse needs a terminal"))
+		}
+		m, err := newBrowseModel()
+		if err != nil {
+			fatal(err)
+		}
+		if !viper.GetBool("offline") {
+			c, err := newClient(cmd.Flags())
+			if err != nil {
+				fatal(err)
+			}
+			defer c.Close()
+			m.client, m.ctx = c, cmd.Context()
+		}
+		if err := tea.NewProgram(m, tea.WithAltScreen()).Start(); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+// fetchedMsg is the answer of the server to r.
+type fetchedMsg struct {
+	quote quote
+	err   error
+}
+
+// browseModel is the state of the browse UI.
+type browseModel struct {
+	quotes        map[string][]quote // by author in lower case
+	names         map[string]string  // name shown of the authors in lower case
+	favorites     []quote
+	search        string
+	searching     bool // whether the keys are typed in the search
+	onlyFavorites bool
+	onQuotes      bool // whether the quotes have the focus, rather than the authors
+	author, quote int  // selected author and quote, in the ones shown
+	width, height int
+	status        string
+	client        *qotdClient // nil when offline
+	ctx           context.Context
+}
+
+// newBrowseModel gathers the quotes of the cache and of the history.
+func newBrowseModel() (*browseModel, error) {
+	m := &browseModel{quotes: map[string][]quote{}, names: map[string]string{}, width: 80, height: 24}
+	quotes, err := knownQuotes()
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range quotes {
+		m.add(q)
+	}
+	if m.favorites, err = loadFavorites(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *browseModel) add(q quote) {
+	key := strings.ToLower(q.Author)
+	if _, ok := m.names[key]; !ok {
+		m.names[key] = q.Author
+	}
+	if !slices.Contains(m.quotes[key], q) {
+		m.quotes[key] = append(m.quotes[key], q)
+	}
+}
+
+// visible tells if the quote is shown with the search and the favorites filter.
+func (m *browseModel) visible(q quote) bool {
+	if m.onlyFavorites && !slices.Contains(m.favorites, q) {
+		return false
+	}
+	search := strings.ToLower(m.search)
+	return strings.Contains(strings.ToLower(q.Author), search) || strings.Contains(strings.ToLower(q.Quote), search)
+}
+
+// shownAuthors returns the authors with a quote shown, sorted.
+func (m *browseModel) shownAuthors() []string {
+	var authors []string
+	for key, quotes := range m.quotes {
+		if slices.ContainsFunc(quotes, m.visible) {
+			authors = append(authors, key)
+		}
+	}
+	sort.Strings(authors)
+	return authors
+}
+
+// shownQuotes returns the quotes shown of the selected author.
+func (m *browseModel) shownQuotes() []quote {
+	authors := m.shownAuthors()
+	if m.author >= len(authors) {
+		return nil
+	}
+	var quotes []quote
+	for _, q := range m.quotes[authors[m.author]] {
+		if m.visible(q) {
+			quotes = append(quotes, q)
+		}
+	}
+	return quotes
+}
+
+// clamp keeps the selection within the authors and the quotes shown.
+func (m *browseModel) clamp() {
+	m.author = max(0, min(m.author, len(m.shownAuthors())-1))
+	m.quote = max(0, min(m.quote, len(m.shownQuotes())-1))
+}
+
+func (m *browseModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case fetchedMsg:
+		if msg.err != nil {
+			m.status = "error: " + msg.err.Error()
+			break
+		}
+		m.add(msg.quote)
+		rememberQuotes(msg.quote)
+		recordHistory(m.client.transport.server(), msg.quote)
+		m.status = "fetched a quote of " + msg.quote.Author
+	case tea.KeyMsg:
+		if m.searching {
+			m.updateSearch(msg)
+			break
+		}
+		return m, m.updateKey(msg)
+	}
+	return m, nil
+}
+
+// updateSearch handles the keys typed in the search.
+func (m *browseModel) updateSearch(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.searching = false
+	case tea.KeyEsc:
+		m.searching, m.search = false, ""
+	case tea.KeyBackspace:
+		if r := []rune(m.search); len(r) > 0 {
+			m.search = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.search += msg.String()
+	case tea.KeyCtrlC:
+		m.searching = false
+	}
+	m.author, m.quote = 0, 0
+	m.clamp()
+}
+
+// updateKey handles the keys outside of the search.
+func (m *browseModel) updateKey(msg tea.KeyMsg) tea.Cmd {
+	m.status = ""
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return tea.Quit
+	case "up", "k":
+		if m.onQuotes {
+			m.quote--
+		} else {
+			m.author, m.quote = m.author-1, 0
+		}
+	case "down", "j":
+		if m.onQuotes {
+			m.quote++
+		} else {
+			m.author, m.quote = m.author+1, 0
+		}
+	case "tab":
+		m.onQuotes = !m.onQuotes
+	case "left", "h":
+		m.onQuotes = false
+	case "right", "l":
+		m.onQuotes = true
+	case "/":
+		m.searching = true
+	case "esc":
+		m.search = ""
+	case "F":
+		m.onlyFavorites = !m.onlyFavorites
+		m.author, m.quote = 0, 0
+	case "f":
+		quotes := m.shownQuotes()
+		if len(quotes) == 0 {
+			break
+		}
+		q := quotes[m.quote]
+		if i := slices.Index(m.favorites, q); i >= 0 {
+			m.favorites = slices.Delete(m.favorites, i, i+1)
+		} else {
+			m.favorites = append(m.favorites, q)
+		}
+		if err := saveFavorites(m.favorites); err != nil {
+			m.status = "error: " + err.Error()
+		}
+	case "r":
+		authors := m.shownAuthors()
+		if m.client == nil || len(authors) == 0 {
+			m.status = "can't fetch a quote offline or without an author"
+			break
+		}
+		author := m.names[authors[m.author]]
+		m.status = "fetching a quote of " + author + "..."
+		return func() tea.Msg {
+			a, q, err := m.client.QOTD(m.ctx, author)
+			return fetchedMsg{quote{a, q}, err}
+		}
+	}
+	m.clamp()
+	return nil
+}
+
+func (m *browseModel) View() string {
+	leftWidth := min(30, m.width/3)
+	rightWidth := max(10, m.width-leftWidth-3)
+	rows := max(1, m.height-2)
+
+	// the authors, scrolled so the selected one is shown
+	authors := m.shownAuthors()
+	var left []string
+	for i := max(0, m.author-rows+1); i < len(authors) && len(left) < rows; i++ {
+		line := fmt.Sprintf("  %s (%d)", m.names[authors[i]], len(m.quotes[authors[i]]))
+		if i == m.author {
+			line = "> " + line[2:]
+		}
+		left = append(left, truncate.String(line, uint(leftWidth)))
+	}
+
+	// the quotes, wrapped and scrolled so the selected one is shown
+	quotes := m.shownQuotes()
+	blocks := make([][]string, len(quotes))
+	for i, q := range quotes {
+		mark := "  "
+		if slices.Contains(m.favorites, q) {
+			mark = "* "
+		}
+		if m.onQuotes && i == m.quote {
+			mark = ">" + mark[1:]
+		}
+		for j, line := range strings.Split(wordwrap.String(q.Quote, rightWidth-2), "\n") {
+			if j > 0 {
+				mark = "  "
+			}
+			blocks[i] = append(blocks[i], truncate.String(mark+line, uint(rightWidth)))
+		}
+		blocks[i] = append(blocks[i], "")
+	}
+	start := 0
+	for start < m.quote && linesOf(blocks[start:m.quote+1]) > rows {
+		start++
+	}
+	var right []string
+	for _, block := range blocks[start:] {
+		right = append(right, block...)
+	}
+
+	var b strings.Builder
+	for i := 0; i < rows; i++ {
+		var l, r string
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		fmt.Fprintf(&b, "%-*s │ %s\n", leftWidth, l, r)
+	}
+	switch {
+	case m.searching:
+		fmt.Fprintf(&b, "/%s", m.search)
+	case m.status != "":
+		b.WriteString(m.status)
+	default:
+		help := "/ search  f favorite  F favorites only  r fetch  tab switch  q quit"
+		if m.search != "" {
+			help = fmt.Sprintf("search %q (esc to clear)  ", m.search) + help
+		}
+		b.WriteString(truncate.String(help, uint(m.width)))
+	}
+	return b.String()
+}
+
+func linesOf(blocks [][]string) int {
+	n := 0
+	for _, block := range blocks {
+		n += len(block)
+	}
+	return n
+}
+
+func init() {
+	qotdCmd.AddCommand(browseCmd)
+
+	// Adds the flags of the connection to the server, see addConnFlags
+	// Adds a flag called --offline that defaults to false
+	addConnFlags(browseCmd.Flags())
+	browseCmd.Flags().Bool("offline", false, "Don't connect to the server, r can't fetch quotes then")
+}
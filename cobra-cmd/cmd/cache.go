@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// quoteCache holds the quotes fetched so far, by author in lower case, so get can
+// print one without the server.
+type quoteCache struct {
+	path   string
+	Quotes map[string][]quote `json:"quotes"`
+}
+
+// qotdDir returns ~/.qotd, where the local data is kept.
+func qotdDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".qotd"), nil
+}
+
+// loadCache reads ~/.qotd/cache/quotes.json, empty when it doesn't exist yet.
+func loadCache() (*quoteCache, error) {
+	dir, err := qotdDir()
+	if err != nil {
+		return nil, err
+	}
+	c := &quoteCache{path: filepath.Join(dir, "cache", "quotes.json"), Quotes: map[string][]quote{}}
+	b, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", c.path, err)
+	}
+	return c, nil
+}
+
+// add adds the quote, unless it's cached already.
+func (c *quoteCache) add(q quote) {
+	key := strings.ToLower(q.Author)
+	if !slices.Contains(c.Quotes[key], q) {
+		c.Quotes[key] = append(c.Quotes[key], q)
+	}
+}
+
+// pick returns up to n random quotes of the author, or of any author when it's empty.
+func (c *quoteCache) pick(author string, n int) []quote {
+	var quotes []quote
+	if author != "" {
+		quotes = c.Quotes[strings.ToLower(author)]
+	} else {
+		for _, q := range c.Quotes {
+			quotes = append(quotes, q...)
+		}
+	}
+	// copied so the cached slices keep their order
+	quotes = slices.Clone(quotes)
+	rand.Shuffle(len(quotes), func(i, j int) { quotes[i], quotes[j] = quotes[j], quotes[i] })
+	return quotes[:min(n, len(quotes))]
+}
+
+// save writes the cache to a temporary file renamed over the old one, so a
+// command interrupted while writing doesn't leave half a file.
+func (c *quoteCache) save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// rememberQuotes caches quotes fetched from the server. The cache is only a fallback,
+// so failing to write it is a warning.
+func rememberQuotes(quotes ...quote) {
+	c, err := loadCache()
+	if err == nil {
+		for _, q := range quotes {
+			c.add(q)
+		}
+		err = c.save()
+	}
+	if err != nil {
+		slog.Warn("can't cache the quotes", "err", err)
+	}
+}
+
+// knownQuotes returns every quote of the cache and of the history, once, the cached
+// ones first.
+func knownQuotes() ([]quote, error) {
+	c, err := loadCache()
+	if err != nil {
+		return nil, err
+	}
+	var quotes []quote
+	seen := map[quote]bool{}
+	authors := make([]string, 0, len(c.Quotes))
+	for author := range c.Quotes {
+		authors = append(authors, author)
+	}
+	sort.Strings(authors)
+	for _, author := range authors {
+		for _, q := range c.Quotes[author] {
+			seen[q] = true
+			quotes = append(quotes, q)
+		}
+	}
+
+	db, err := openHistory()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	entries, err := readHistory(db, time.Time{}, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if q := (quote{e.Author, e.Quote}); !seen[q] {
+			seen[q] = true
+			quotes = append(quotes, q)
+		}
+	}
+	return quotes, nil
+}
+
+// cachedQuotes returns up to n cached quotes of the author, or of any author when
+// it's empty.
+func cachedQuotes(author string, n int) ([]quote, error) {
+	c, err := loadCache()
+	if err != nil {
+		return nil, err
+	}
+	quotes := c.pick(author, n)
+	if len(quotes) == 0 {
+		if author == "" {
+			return nil, errors.New("no quote cached yet")
+		}
+		return nil, fmt.Errorf("no quote of %s cached yet", author)
+	}
+	return quotes, nil
+}
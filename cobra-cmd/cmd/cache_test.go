@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// tempHome points the home directory, holding ~/.qotd, to a temporary one.
+func tempHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	return home
+}
+
+func Test_quoteCache_pick(t *testing.T) {
+	c := &quoteCache{Quotes: map[string][]quote{}}
+	for _, q := range []quote{{"Mark Twain", "a"}, {"mark twain", "b"}, {"Yoda", "c"}, {"Mark Twain", "a"}} {
+		c.add(q)
+	}
+	if got := len(c.Quotes["mark twain"]); got != 2 {
+		t.Errorf("cached %d quotes of mark twain, want 2 with the same one added twice", got)
+	}
+	tests := []struct {
+		author string
+		n      int
+		want   []string // The quotes picked, sorted
+	}{
+		{"MARK TWAIN", 10, []string{"a", "b"}},
+		{"", 10, []string{"a", "b", "c"}},
+		{"", 0, []string{}},
+		{"twain", 10, []string{}},
+		{"Yoda", 1, []string{"c"}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, q := range c.pick(tt.author, tt.n) {
+			got = append(got, q.Quote)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("pick(%q, %d) = %v, want %v", tt.author, tt.n, got, tt.want)
+		}
+	}
+	if n := len(c.pick("", 2)); n != 2 {
+		t.Errorf("pick(\"\", 2) returned %d quotes", n)
+	}
+	if c.Quotes["mark twain"][0].Quote != "a" {
+		t.Error("pick() reordered the cached quotes")
+	}
+}
+
+func Test_cachedQuotes(t *testing.T) {
+	tempHome(t)
+	if _, err := cachedQuotes("", 1); err == nil || !strings.Contains(err.Error(), "no quote cached yet") {
+		t.Errorf("cachedQuotes() of an empty cache error = %v", err)
+	}
+
+	rememberQuotes(quote{"Yoda", "Do, or do not"}, quote{"Ada", "b"})
+	rememberQuotes(quote{"yoda", "Do, or do not"})
+	c, err := loadCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]quote{"yoda": {{"Yoda", "Do, or do not"}, {"yoda", "Do, or do not"}}, "ada": {{"Ada", "b"}}}
+	if !reflect.DeepEqual(c.Quotes, want) {
+		t.Errorf("cached %v, want %v", c.Quotes, want)
+	}
+
+	quotes, err := cachedQuotes("ADA", 5)
+	if err != nil || !reflect.DeepEqual(quotes, []quote{{"Ada", "b"}}) {
+		t.Errorf("cachedQuotes(ADA) = %v, %v", quotes, err)
+	}
+	if _, err := cachedQuotes("Twain", 1); err == nil || !strings.Contains(err.Error(), "no quote of Twain") {
+		t.Errorf("cachedQuotes(Twain) error = %v", err)
+	}
+}
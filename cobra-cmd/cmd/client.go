@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"clikit/clierr"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxBackoff caps the wait between two attempts, however many retries there are.
+const maxBackoff = 30 * time.Second
+
+// qotdClient is a client to the QOTD server. It does what client.Client does, over a
+// connection that can be secured with TLS, which client.New can't do, and over gRPC
+// or HTTP.
+type qotdClient struct {
+	transport transport
+	timeout   time.Duration // deadline of every attempt
+	retries   int           // attempts made again after a transient error
+	backoff   time.Duration // wait before the first retry, doubled for every next one
+}
+
+// addConnFlags adds the flags choosing the QOTD server and how to connect to it. Every
+// command talking to the server adds them and creates its client with newClient. Like
+// every flag, they can be set in the config file or the environment too.
+func addConnFlags(fs *pflag.FlagSet) {
+	// Adds a flag called --dev that can be shortened to -d and defaults to false
+	// Adds a flag called --addr that defaults to "127.0.0.1:80"
+	// Adds a flag called --transport that defaults to grpc, and --http-addr
+	// Adds a flag called --timeout that defaults to 2s
+	// Adds a flag called --retries that defaults to 2, and --backoff that defaults to 200ms
+	// Adds the flags securing the connection, --tls being implied by the other ones
+	// Adds the flags of the token sent on every call, QOTD_TOKEN being used without them,
+	// and --insecure-token allowing it without TLS
+	// Adds a flag called --proxy, HTTPS_PROXY and ALL_PROXY being used without it
+	fs.BoolP("dev", "d", false, "Uses the dev server instead of prod")
+	fs.String("addr", "127.0.0.1:80", "Set the QOTD server to use, defaults to production")
+	fs.String("transport", "grpc", "How to reach the server: grpc, http for its JSON gateway, or auto for http when grpc is unavailable or never answers")
+	fs.String("http-addr", "", "URL or host:port of the JSON gateway of the server, defaults to --addr")
+	fs.Duration("timeout", 2*time.Second, "Time to wait for the answer of the server, for every attempt")
+	fs.Int("retries", 2, "Number of times a call is made again when the server is unavailable or too slow")
+	fs.Duration("backoff", 200*time.Millisecond, "Wait before the first retry, doubled for every next one with some jitter")
+	fs.Bool("tls", false, "Connect to the server over TLS")
+	fs.String("ca-cert", "", "PEM file of the CA certificates verifying the server, instead of the system ones")
+	fs.String("client-cert", "", "PEM file of the client certificate, for servers requiring mTLS")
+	fs.String("client-key", "", "PEM file of the key of the client certificate")
+	fs.Bool("insecure-skip-verify", false, "Don't verify the certificate of the server, for testing only")
+	fs.String("auth-token", "", "Token sent to the server on every call, defaults to $QOTD_TOKEN")
+	fs.String("auth-token-file", "", "File holding the token, keeping it out of the shell history")
+	fs.Bool("insecure-token", false, "Send the token without --tls too, in clear text, for a dev server only")
+	fs.String("proxy", "", "URL of the HTTP CONNECT or SOCKS5 proxy to the server, like socks5://host:1080, defaults to $HTTPS_PROXY or $ALL_PROXY, direct for none")
+}
+
+// newClient connects to the server chosen by the settings of addConnFlags.
+func newClient(fs *pflag.FlagSet) (*qotdClient, error) {
+	const devAddr = "127.0.0.1:3450"
+	addr := viper.GetString("addr")
+	if viper.GetBool("dev") {
+		addr = devAddr
+	}
+	timeout, retries, backoff := viper.GetDuration("timeout"), viper.GetInt("retries"), viper.GetDuration("backoff")
+	if timeout <= 0 || backoff <= 0 {
+		return nil, clierr.Errorf(clierr.Usage, "timeout and backoff have to be positive")
+	}
+	if retries < 0 {
+		return nil, clierr.Errorf(clierr.Usage, "retries can't be negative")
+	}
+
+	if p := viper.GetString("proxy"); p != "" && p != "direct" {
+		if _, err := parseProxy(p); err != nil {
+			return nil, err
+		}
+	}
+	config, err := tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	token, err := authToken(fs)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" && config == nil && !viper.GetBool("insecure-token") {
+		return nil, clierr.Errorf(clierr.Usage, "the token would be sent in clear text, use --tls, or --insecure-token for a dev server")
+	}
+	httpAddr := viper.GetString("http-addr")
+	if httpAddr == "" {
+		httpAddr = addr
+	}
+
+	var t transport
+	switch viper.GetString("transport") {
+	case "grpc":
+		t, err = newGRPCTransport(addr, config, token)
+	case "http":
+		t, err = newHTTPTransport(httpAddr, config, token)
+	case "auto":
+		var grpcT *grpcTransport
+		var httpT *httpTransport
+		if grpcT, err = newGRPCTransport(addr, config, token); err != nil {
+			return nil, err
+		}
+		if httpT, err = newHTTPTransport(httpAddr, config, token); err != nil {
+			grpcT.Close()
+			return nil, err
+		}
+		t = &autoTransport{grpc: grpcT, http: httpT}
+	default:
+		return nil, clierr.Errorf(clierr.Usage, "transport has to be either grpc, http or auto")
+	}
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("connecting", "transport", viper.GetString("transport"), "server", t.server(),
+		"tls", config != nil, "token", token != "", "timeout", timeout, "retries", retries)
+	return &qotdClient{
+		transport: t,
+		timeout:   timeout,
+		retries:   retries,
+		backoff:   backoff,
+	}, nil
+}
+
+// tlsConfig returns nil for a plaintext connection, or the TLS config when --tls or
+// any of the certificate flags is set.
+func tlsConfig() (*tls.Config, error) {
+	caCert := viper.GetString("ca-cert")
+	clientCert := viper.GetString("client-cert")
+	clientKey := viper.GetString("client-key")
+	skipVerify := viper.GetBool("insecure-skip-verify")
+	if !viper.GetBool("tls") && caCert == "" && clientCert == "" && clientKey == "" && !skipVerify {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: skipVerify}
+	if caCert != "" {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no PEM certificate found", caCert)
+		}
+		config.RootCAs = pool
+	}
+	if (clientCert == "") != (clientKey == "") {
+		return nil, clierr.Errorf(clierr.Usage, "--client-cert and --client-key have to be given together")
+	}
+	if clientCert != "" {
+		cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// authToken returns the token of --auth-token, else the one of --auth-token-file, else
+// the one of $QOTD_TOKEN or the config file. It's empty when none of them is set.
+func authToken(fs *pflag.FlagSet) (string, error) {
+	if fs.Changed("auth-token") {
+		return viper.GetString("auth-token"), nil
+	}
+	if path := viper.GetString("auth-token-file"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		token := strings.TrimSpace(string(b))
+		if token == "" {
+			return "", fmt.Errorf("%s: no token found", path)
+		}
+		return token, nil
+	}
+	return viper.GetString("auth-token"), nil
+}
+
+// tokenCredentials attaches the token to every call as an authorization header.
+type tokenCredentials struct {
+	token    string
+	insecure bool // whether it can be sent without TLS, see --insecure-token
+}
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+// RequireTransportSecurity makes gRPC refuse to send the token in clear text, unless
+// --insecure-token was given.
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return !t.insecure
+}
+
+// QOTD retrieves a quote of the day. If wantAuthor is not set, will randomly choose the author
+// of a quote.
+func (c *qotdClient) QOTD(ctx context.Context, wantAuthor string) (string, string, error) {
+	var q quote
+	err := c.call(ctx, func(ctx context.Context) (err error) {
+		q, err = c.transport.getQOTD(ctx, wantAuthor)
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return q.Author, q.Quote, nil
+}
+
+// call makes the call with a deadline of c.timeout. It's made again up to c.retries
+// times while it fails with a transient error, waiting a backoff doubled every time,
+// from half of it to all of it so the clients of a flaky server don't retry together.
+func (c *qotdClient) call(ctx context.Context, rpc func(ctx context.Context) error) error {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		start := time.Now()
+		err := rpc(attemptCtx)
+		cancel()
+		slog.Debug("called the server", "attempt", attempt+1, "code", status.Code(err), "latency", time.Since(start))
+		if err == nil || attempt == c.retries || !transient(err) || ctx.Err() != nil {
+			return err
+		}
+
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		slog.Info("retrying", "err", err, "wait", wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// transient tells if the call can succeed when made again.
+func transient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// Close closes the connection to the server.
+func (c *qotdClient) Close() error {
+	return c.transport.Close()
+}
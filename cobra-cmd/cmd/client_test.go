@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// connFlags parses the flags of addConnFlags, bound to viper like the commands do.
+func connFlags(t *testing.T, args ...string) *pflag.FlagSet {
+	t.Helper()
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	addConnFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	if err := viper.BindPFlags(fs); err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+func Test_newClient_token(t *testing.T) {
+	t.Setenv("QOTD_TOKEN", "")
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"No token", []string{}, ""},
+		{"Token over TLS", []string{"--auth-token=secret", "--tls"}, ""},
+		{"Token without TLS", []string{"--auth-token=secret"}, "clear text"},
+		{"Token over HTTP without TLS", []string{"--auth-token=secret", "--transport=http"}, "clear text"},
+		{"Token with --insecure-token", []string{"--auth-token=secret", "--insecure-token"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newClient(connFlags(t, tt.args...))
+			if err == nil {
+				client.Close()
+			}
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("newClient(%v) error = %v, want %q", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_tokenCredentials(t *testing.T) {
+	creds := tokenCredentials{token: "secret"}
+	md, err := creds.GetRequestMetadata(context.Background())
+	if err != nil || md["authorization"] != "Bearer secret" {
+		t.Errorf("GetRequestMetadata() = %v, %v", md, err)
+	}
+	if !creds.RequireTransportSecurity() {
+		t.Error("RequireTransportSecurity() = false without --insecure-token")
+	}
+	if (tokenCredentials{token: "secret", insecure: true}).RequireTransportSecurity() {
+		t.Error("RequireTransportSecurity() = true with --insecure-token")
+	}
+}
+
+// writeCert writes a self-signed certificate and its key as PEM files in dir.
+func writeCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "qotd"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func Test_tlsConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir)
+	garbage := filepath.Join(dir, "garbage.pem")
+	if err := os.WriteFile(garbage, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		args      []string
+		wantTLS   bool
+		wantCA    bool
+		wantCerts int
+		wantErr   string
+	}{
+		{"Plaintext", []string{}, false, false, 0, ""},
+		{"TLS", []string{"--tls"}, true, false, 0, ""},
+		{"Implied by --insecure-skip-verify", []string{"--insecure-skip-verify"}, true, false, 0, ""},
+		{"CA", []string{"--ca-cert", certFile}, true, true, 0, ""},
+		{"CA without PEM", []string{"--ca-cert", garbage}, false, false, 0, "no PEM certificate"},
+		{"CA missing", []string{"--ca-cert", filepath.Join(dir, "missing.pem")}, false, false, 0, "no such file"},
+		{"mTLS", []string{"--tls", "--client-cert", certFile, "--client-key", keyFile}, true, false, 1, ""},
+		{"Client cert without key", []string{"--client-cert", certFile}, false, false, 0, "given together"},
+		{"Client key without cert", []string{"--client-key", keyFile}, false, false, 0, "given together"},
+		{"Client cert not matching", []string{"--client-cert", certFile, "--client-key", garbage}, false, false, 0, "PEM"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connFlags(t, tt.args...)
+			config, err := tlsConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("tlsConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (config != nil) != tt.wantTLS {
+				t.Fatalf("tlsConfig() = %v, want TLS %v", config, tt.wantTLS)
+			}
+			if config == nil {
+				return
+			}
+			if config.MinVersion != tls.VersionTLS12 {
+				t.Errorf("MinVersion = %x, want TLS 1.2", config.MinVersion)
+			}
+			if (config.RootCAs != nil) != tt.wantCA || len(config.Certificates) != tt.wantCerts {
+				t.Errorf("tlsConfig() RootCAs = %v, %d certificates", config.RootCAs != nil, len(config.Certificates))
+			}
+		})
+	}
+}
+
+func Test_qotdClient_call(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+	tests := []struct {
+		name         string
+		errs         []error // of the successive attempts, the last one repeated
+		retries      int
+		wantAttempts int
+		wantCode     codes.Code
+	}{
+		{"Succeeds", []error{nil}, 2, 1, codes.OK},
+		{"Succeeds after retries", []error{unavailable, unavailable, nil}, 2, 3, codes.OK},
+		{"Too many failures", []error{unavailable}, 2, 3, codes.Unavailable},
+		{"No retries", []error{unavailable}, 0, 1, codes.Unavailable},
+		{"Timeout retried", []error{status.Error(codes.DeadlineExceeded, "slow"), nil}, 1, 2, codes.OK},
+		{"Not transient", []error{status.Error(codes.NotFound, "no author"), nil}, 2, 1, codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &qotdClient{timeout: time.Second, retries: tt.retries, backoff: time.Millisecond}
+			attempts := 0
+			err := c.call(context.Background(), func(ctx context.Context) error {
+				if _, ok := ctx.Deadline(); !ok {
+					t.Error("attempt without a deadline")
+				}
+				attempts++
+				return tt.errs[min(attempts, len(tt.errs))-1]
+			})
+			if attempts != tt.wantAttempts || status.Code(err) != tt.wantCode {
+				t.Errorf("call() = %v after %d attempts, want %v after %d", err, attempts, tt.wantCode, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func Test_qotdClient_call_backoff(t *testing.T) {
+	// the waits are 50-100ms, 100-200ms and 200-400ms
+	c := &qotdClient{timeout: time.Second, retries: 3, backoff: 100 * time.Millisecond}
+	var starts []time.Time
+	c.call(context.Background(), func(ctx context.Context) error {
+		starts = append(starts, time.Now())
+		return status.Error(codes.Unavailable, "down")
+	})
+	if len(starts) != 4 {
+		t.Fatalf("made %d attempts, want 4", len(starts))
+	}
+	for i, want := range []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond} {
+		if wait := starts[i+1].Sub(starts[i]); wait < want || wait > 2*want+100*time.Millisecond {
+			t.Errorf("wait %d = %v, want between %v and %v", i+1, wait, want, 2*want)
+		}
+	}
+
+	// the wait ends with the context
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	c = &qotdClient{timeout: time.Second, retries: 3, backoff: time.Minute}
+	start := time.Now()
+	attempts := 0
+	c.call(ctx, func(ctx context.Context) error {
+		attempts++
+		return status.Error(codes.Unavailable, "down")
+	})
+	if attempts != 1 || time.Since(start) > time.Second {
+		t.Errorf("made %d attempts in %v after the context ended", attempts, time.Since(start))
+	}
+}
+
+func Test_transient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{status.Error(codes.Unavailable, ""), true},
+		{status.Error(codes.DeadlineExceeded, ""), true},
+		{status.Error(codes.NotFound, ""), false},
+		{status.Error(codes.Unauthenticated, ""), false},
+		{errors.New("not a status"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := transient(tt.err); got != tt.want {
+			t.Errorf("transient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
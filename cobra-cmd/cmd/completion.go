@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// completionCmd represents the completion command, replacing the one cobra adds so
+// its help tells how to install the scripts of goclitool
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish|powershell",
+	Short: "Prints the shell completion script",
+	Long: `This command prints the script completing the commands, the flags and the
+values of the flags of goclitool in the shell. It's generated from the commands, so it
+always covers all of them.
+
+Example usage:
+  bash:       goclitool completion bash > /etc/bash_completion.d/goclitool
+  zsh:        goclitool completion zsh > "${fpath[1]}/_goclitool"
+  fish:       goclitool completion fish > ~/.config/fish/completions/goclitool.fish
+  powershell: goclitool completion powershell | Out-String | Invoke-Expression
+`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := cmd.Root()
+		switch args[0] {
+		case "bash":
+			return root.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			return root.GenZshCompletion(os.Stdout)
+		case "fish":
+			return root.GenFishCompletion(os.Stdout, true)
+		default:
+			return root.GenPowerShellCompletionWithDesc(os.Stdout)
+		}
+	},
+}
+
+// flagValues are the values completed of the flags taking one of a few, whatever
+// command they're on.
+var flagValues = map[string][]string{
+	"output":    {"text", "json", "ndjson", "yaml", "table", "csv", "go-template=", "go-template-file="},
+	"transport": {"grpc", "http", "auto"},
+	"notify":    {"stdout", "file=", "desktop", "webhook="},
+	"channel":   {"stable", "beta"},
+}
+
+// registerFlagCompletions completes the values of flagValues on cmd and on all of its
+// subcommands. It's called once all of them are added, so it covers every command.
+func registerFlagCompletions(cmd *cobra.Command) {
+	// the flags of the former tools only share their names with the ones of goclitool
+	if cmd.DisableFlagParsing {
+		return
+	}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		values, ok := flagValues[f.Name]
+		if !ok {
+			return
+		}
+		cmd.RegisterFlagCompletionFunc(f.Name, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return values, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+		})
+	})
+	for _, c := range cmd.Commands() {
+		registerFlagCompletions(c)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
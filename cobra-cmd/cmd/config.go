@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"clikit/clierr"
+	"clikit/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// cfgFile is the value of --config, empty for ~/.qotd.yaml.
+var cfgFile string
+
+// configPath returns the config file, which doesn't have to exist.
+func configPath() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".qotd.yaml"), nil
+}
+
+// qotdConfig is where the settings of the qotd commands come from, read by initConfig.
+var qotdConfig *config.Config
+
+// initConfig reads the config file, the QOTD_* environment variables winning over it,
+// see package config. They give their value to the flags not given on the command
+// line, which viper then reads.
+func initConfig() {
+	path, err := configPath()
+	if err != nil {
+		fatal(err)
+	}
+	file, err := config.ReadFile(path, true)
+	if err != nil {
+		fatal(err)
+	}
+	if file.Found {
+		slog.Info("read the config file", "path", path)
+	}
+	// QOTD_TOKEN is shorter than QOTD_AUTH_TOKEN, and was there first
+	qotdConfig = &config.Config{File: file, EnvPrefix: "QOTD", EnvAliases: map[string][]string{"auth-token": {"QOTD_TOKEN"}}}
+}
+
+// applyConfig gives the flags not given on the command line their value from the
+// environment or the config file, where the ones of the section win, and returns the
+// settings of all of them. The flags aren't marked as changed, so the commands still
+// tell the ones given.
+func applyConfig(section string, fs *pflag.FlagSet) ([]config.Setting, error) {
+	var flags []config.Flag
+	fs.VisitAll(func(f *pflag.Flag) {
+		if f.Name != "help" && f.Name != "config" {
+			flags = append(flags, config.Flag{Name: f.Name, Value: f.Value.String(), Changed: f.Changed, Set: f.Value.Set})
+		}
+	})
+	return qotdConfig.Apply(section, flags)
+}
+
+// configSection returns the section of the config file of a qotd command, its path
+// under qotd like export or fav.add.
+func configSection(cmd *cobra.Command) string {
+	var names []string
+	for c := cmd; c.HasParent() && c.Name() != "qotd"; c = c.Parent() {
+		names = append([]string{c.Name()}, names...)
+	}
+	return strings.Join(names, ".")
+}
+
+// configCommands returns the qotd commands whose flags can be set in the config file,
+// config aside. Their flags may share a name but not a type or a default, like
+// --concurrency, so each of them has its own settings.
+func configCommands() []*cobra.Command {
+	var commands []*cobra.Command
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		for _, sub := range c.Commands() {
+			if sub == configCmd {
+				continue
+			}
+			if sub.Runnable() {
+				commands = append(commands, sub)
+			}
+			walk(sub)
+		}
+	}
+	walk(qotdCmd)
+	return commands
+}
+
+// configSettings returns the settings of every command, named by their section and
+// flag like export.concurrency, and the names of the tokens among them.
+func configSettings() ([]config.Setting, []string, error) {
+	var all []config.Setting
+	var tokens []string
+	for _, c := range configCommands() {
+		section := configSection(c)
+		settings, err := applyConfig(section, c.Flags())
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, s := range settings {
+			if s.Name == "auth-token" {
+				tokens = append(tokens, section+"."+s.Name)
+			}
+			s.Name = section + "." + s.Name
+			all = append(all, s)
+		}
+	}
+	return all, tokens, nil
+}
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Views or changes the settings of the config file",
+	Long: `Every flag of the other commands, like addr, dev, json, author or timeout,
+can be set in the config file (~/.qotd.yaml by default, see --config) or with a
+QOTD_* environment variable, like QOTD_ADDR or QOTD_AUTH_TOKEN. A flag wins over
+the environment, which wins over the config file. config show tells where every
+setting comes from.
+
+The settings are the ones of every command, like export.concurrency. In the config
+file, a flag at the top applies to every command having it, and one under the name
+of a command, like export: or fav.add:, to that command only, winning over the top.
+
+Example usage:
+qotd config set addr 10.0.0.1:80
+qotd config set export.concurrency 8
+qotd config view
+qotd config show
+`,
+}
+
+// configViewCmd represents the config view command
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Prints the settings, from the environment, the config file or their default",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		settings, tokens, err := configSettings()
+		if err != nil {
+			fatal(err)
+		}
+		for _, s := range settings {
+			if slices.Contains(tokens, s.Name) && s.Value != "" {
+				s.Value = "<hidden>"
+			}
+			fmt.Printf("%s: %s\n", s.Name, s.Value)
+		}
+	},
+}
+
+// configShowCmd represents the config show command
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Prints the settings and where they come from, the environment, the config file or their default",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		settings, tokens, err := configSettings()
+		if err != nil {
+			fatal(err)
+		}
+		if err := config.Print(os.Stdout, settings, tokens...); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Writes a setting to the config file",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := setConfig(args[0], args[1]); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+// setConfig writes the setting to the config file, keeping its other settings and its
+// comments. A key like export.concurrency goes to the section of the command, a flag
+// name alone before the sections, for every command having that flag.
+func setConfig(key, value string) error {
+	section, name := "", key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		section, name = key[:i], key[i+1:]
+	}
+	var flags []*pflag.Flag
+	for _, c := range configCommands() {
+		if section != "" && configSection(c) != section {
+			continue
+		}
+		if f := c.Flags().Lookup(name); f != nil && name != "help" && name != "config" {
+			flags = append(flags, f)
+		}
+	}
+	if len(flags) == 0 {
+		return clierr.Errorf(clierr.Usage, "unknown setting %q, see qotd config view", key)
+	}
+	for _, f := range flags {
+		switch f.Value.Type() {
+		case "bool":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return clierr.Errorf(clierr.Usage, "%s has to be true or false", key)
+			}
+			value = strconv.FormatBool(b)
+		case "duration":
+			if _, err := time.ParseDuration(value); err != nil {
+				return clierr.Errorf(clierr.Usage, "%s has to be a duration like 5s", key)
+			}
+		case "int":
+			if _, err := strconv.Atoi(value); err != nil {
+				return clierr.Errorf(clierr.Usage, "%s has to be a number", key)
+			}
+		}
+	}
+	return qotdConfig.File.Set(section, name, value)
+}
+
+func init() {
+	qotdCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configViewCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configSetCmd)
+}
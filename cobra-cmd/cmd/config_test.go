@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func Test_setConfig_byCommand(t *testing.T) {
+	tempHome(t)
+	initConfig()
+	// applying the settings sets the flags of the commands, they get their default back
+	t.Cleanup(func() {
+		for _, c := range configCommands() {
+			c.Flags().VisitAll(func(f *pflag.Flag) {
+				f.Value.Set(f.DefValue)
+			})
+		}
+	})
+	for _, kv := range [][2]string{{"author", "Ada"}, {"export.concurrency", "8"}, {"fav.add.author", "Yoda"}} {
+		if err := setConfig(kv[0], kv[1]); err != nil {
+			t.Fatalf("setConfig(%s, %s) = %v", kv[0], kv[1], err)
+		}
+	}
+	failures := []struct {
+		key, value, want string
+	}{
+		{"concurrency", "many", "concurrency has to be a number"},
+		{"export.offline", "true", `unknown setting "export.offline"`},
+		{"nope.author", "Ada", `unknown setting "nope.author"`},
+		{"get.offline", "maybe", "get.offline has to be true or false"},
+	}
+	for _, tt := range failures {
+		if err := setConfig(tt.key, tt.value); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("setConfig(%s, %s) error = %v, want %s", tt.key, tt.value, err, tt.want)
+		}
+	}
+
+	settings, tokens, err := configSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]string{}
+	for _, s := range settings {
+		values[s.Name] = s.Value
+	}
+	want := map[string]string{
+		"export.concurrency": "8",
+		"get.concurrency":    "4",
+		"bench.concurrency":  "50",
+		"get.author":         "Ada",
+		"fav.add.author":     "Yoda",
+		"fav.list.author":    "Ada",
+	}
+	for name, value := range want {
+		if values[name] != value {
+			t.Errorf("%s = %q, want %q", name, values[name], value)
+		}
+	}
+	if !strings.Contains(strings.Join(tokens, " "), "get.auth-token") {
+		t.Errorf("the tokens %v don't have get.auth-token", tokens)
+	}
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"csv2json"
+
+	"github.com/spf13/cobra"
+)
+
+// convertCmd represents the convert command
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Converts files from one format to another",
+}
+
+// convertCSV2JSONCmd represents the convert csv2json command, running csv2json
+var convertCSV2JSONCmd = &cobra.Command{
+	Use:   "csv2json [flags] <csvFile>",
+	Short: "Converts CSV files to JSON, like csv2json",
+	Long: `This command runs csv2json, converting a CSV file to JSON, with its own
+options. Run "goclitool convert csv2json -h" for the list of them.
+
+Example usage:
+goclitool convert csv2json --pretty data.csv
+goclitool convert csv2json --separator=semicolon --format=ndjson data.csv
+`,
+	// the options are the ones of csv2json, given to it as they are
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		csv2json.Subcommand = []string{"convert", "csv2json"}
+		csv2json.SelfUpdater = selfUpdater()
+		csv2json.Main(programName(cmd), args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.AddCommand(convertCSV2JSONCmd)
+	// shown by the help and completed, csv2json still parsing them
+	addGoFlags(convertCSV2JSONCmd.Flags(), csv2json.Flags())
+}
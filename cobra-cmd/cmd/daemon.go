@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"clikit/clierr"
+
+	"goclitool/output"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// notifier delivers a quote fetched by the daemon.
+type notifier func(q quote) error
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Fetches a quote on a schedule and delivers it",
+	Long: `This command fetches a quote every --every, the first one right away, and
+delivers it to every --notify, until interrupted:
+  stdout           prints it, in the format of --output
+  file=PATH        appends it to the file, in the format of --output
+  desktop          shows it as a desktop notification (notify-send or osascript)
+  webhook=URL      posts it as {"text": ...} to the URL, like a Slack webhook
+
+It stays in the foreground for a service manager like systemd, or runs in the
+background with --detach, logging to ~/.qotd/daemon.log.
+
+Example usage:
+qotd daemon --every=24h --notify=desktop --notify=webhook=https://hooks.slack.com/services/...
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		every := viper.GetDuration("every")
+		if every <= 0 {
+			fatal(clierr.Errorf(clierr.Usage, "every has to be positive"))
+		}
+		p, err := newPrinter()
+		if err != nil {
+			fatal(err)
+		}
+		notifiers, err := newNotifiers(viper.GetStringSlice("notify"), p)
+		if err != nil {
+			fatal(err)
+		}
+		if viper.GetBool("detach") {
+			if err := detach(); err != nil {
+				fatal(err)
+			}
+			return
+		}
+		c, err := newClient(cmd.Flags())
+		if err != nil {
+			fatal(err)
+		}
+		defer c.Close()
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		runDaemon(ctx, c, every, notifiers)
+	},
+}
+
+// runDaemon fetches and delivers a quote every interval until ctx is done. The errors
+// are logged, the next delivery may work.
+func runDaemon(ctx context.Context, c *qotdClient, every time.Duration, notifiers []notifier) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		a, q, err := c.QOTD(ctx, viper.GetString("author"))
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			slog.Error("can't get a quote", "err", err)
+		default:
+			rememberQuotes(quote{a, q})
+			recordHistory(c.transport.server(), quote{a, q})
+			for _, notify := range notifiers {
+				if err := notify(quote{a, q}); err != nil {
+					slog.Error("can't deliver the quote", "err", err)
+				}
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// newNotifiers returns the notifiers of the --notify values, stdout when there are none.
+func newNotifiers(values []string, p *output.Printer) ([]notifier, error) {
+	if len(values) == 0 {
+		values = []string{"stdout"}
+	}
+	var notifiers []notifier
+	for _, value := range values {
+		name, arg, _ := strings.Cut(value, "=")
+		switch name {
+		case "stdout":
+			notifiers = append(notifiers, func(q quote) error {
+				return p.Print(os.Stdout, q)
+			})
+		case "file":
+			if arg == "" {
+				return nil, clierr.Errorf(clierr.Usage, "notify file needs a path, like file=quotes.txt")
+			}
+			notifiers = append(notifiers, func(q quote) error {
+				return appendQuote(arg, p, q)
+			})
+		case "desktop":
+			notifiers = append(notifiers, notifyDesktop)
+		case "webhook":
+			if u, err := url.Parse(arg); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return nil, clierr.Errorf(clierr.Usage, "notify webhook needs an http or https URL, like webhook=https://hooks.slack.com/services/...")
+			}
+			notifiers = append(notifiers, func(q quote) error {
+				return postWebhook(arg, q)
+			})
+		default:
+			return nil, clierr.Errorf(clierr.Usage, "unknown notify %q, it has to be one of stdout, file=PATH, desktop or webhook=URL", value)
+		}
+	}
+	return notifiers, nil
+}
+
+func appendQuote(path string, p *output.Printer, q quote) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := p.Print(f, q); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// notifyDesktop shows the quote with notify-send on Linux and the BSDs, and with
+// osascript on macOS.
+func notifyDesktop(q quote) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(q.Quote), appleScriptString(q.Author))
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows", "plan9", "js", "wasip1":
+		return fmt.Errorf("desktop notifications aren't supported on %s", runtime.GOOS)
+	default:
+		cmd = exec.Command("notify-send", "--app-name=qotd", q.Author, q.Quote)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if out = bytes.TrimSpace(out); len(out) > 0 {
+			return fmt.Errorf("%s: %w: %s", cmd.Args[0], err, out)
+		}
+		return fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return nil
+}
+
+// appleScriptString quotes s for AppleScript, which escapes like JSON does.
+func appleScriptString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// postWebhook posts the quote as {"text": ...}, what Slack and the chat tools copying
+// its incoming webhooks expect.
+func postWebhook(u string, q quote) error {
+	body, err := json.Marshal(map[string]string{"text": fmt.Sprintf("%s\n— %s", q.Quote, q.Author)})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(u, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook: %s %s", resp.Status, bytes.TrimSpace(b))
+	}
+	return nil
+}
+
+// detach starts the daemon again in the background, without --detach, writing its
+// output to ~/.qotd/daemon.log, and returns once it's started.
+func detach() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	dir, err := qotdDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	logPath := filepath.Join(dir, "daemon.log")
+	log, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	args := slices.DeleteFunc(slices.Clone(cliArgs), func(arg string) bool {
+		return arg == "--detach" || strings.HasPrefix(arg, "--detach=")
+	})
+	cmd := exec.Command(exe, append(args, "--detach=false")...)
+	cmd.Stdout, cmd.Stderr = log, log
+	cmd.SysProcAttr = detachedAttr()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	fmt.Printf("daemon started with pid %d, logging to %s\n", cmd.Process.Pid, logPath)
+	return cmd.Process.Release()
+}
+
+func init() {
+	qotdCmd.AddCommand(daemonCmd)
+
+	// Adds the flags of the connection to the server, see addConnFlags
+	// Adds a flag called --author that can be shortened to -a
+	// Adds a flag called --every that defaults to 24h
+	// Adds a flag called --notify that can be repeated and defaults to stdout
+	// Adds a flag called --output that can be shortened to -o and defaults to text
+	// Adds a flag called --detach that defaults to false
+	addConnFlags(daemonCmd.Flags())
+	daemonCmd.Flags().StringP("author", "a", "", "Specify the author to get quotes for")
+	daemonCmd.Flags().Duration("every", 24*time.Hour, "Time between two quotes delivered")
+	daemonCmd.Flags().StringSlice("notify", nil, "Where to deliver the quotes: stdout, file=PATH, desktop or webhook=URL (can be repeated)")
+	output.AddFlag(daemonCmd.Flags())
+	daemonCmd.Flags().Bool("detach", false, "Run in the background, logging to ~/.qotd/daemon.log")
+}
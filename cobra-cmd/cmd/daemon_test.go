@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"goclitool/output"
+)
+
+func Test_newNotifiers(t *testing.T) {
+	p, err := output.New("text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		values  []string
+		want    int
+		wantErr string
+	}{
+		{nil, 1, ""},
+		{[]string{"stdout", "desktop"}, 2, ""},
+		{[]string{"file=quotes.txt", "webhook=https://hooks.example.com/x"}, 2, ""},
+		{[]string{"file"}, 0, "needs a path"},
+		{[]string{"file="}, 0, "needs a path"},
+		{[]string{"webhook"}, 0, "http or https URL"},
+		{[]string{"webhook=ftp://example.com"}, 0, "http or https URL"},
+		{[]string{"stdout", "email=me@example.com"}, 0, `unknown notify "email=me@example.com"`},
+	}
+	for _, tt := range tests {
+		notifiers, err := newNotifiers(tt.values, p)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("newNotifiers(%q) error = %v, want %q", tt.values, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || len(notifiers) != tt.want {
+			t.Errorf("newNotifiers(%q) = %d notifiers, %v, want %d", tt.values, len(notifiers), err, tt.want)
+		}
+	}
+}
+
+func Test_notifiers_deliver(t *testing.T) {
+	var posted map[string]string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.Error(w, "no such hook", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("posted %s", r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&posted)
+	}))
+	defer hook.Close()
+
+	p, err := output.New("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "quotes.ndjson")
+	notifiers, err := newNotifiers([]string{"file=" + path, "webhook=" + hook.URL}, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []quote{{"Yoda", "Do, or do not"}, {"Ada", "b"}} {
+		for _, notify := range notifiers {
+			if err := notify(q); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	b, err := os.ReadFile(path)
+	if want := `{"Author":"Yoda","Quote":"Do, or do not"}` + "\n" + `{"Author":"Ada","Quote":"b"}` + "\n"; string(b) != want {
+		t.Errorf("file = %q, %v, want %q", b, err, want)
+	}
+	if want := "b\n— Ada"; posted["text"] != want {
+		t.Errorf("webhook got %q, want %q", posted["text"], want)
+	}
+
+	err = postWebhook(hook.URL+"/gone", quote{"Ada", "b"})
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found no such hook") {
+		t.Errorf("postWebhook() to a missing hook error = %v", err)
+	}
+}
+
+func Test_appleScriptString(t *testing.T) {
+	if got, want := appleScriptString(`say "hi" \ bye`), `"say \"hi\" \\ bye"`; got != want {
+		t.Errorf("appleScriptString() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package cmd
+
+import "syscall"
+
+// detachedAttr has nothing to set, the daemon keeps running after the command that
+// started it.
+func detachedAttr() *syscall.SysProcAttr {
+	return nil
+}
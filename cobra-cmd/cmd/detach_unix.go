@@ -0,0 +1,11 @@
+//go:build unix
+
+package cmd
+
+import "syscall"
+
+// detachedAttr starts the daemon in its own session, so closing the terminal doesn't
+// stop it.
+func detachedAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
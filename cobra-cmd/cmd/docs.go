@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+	"github.com/spf13/viper"
+)
+
+// docsCmd represents the docs command
+var docsCmd = &cobra.Command{
+	Use:   "docs man|markdown",
+	Short: "Generates the man pages or the markdown docs",
+	Long: `This command writes a page for every command of goclitool in --dir, as man pages
+in section 1 or as markdown. They're generated from the commands, so they always
+cover all of them and all of their flags.
+
+Example usage:
+goclitool docs man --dir /usr/share/man/man1
+goclitool docs markdown --dir docs
+`,
+	ValidArgs: []string{"man", "markdown"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := viper.GetString("dir")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			fatal(err)
+		}
+
+		// the pages don't say when they were generated, so they only change with goclitool
+		root := cmd.Root()
+		root.DisableAutoGenTag = true
+		var err error
+		if args[0] == "man" {
+			err = doc.GenManTree(root, &doc.GenManHeader{Title: "GOCLITOOL", Section: "1", Source: "goclitool " + buildVersion().Version}, dir)
+		} else {
+			err = doc.GenMarkdownTree(root, dir)
+		}
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(docsCmd)
+
+	// Adds a flag called --dir that defaults to the current directory
+	docsCmd.Flags().String("dir", ".", "Directory the pages are written to, created if needed")
+}
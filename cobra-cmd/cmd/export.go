@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"clikit/clierr"
+
+	"goclitool/output"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports the quotes to a CSV, JSON or NDJSON file",
+	Long: `This command writes quotes to --out, or to stdout without it, in --format,
+which defaults to the one of the extension of --out and else to json. --from
+chooses the quotes:
+  local     every quote of the cache and of the history, once
+  history   every quote of the history, with when and where it was fetched
+  server    --count quotes fetched from the server, like get does
+
+The file is written entirely or not at all, so a failed export doesn't replace
+the last one.
+
+Example usage:
+qotd export --format=csv --out=quotes.csv
+qotd export --from=server --count=100 --author=Eleanor --out=eleanor.ndjson
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := viper.GetString("out")
+		format := viper.GetString("format")
+		if format == "" {
+			format = exportFormat(out)
+		}
+		if format != "csv" && format != "json" && format != "ndjson" {
+			fatal(clierr.Errorf(clierr.Usage, "format has to be either csv, json or ndjson"))
+		}
+		p, err := output.New(format)
+		if err != nil {
+			fatal(err)
+		}
+		v, n, err := exportQuotes(cmd.Context(), cmd.Flags())
+		if err != nil {
+			fatal(err)
+		}
+		if err := writeExport(out, p, v); err != nil {
+			fatal(err)
+		}
+		slog.Info("exported the quotes", "count", n, "out", out, "format", format)
+	},
+}
+
+// exportFormat returns the format of the extension of the file, json when it has none
+// of csv, ndjson or jsonl.
+func exportFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	}
+	return "json"
+}
+
+// exportQuotes returns the quotes of --from, the ones whose author contains --author
+// whatever the case for the local ones, and their number.
+func exportQuotes(ctx context.Context, fs *pflag.FlagSet) (any, int, error) {
+	author := viper.GetString("author")
+	switch viper.GetString("from") {
+	case "local":
+		known, err := knownQuotes()
+		if err != nil {
+			return nil, 0, err
+		}
+		quotes := []quote{}
+		for _, q := range known {
+			if strings.Contains(strings.ToLower(q.Author), strings.ToLower(author)) {
+				quotes = append(quotes, q)
+			}
+		}
+		return quotes, len(quotes), nil
+	case "history":
+		db, err := openHistory()
+		if err != nil {
+			return nil, 0, err
+		}
+		defer db.Close()
+		entries, err := readHistory(db, time.Time{}, author)
+		return entries, len(entries), err
+	case "server":
+		count := viper.GetInt("count")
+		if count < 1 {
+			return nil, 0, clierr.Errorf(clierr.Usage, "count has to be at least 1")
+		}
+		quotes, err := getQuotes(ctx, fs, count)
+		return quotes, len(quotes), err
+	}
+	return nil, 0, clierr.Errorf(clierr.Usage, "from has to be either local, history or server")
+}
+
+// writeExport prints v to the file, through a temporary file renamed once it's all
+// written, or to stdout when the path is - or empty.
+func writeExport(path string, p *output.Printer, v any) error {
+	if path == "" || path == "-" {
+		return p.Print(os.Stdout, v)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	err = p.Print(f, v)
+	if err == nil {
+		err = f.Chmod(0o644)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func init() {
+	qotdCmd.AddCommand(exportCmd)
+
+	// Adds the flags of the connection to the server, see addConnFlags
+	// Adds a flag called --format that defaults to the extension of --out
+	// Adds a flag called --out that defaults to stdout
+	// Adds a flag called --from that defaults to local
+	// Adds a flag called --author that can be shortened to -a
+	// Adds a flag called --count that can be shortened to -n and defaults to 10, and --concurrency
+	addConnFlags(exportCmd.Flags())
+	exportCmd.Flags().String("format", "", "Format of the file: csv, json or ndjson, defaults to the extension of --out")
+	exportCmd.Flags().String("out", "-", "File the quotes are written to, - for stdout")
+	exportCmd.Flags().String("from", "local", "Quotes exported: local, history or server")
+	exportCmd.Flags().StringP("author", "a", "", "Only export the quotes of this author")
+	exportCmd.Flags().IntP("count", "n", 10, "Number of quotes fetched with --from=server, the duplicates being left out")
+	exportCmd.Flags().Int("concurrency", 4, "Number of quotes fetched at the same time with --from=server")
+	exportCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"csv", "json", "ndjson"}, cobra.ShellCompDirectiveNoFileComp))
+	exportCmd.RegisterFlagCompletionFunc("from", cobra.FixedCompletions([]string{"local", "history", "server"}, cobra.ShellCompDirectiveNoFileComp))
+}
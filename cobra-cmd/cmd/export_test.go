@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"goclitool/output"
+
+	"github.com/spf13/viper"
+)
+
+func Test_exportFormat(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"quotes.csv", "csv"},
+		{"QUOTES.CSV", "csv"},
+		{"quotes.ndjson", "ndjson"},
+		{"dir.v2/quotes.jsonl", "ndjson"},
+		{"quotes.json", "json"},
+		{"quotes", "json"},
+		{"quotes.txt", "json"},
+		{"", "json"},
+	}
+	for _, tt := range tests {
+		if got := exportFormat(tt.path); got != tt.want {
+			t.Errorf("exportFormat(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func Test_writeExport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "quotes.csv")
+	p, err := output.New("csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeExport(path, p, []quote{{"Yoda", "Do, or do not"}}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if want := "Author,Quote\nYoda,\"Do, or do not\"\n"; string(b) != want {
+		t.Errorf("exported %q, %v, want %q", b, err, want)
+	}
+
+	// a failed export leaves the last one as it was, and no temporary file
+	failing, err := output.New("go-template={{.Missing}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeExport(path, failing, quote{"Ada", "b"}); err == nil {
+		t.Error("writeExport() returned no error")
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(b) {
+		t.Errorf("the failed export replaced the last one with %q", after)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("left %d files in the directory, want 1", len(entries))
+	}
+}
+
+func Test_exportQuotes_local(t *testing.T) {
+	tempHome(t)
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	rememberQuotes(quote{"Mark Twain", "a"}, quote{"Yoda", "b"})
+	recordHistory("s", quote{"Yoda", "b"}, quote{"mark twain", "c"})
+
+	tests := []struct {
+		from, author string
+		want         []string // The quotes exported, in order
+	}{
+		{"local", "", []string{"a", "b", "c"}},
+		{"local", "TWAIN", []string{"a", "c"}},
+		{"history", "", []string{"b", "c"}},
+		{"history", "yoda", []string{"b"}},
+	}
+	for _, tt := range tests {
+		viper.Set("from", tt.from)
+		viper.Set("author", tt.author)
+		v, n, err := exportQuotes(context.Background(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		switch v := v.(type) {
+		case []quote:
+			for _, q := range v {
+				got = append(got, q.Quote)
+			}
+		case []historyEntry:
+			for _, e := range v {
+				got = append(got, e.Quote)
+			}
+		}
+		if n != len(got) || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("exportQuotes(%s, %q) = %v, %d, want %v", tt.from, tt.author, got, n, tt.want)
+		}
+	}
+
+	viper.Set("from", "nowhere")
+	if _, _, err := exportQuotes(context.Background(), nil); err == nil {
+		t.Error("exportQuotes() from nowhere returned no error")
+	}
+}
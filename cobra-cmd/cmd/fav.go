@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"clikit/clierr"
+
+	"goclitool/output"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// favoritesPath returns ~/.qotd/favorites.json.
+func favoritesPath() (string, error) {
+	dir, err := qotdDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "favorites.json"), nil
+}
+
+// loadFavorites reads the favorite quotes, none when the file doesn't exist yet.
+func loadFavorites() ([]quote, error) {
+	path, err := favoritesPath()
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var favorites []quote
+	if err := json.Unmarshal(b, &favorites); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return favorites, nil
+}
+
+func saveFavorites(favorites []quote) error {
+	path, err := favoritesPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(favorites)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o600)
+}
+
+// randomFavorites returns up to n random favorites of the author, or of any author
+// when it's empty, for get --random-fav.
+func randomFavorites(author string, n int) ([]quote, error) {
+	favorites, err := loadFavorites()
+	if err != nil {
+		return nil, err
+	}
+	favorites = slices.DeleteFunc(favorites, func(q quote) bool {
+		return author != "" && !strings.EqualFold(q.Author, author)
+	})
+	if len(favorites) == 0 {
+		if author == "" {
+			return nil, errors.New("no favorite yet, add one with qotd fav add")
+		}
+		return nil, fmt.Errorf("no favorite of %s yet", author)
+	}
+	rand.Shuffle(len(favorites), func(i, j int) { favorites[i], favorites[j] = favorites[j], favorites[i] })
+	return favorites[:min(n, len(favorites))], nil
+}
+
+// favorite is a favorite quote as listed, its ID being the one fav rm takes.
+type favorite struct {
+	ID     int
+	Author string
+	Quote  string
+}
+
+// favCmd represents the fav command
+var favCmd = &cobra.Command{
+	Use:   "fav",
+	Short: "Manages the favorite quotes",
+	Long: `This command manages the favorite quotes, kept in ~/.qotd/favorites.json, the
+ones f marks in browse too. get --random-fav prints one of them.
+
+Example usage:
+qotd get
+qotd fav add
+qotd fav list
+qotd fav rm 2
+`,
+}
+
+// favAddCmd represents the fav add command
+var favAddCmd = &cobra.Command{
+	Use:   "add [quote]",
+	Short: "Adds the last quote fetched, or the one given, to the favorites",
+	Long: `This command adds the quote to the favorites, the last one fetched from the
+server without an argument. The author of a quote given is the one of --author,
+else the one of the same quote fetched before.
+
+Example usage:
+qotd fav add
+qotd fav add "Simplicity is prerequisite for reliability." --author "Edsger Dijkstra"
+`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var q quote
+		var err error
+		if len(args) == 0 {
+			q, err = lastFetched()
+		} else {
+			q, err = knownAuthor(args[0], viper.GetString("author"))
+		}
+		if err != nil {
+			fatal(err)
+		}
+		favorites, err := loadFavorites()
+		if err != nil {
+			fatal(err)
+		}
+		if slices.Contains(favorites, q) {
+			fmt.Printf("%q by %s is a favorite already\n", q.Quote, q.Author)
+			return
+		}
+		if err := saveFavorites(append(favorites, q)); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("added %q by %s to the favorites\n", q.Quote, q.Author)
+	},
+}
+
+// lastFetched returns the last quote recorded in the history.
+func lastFetched() (quote, error) {
+	db, err := openHistory()
+	if err != nil {
+		return quote{}, err
+	}
+	defer db.Close()
+	entries, err := readHistory(db, time.Time{}, "")
+	if err != nil {
+		return quote{}, err
+	}
+	if len(entries) == 0 {
+		return quote{}, errors.New("no quote fetched yet, give the quote to add")
+	}
+	e := entries[len(entries)-1]
+	return quote{e.Author, e.Quote}, nil
+}
+
+// knownAuthor returns the quote of the author, looked up in the quotes fetched before
+// when it's empty.
+func knownAuthor(text, author string) (quote, error) {
+	if author != "" {
+		return quote{author, text}, nil
+	}
+	known, err := knownQuotes()
+	if err != nil {
+		return quote{}, err
+	}
+	for _, q := range known {
+		if q.Quote == text {
+			return q, nil
+		}
+	}
+	return quote{}, errors.New("the quote wasn't fetched before, give its author with --author")
+}
+
+// favListCmd represents the fav list command
+var favListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the favorite quotes",
+	Long: `This command prints the favorite quotes, in the order they were added, with
+the ID fav rm takes. --author only keeps the ones whose author contains it,
+whatever the case.
+
+Example usage:
+qotd fav list -o table
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		p, err := newPrinter()
+		if err != nil {
+			fatal(err)
+		}
+		favorites, err := loadFavorites()
+		if err != nil {
+			fatal(err)
+		}
+		author := strings.ToLower(viper.GetString("author"))
+		listed := []favorite{}
+		for i, q := range favorites {
+			if strings.Contains(strings.ToLower(q.Author), author) {
+				listed = append(listed, favorite{i + 1, q.Author, q.Quote})
+			}
+		}
+		if err := p.Print(os.Stdout, listed); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+// favRmCmd represents the fav rm command
+var favRmCmd = &cobra.Command{
+	Use:   "rm <id>...",
+	Short: "Removes quotes from the favorites",
+	Long: `This command removes the favorites with the IDs printed by fav list.
+
+Example usage:
+qotd fav rm 2 5
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		favorites, err := loadFavorites()
+		if err != nil {
+			fatal(err)
+		}
+		var ids []int
+		for _, arg := range args {
+			id, err := strconv.Atoi(arg)
+			if err != nil || id < 1 || id > len(favorites) {
+				fatal(clierr.Errorf(clierr.Usage, "%q isn't the ID of a favorite, see qotd fav list", arg))
+			}
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+		// removed from the last one, so the IDs left keep pointing to the same quotes
+		sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+		var removed []quote
+		for _, id := range ids {
+			removed = append(removed, favorites[id-1])
+			favorites = slices.Delete(favorites, id-1, id)
+		}
+		if err := saveFavorites(favorites); err != nil {
+			fatal(err)
+		}
+		for i := len(removed) - 1; i >= 0; i-- {
+			fmt.Printf("removed %q by %s from the favorites\n", removed[i].Quote, removed[i].Author)
+		}
+	},
+}
+
+func init() {
+	qotdCmd.AddCommand(favCmd)
+	favCmd.AddCommand(favAddCmd)
+	favCmd.AddCommand(favListCmd)
+	favCmd.AddCommand(favRmCmd)
+
+	// Adds a flag called --author to fav add and fav list, that can be shortened to -a
+	// Adds a flag called --output to fav list, that can be shortened to -o and defaults to text
+	favAddCmd.Flags().StringP("author", "a", "", "Author of the quote given, when it wasn't fetched before")
+	favListCmd.Flags().StringP("author", "a", "", "Only list the favorites whose author contains this, whatever the case")
+	output.AddFlag(favListCmd.Flags())
+}
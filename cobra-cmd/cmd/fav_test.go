@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_randomFavorites(t *testing.T) {
+	tempHome(t)
+	if _, err := randomFavorites("", 1); err == nil || !strings.Contains(err.Error(), "no favorite yet") {
+		t.Errorf("randomFavorites() without favorites error = %v", err)
+	}
+	if err := saveFavorites([]quote{{"Yoda", "a"}, {"Ada", "b"}, {"yoda", "c"}}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		author string
+		n      int
+		want   string // The quotes picked, sorted
+	}{
+		{"YODA", 5, "a c"},
+		{"", 5, "a b c"},
+		{"Ada", 1, "b"},
+	}
+	for _, tt := range tests {
+		favorites, err := randomFavorites(tt.author, tt.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, q := range favorites {
+			got = append(got, q.Quote)
+		}
+		sort.Strings(got)
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("randomFavorites(%q, %d) = %v, want %s", tt.author, tt.n, got, tt.want)
+		}
+	}
+	if favorites, _ := randomFavorites("", 2); len(favorites) != 2 {
+		t.Errorf("randomFavorites(\"\", 2) returned %d favorites", len(favorites))
+	}
+	// the author has to be the same, not contain the one given
+	if _, err := randomFavorites("Yod", 1); err == nil || !strings.Contains(err.Error(), "no favorite of Yod") {
+		t.Errorf("randomFavorites(Yod) error = %v", err)
+	}
+}
+
+func Test_lastFetched_knownAuthor(t *testing.T) {
+	tempHome(t)
+	if _, err := lastFetched(); err == nil {
+		t.Error("lastFetched() of an empty history returned no error")
+	}
+	rememberQuotes(quote{"Ada", "cached"})
+	recordHistory("s", quote{"Yoda", "first"}, quote{"Mark Twain", "last"})
+
+	if q, err := lastFetched(); err != nil || q != (quote{"Mark Twain", "last"}) {
+		t.Errorf("lastFetched() = %v, %v", q, err)
+	}
+	tests := []struct {
+		text, author string
+		want         quote
+		wantErr      bool
+	}{
+		{"first", "", quote{"Yoda", "first"}, false},
+		{"cached", "", quote{"Ada", "cached"}, false},
+		{"first", "Someone", quote{"Someone", "first"}, false},
+		{"new", "Someone", quote{"Someone", "new"}, false},
+		{"new", "", quote{}, true},
+	}
+	for _, tt := range tests {
+		q, err := knownAuthor(tt.text, tt.author)
+		if (err != nil) != tt.wantErr || q != tt.want {
+			t.Errorf("knownAuthor(%q, %q) = %v, %v, want %v", tt.text, tt.author, q, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"flag"
+	"strings"
+
+	"simpleCli"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// fsCmd represents the fs command, running the commands of simpleCli
+var fsCmd = &cobra.Command{
+	Use:   "fs <command> [options]",
+	Short: "Lists, searches, copies and watches files, like simpleCli",
+	Long: `This command runs the file commands of simpleCli, like list, tree, du, find,
+grep, hash or watch, with their own options. Run "goclitool fs -h" for the list of
+them.
+
+Example usage:
+goclitool fs list --sort=size .
+goclitool fs --no-pager grep -r TODO .
+`,
+	// the options are the ones of simpleCli, given to it as they are
+	DisableFlagParsing: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		simplecli.SelfUpdater = selfUpdater()
+		simplecli.Main(programName(cmd), args)
+	},
+}
+
+// newFsCommand returns the command running the simpleCli command c. Its flags are the
+// ones of c, shown by the help and completed, simpleCli still parsing them.
+func newFsCommand(c simplecli.Command) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                c.Name + " [flags] [arguments]",
+		Short:              c.Summary,
+		DisableFlagParsing: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			// the global options of simpleCli come before the command
+			globals, args := leadingFlags(cmd.InheritedFlags(), args)
+			simplecli.SelfUpdater = selfUpdater()
+			simplecli.Main(programName(fsCmd), append(append(globals, c.Name), args...))
+		},
+	}
+	addGoFlags(cmd.Flags(), c.Flags)
+	return cmd
+}
+
+// leadingFlags splits the flags of flags at the start of args, with their values,
+// from the rest.
+func leadingFlags(flags *pflag.FlagSet, args []string) (leading, rest []string) {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		f := flags.Lookup(name)
+		if f == nil && len(name) == 1 {
+			f = flags.ShorthandLookup(name)
+		}
+		if f == nil && name == "vv" {
+			f = flags.Lookup("verbose")
+		}
+		if f == nil {
+			break
+		}
+		n := 1
+		if !hasValue && f.NoOptDefVal == "" && len(args) > 1 {
+			n = 2
+		}
+		leading, args = append(leading, args[:n]...), args[n:]
+	}
+	return leading, args
+}
+
+// addGoFlags adds the flags of a former tool to the ones of its command. A one letter
+// flag described like a longer one, "(shorthand)" aside, becomes its shorthand.
+func addGoFlags(to *pflag.FlagSet, from *flag.FlagSet) {
+	long := map[string]*pflag.Flag{}
+	from.VisitAll(func(f *flag.Flag) {
+		if len(f.Name) > 1 {
+			to.AddGoFlag(f)
+			long[f.Usage] = to.Lookup(f.Name)
+		}
+	})
+	from.VisitAll(func(f *flag.Flag) {
+		if len(f.Name) > 1 {
+			return
+		}
+		if l, ok := long[strings.TrimSuffix(f.Usage, " (shorthand)")]; ok && l.Shorthand == "" {
+			l.Shorthand = f.Name
+			return
+		}
+		to.AddGoFlag(f)
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(fsCmd)
+	// -v, -q and --log-format are the ones of goclitool
+	fsCmd.PersistentFlags().Bool("no-pager", false, "Print the long outputs without the pager")
+	fsCmd.PersistentFlags().String("config", "", "File giving the default values of the flags (default ~/.goclirc)")
+	for _, c := range simplecli.Commands() {
+		fsCmd.AddCommand(newFsCommand(c))
+	}
+}
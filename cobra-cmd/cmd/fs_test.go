@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func Test_leadingFlags(t *testing.T) {
+	flags := pflag.NewFlagSet("fs", pflag.ContinueOnError)
+	flags.Bool("no-pager", false, "")
+	flags.String("config", "", "")
+	flags.CountP("verbose", "v", "")
+	tests := []struct {
+		args          string
+		leading, rest string
+	}{
+		{"--no-pager -v dir", "--no-pager -v", "dir"},
+		{"--config my.rc --sort size dir", "--config my.rc", "--sort size dir"},
+		{"--config=my.rc -vv dir", "--config=my.rc -vv", "dir"},
+		{"dir -v", "", "dir -v"},
+		{"-r --no-pager dir", "", "-r --no-pager dir"},
+		{"--config", "--config", ""},
+	}
+	for _, tt := range tests {
+		leading, rest := leadingFlags(flags, strings.Fields(tt.args))
+		if strings.Join(leading, " ") != tt.leading || strings.Join(rest, " ") != tt.rest {
+			t.Errorf("leadingFlags(%s) = %q, %q, want %s and %s", tt.args, leading, rest, tt.leading, tt.rest)
+		}
+	}
+}
+
+func Test_addGoFlags(t *testing.T) {
+	from := flag.NewFlagSet("list", flag.ContinueOnError)
+	from.Bool("recursive", false, "List the subdirectories too")
+	from.Bool("r", false, "List the subdirectories too (shorthand)")
+	from.Bool("l", false, "List the permissions")
+	from.String("sort", "name", "Order of the entries")
+	to := pflag.NewFlagSet("list", pflag.ContinueOnError)
+	addGoFlags(to, from)
+
+	var got []string
+	to.VisitAll(func(f *pflag.Flag) {
+		got = append(got, f.Shorthand+"/"+f.Name+"="+f.DefValue)
+	})
+	if want := []string{"l/l=false", "r/recursive=false", "/sort=name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("addGoFlags() added %q, want %q", got, want)
+	}
+	if f := to.Lookup("recursive"); f.NoOptDefVal != "true" {
+		t.Errorf("--recursive needs a value")
+	}
+}
@@ -4,13 +4,19 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"log/slog"
 	"os"
+	"slices"
+	"sync"
+
+	"clikit/clierr"
+
+	"goclitool/output"
 
-	"github.com/PacktPublishing/Go-for-DevOps/chapter/6/grpc/client"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
 )
 
 // getCmd represents the get command
@@ -18,77 +24,168 @@ var getCmd = &cobra.Command{
 	Use:   "get",
 	Short: "Fetches a quote of the day from the QOTD server",
 	Long: `This command allows you to fetch a quote of the day from our
-QOTD server we designed in our chapter on gRPC. This command defaults to a
-production server (which doesn't exist). This can be changed to the devlopement
-server (which doesn't exist) using --dev or to a specific address with --addr .
-
-Example usage for a random author:
-qotd get
+QOTD server we designed in our chapter on gRPC. --count fetches several of them.
+
+Connection:
+  --addr, --dev    the server, a production one by default (which doesn't exist)
+                   or the development one (which doesn't exist either) with --dev
+  --transport      grpc, http to talk to the JSON gateway at --http-addr when the
+                   gRPC port is blocked, or auto for http when grpc fails
+  --tls            secures the connection, --ca-cert, --client-cert and
+                   --client-key for a private CA or servers requiring mTLS
+  --auth-token     sent on every call, or --auth-token-file or QOTD_TOKEN, over
+                   TLS only unless --insecure-token is given for a dev server
+
+Output:
+  -o, --output     text, json, ndjson, yaml, table, csv, go-template=TEMPLATE
+                   or go-template-file=FILE
+
+Offline and cache:
+  --offline        prints a quote fetched before instead of asking the server,
+                   which get also does when the server can't be reached. Every
+                   quote fetched is cached in ~/.qotd/cache, and recorded in the
+                   history, see qotd history
+
+Favorites:
+  --random-fav     prints a random favorite instead of asking the server, see
+                   qotd fav
+
+Every flag can also be set in the config file or with a QOTD_* environment
+variable, see qotd config.
 
 Example usage for a specific author:
 qotd get --author="mark twain"
 
-Example usage using a 127.0.0.1 for the server:
-qotd get -addr=127.0.0.1:80 -author="mark twain"
+Example usage with a server of your own:
+qotd get --addr=127.0.0.1:80 --transport=auto
+
+Example usage over mTLS with a private CA:
+qotd get --addr=qotd.example.com:443 --ca-cert=ca.pem --client-cert=client.pem --client-key=client-key.pem
+
+Example usage for a handful of quotes, or the quote alone:
+qotd get -n 5 -o table
+qotd get -o go-template='{{.Quote}}{{"\n"}}'
+
+Example usage without the server, from the quotes fetched before:
+qotd get --offline --author="mark twain"
+
+Example usage for one of your favorites:
+qotd get --random-fav --author=Yoda
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		const devAddr = "127.0.0.1:3450"
 		fs := cmd.Flags()
-		addr := mustString(fs, "addr")
-
-		if mustBool(fs, "dev") {
-			addr = devAddr
-		}
-
-		c, err := client.New(addr)
+		p, err := newPrinter()
 		if err != nil {
-			fmt.Println("error: ", err)
-			os.Exit(1)
+			fatal(err)
 		}
-
-		a, q, err := c.QOTD(cmd.Context(), mustString(fs, "author"))
+		count := viper.GetInt("count")
+		if count < 1 {
+			fatal(clierr.Errorf(clierr.Usage, "count has to be at least 1"))
+		}
+		quotes, err := getQuotes(cmd.Context(), fs, count)
 		if err != nil {
-			fmt.Println("error: ", err)
-			os.Exit(1)
+			fatal(err)
 		}
 
-		switch {
-		case mustBool(fs, "json"):
-			b, err := json.Marshal(
-				struct {
-					Author string
-					Quote  string
-				}{a, q},
-			)
-			if err != nil {
-				panic(err)
-			}
-			fmt.Printf("%s\n", b)
-		default:
-			fmt.Println("Author: ", a)
-			fmt.Println("Quote: ", q)
+		// a single quote is printed alone, not as a list
+		var v any = quotes
+		if count == 1 {
+			v = quotes[0]
+		}
+		if err := p.Print(os.Stdout, v); err != nil {
+			fatal(err)
 		}
 	},
 }
 
-func mustString(fs *pflag.FlagSet, name string) string {
-	v, err := fs.GetString(name)
+// quote is a quote of the day, as printed by the commands.
+type quote struct {
+	Author string
+	Quote  string
+}
+
+// getQuotes fetches count quotes, with at most --concurrency calls at the same time,
+// and caches them. The duplicates are left out, the others are in the order of the
+// calls. It returns cached quotes instead with --offline, or when the server can't be
+// reached, and favorites with --random-fav.
+func getQuotes(ctx context.Context, fs *pflag.FlagSet, count int) ([]quote, error) {
+	author := viper.GetString("author")
+	if viper.GetBool("random-fav") {
+		return randomFavorites(author, count)
+	}
+	if viper.GetBool("offline") {
+		return cachedQuotes(author, count)
+	}
+	concurrency := viper.GetInt("concurrency")
+	if concurrency < 1 {
+		return nil, clierr.Errorf(clierr.Usage, "concurrency has to be at least 1")
+	}
+	c, err := newClient(fs)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	defer c.Close()
+
+	quotes := make([]quote, count)
+	errs := make([]error, count)
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range quotes {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			a, q, err := c.QOTD(ctx, author)
+			quotes[i], errs[i] = quote{a, q}, err
+		}(i)
+	}
+	wg.Wait()
+
+	var fetched []quote
+	var failed []error
+	for i, q := range quotes {
+		if errs[i] != nil {
+			failed = append(failed, errs[i])
+		} else if !slices.Contains(fetched, q) {
+			fetched = append(fetched, q)
+		}
 	}
-	return v
+	for _, err := range failed {
+		if !transient(err) {
+			return nil, err
+		}
+	}
+	if len(fetched) == 0 {
+		cached, cacheErr := cachedQuotes(author, count)
+		if cacheErr != nil {
+			return nil, failed[0]
+		}
+		slog.Warn("the server can't be reached, these quotes are from the cache", "err", failed[0])
+		return cached, nil
+	}
+	if len(failed) > 0 {
+		slog.Warn("some calls failed", "failed", len(failed), "calls", count, "err", failed[0])
+	}
+	rememberQuotes(fetched...)
+	recordHistory(c.transport.server(), fetched...)
+	return fetched, nil
 }
 
-func mustBool(fs *pflag.FlagSet, name string) bool {
-	v, err := fs.GetBool(name)
-	if err != nil {
-		panic(err)
+// newPrinter returns the printer of --output, or of the deprecated --json when
+// --output isn't set anywhere.
+func newPrinter() (*output.Printer, error) {
+	format := viper.GetString("output")
+	if !viper.IsSet("output") && viper.GetBool("json") {
+		format = "json"
 	}
-	return v
+	return output.New(format)
 }
 
 func init() {
-	rootCmd.AddCommand(getCmd)
+	qotdCmd.AddCommand(getCmd)
 
 	// Here you will define your flags and configuration settings.
 
@@ -100,12 +197,21 @@ func init() {
 	// is called directly, e.g.:
 	// getCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	// **************************************************************************************
-	// Adds a flag called --dev that can be shortened to -d and defaults to false
-	// Adds a flag called --addr that defaults to "127.0.0.1:80"
+	// Adds the flags of the connection to the server, see addConnFlags
 	// Adds a flag called --author that can be shortened to -a
-	// Adds a flag called --json that defaults to false
-	getCmd.Flags().BoolP("dev", "d", false, "Uses the dev server instead of prod")
-	getCmd.Flags().String("addr", "127.0.0.1:80", "Set the QOTD server to use, defaults to production")
+	// Adds a flag called --count that can be shortened to -n and defaults to 1
+	// Adds a flag called --concurrency that defaults to 4
+	// Adds a flag called --offline that defaults to false
+	// Adds a flag called --random-fav that defaults to false
+	// Adds a flag called --output that can be shortened to -o and defaults to text
+	// Keeps the flag called --json, replaced by --output=json
+	addConnFlags(getCmd.Flags())
 	getCmd.Flags().StringP("author", "a", "", "Specify the author to get a quote for")
+	getCmd.Flags().IntP("count", "n", 1, "Number of quotes fetched, the duplicates being left out")
+	getCmd.Flags().Int("concurrency", 4, "Number of quotes fetched at the same time with --count")
+	getCmd.Flags().Bool("offline", false, "Print a quote fetched before instead of asking the server")
+	getCmd.Flags().Bool("random-fav", false, "Print a random favorite instead of asking the server, see qotd fav")
+	output.AddFlag(getCmd.Flags())
 	getCmd.Flags().Bool("json", false, "Output is in JSON format")
+	getCmd.Flags().MarkDeprecated("json", "use --output=json instead")
 }
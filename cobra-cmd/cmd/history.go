@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"clikit/clierr"
+
+	"goclitool/output"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+)
+
+// historyBucket is the bucket of ~/.qotd/history.db holding the quotes fetched.
+var historyBucket = []byte("history")
+
+// historyEntry is a quote fetched from a server, as recorded in the history.
+type historyEntry struct {
+	Time   time.Time
+	Server string
+	Author string
+	Quote  string
+}
+
+// openHistory opens ~/.qotd/history.db, creating it when it doesn't exist. A watch
+// only holds it while recording, so the wait for the lock is short.
+func openHistory() (*bolt.DB, error) {
+	dir, err := qotdDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return bolt.Open(filepath.Join(dir, "history.db"), 0o600, &bolt.Options{Timeout: time.Second})
+}
+
+// historyKey sorts the entries by time, the sequence number telling apart the ones
+// recorded at the same time.
+func historyKey(t time.Time, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+// recordHistory adds the quotes fetched from the server to the history. Like the cache,
+// the history isn't worth failing a command for, so errors are warnings.
+func recordHistory(server string, quotes ...quote) {
+	db, err := openHistory()
+	if err == nil {
+		err = db.Update(func(tx *bolt.Tx) error {
+			b, err := tx.CreateBucketIfNotExists(historyBucket)
+			if err != nil {
+				return err
+			}
+			now := time.Now().Truncate(time.Second)
+			for _, q := range quotes {
+				seq, err := b.NextSequence()
+				if err != nil {
+					return err
+				}
+				v, err := json.Marshal(historyEntry{now, server, q.Author, q.Quote})
+				if err != nil {
+					return err
+				}
+				if err := b.Put(historyKey(now, seq), v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if closeErr := db.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		slog.Warn("can't record the history", "err", err)
+	}
+}
+
+// readHistory returns the entries recorded since the time, oldest first, whose author
+// contains the given one whatever the case.
+func readHistory(db *bolt.DB, since time.Time, author string) ([]historyEntry, error) {
+	entries := []historyEntry{}
+	author = strings.ToLower(author)
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(historyBucket)
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		k, v := c.First()
+		if !since.IsZero() {
+			k, v = c.Seek(historyKey(since, 0))
+		}
+		for ; k != nil; k, v = c.Next() {
+			var e historyEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return fmt.Errorf("history entry %x: %w", k, err)
+			}
+			if strings.Contains(strings.ToLower(e.Author), author) {
+				entries = append(entries, e)
+			}
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// parseSince parses --since, a date like 2024-03-05, a time like 2024-03-05T10:00:00Z,
+// or a duration before now like 36h or 7d.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, clierr.Errorf(clierr.Usage, "since %q has to be a date like 2024-03-05, a time like 2024-03-05T10:00:00Z or a duration like 7d", value)
+}
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Prints the quotes fetched before",
+	Long: `Every quote fetched by get or watch is recorded in ~/.qotd/history.db with
+the server it came from and the time. This command prints them, oldest first.
+
+Example usage for the quotes of mark twain fetched in the last week:
+qotd history --author=twain --since=7d
+
+Example usage for the quotes fetched since a date:
+qotd history --since=2024-03-05 -o table
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var since time.Time
+		if value := viper.GetString("since"); value != "" {
+			var err error
+			if since, err = parseSince(value, time.Now()); err != nil {
+				fatal(err)
+			}
+		}
+		p, err := output.New(viper.GetString("output"))
+		if err != nil {
+			fatal(err)
+		}
+		db, err := openHistory()
+		if err != nil {
+			fatal(err)
+		}
+		defer db.Close()
+
+		entries, err := readHistory(db, since, viper.GetString("author"))
+		if err != nil {
+			fatal(err)
+		}
+		if err := p.Print(os.Stdout, entries); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+// historyClearCmd represents the history clear command
+var historyClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Removes every quote from the history",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := openHistory()
+		if err != nil {
+			fatal(err)
+		}
+		defer db.Close()
+
+		err = db.Update(func(tx *bolt.Tx) error {
+			err := tx.DeleteBucket(historyBucket)
+			if err == bolt.ErrBucketNotFound {
+				return nil
+			}
+			return err
+		})
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	qotdCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyClearCmd)
+
+	// Adds a flag called --author that can be shortened to -a
+	// Adds a flag called --since
+	// Adds a flag called --output that can be shortened to -o and defaults to text
+	historyCmd.Flags().StringP("author", "a", "", "Only print the quotes whose author contains this, whatever the case")
+	historyCmd.Flags().String("since", "", "Only print the quotes fetched since a date like 2024-03-05, or a duration like 7d or 12h")
+	output.AddFlag(historyCmd.Flags())
+}
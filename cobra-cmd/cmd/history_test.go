@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func Test_readHistory(t *testing.T) {
+	tempHome(t)
+	db, err := openHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if entries, err := readHistory(db, time.Time{}, ""); err != nil || len(entries) != 0 {
+		t.Fatalf("readHistory() of an empty history = %v, %v", entries, err)
+	}
+
+	day := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	recorded := []historyEntry{
+		{day.AddDate(0, 0, 1), "s", "Yoda", "b"},
+		{day, "s", "Mark Twain", "a"},
+		{day.AddDate(0, 0, 1), "s", "Ada", "c"},
+		{day.AddDate(0, 0, 2), "s", "mark twain", "d"},
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(historyBucket)
+		if err != nil {
+			return err
+		}
+		for i, e := range recorded {
+			v, _ := json.Marshal(e)
+			if err := b.Put(historyKey(e.Time, uint64(i)), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		since  time.Time
+		author string
+		want   []string // The quotes read, in order
+	}{
+		{"All, oldest first", time.Time{}, "", []string{"a", "b", "c", "d"}},
+		{"Since a time", day.Add(time.Hour), "", []string{"b", "c", "d"}},
+		{"Since the exact time", day.AddDate(0, 0, 1), "", []string{"b", "c", "d"}},
+		{"Author contained whatever the case", time.Time{}, "TWAIN", []string{"a", "d"}},
+		{"Author and since", day.Add(time.Hour), "twain", []string{"d"}},
+		{"No match", time.Time{}, "shakespeare", []string{}},
+		{"After the last", day.AddDate(0, 0, 3), "", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := readHistory(db, tt.since, tt.author)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, e := range entries {
+				got = append(got, e.Quote)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readHistory(%v, %q) = %v, want %v", tt.since, tt.author, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_recordHistory(t *testing.T) {
+	tempHome(t)
+	recordHistory("127.0.0.1:3450", quote{"Yoda", "a"}, quote{"Yoda", "a"})
+	db, err := openHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	entries, err := readHistory(db, time.Time{}, "")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("readHistory() = %v, %v, want the 2 quotes recorded at the same time", entries, err)
+	}
+	if e := entries[0]; e.Server != "127.0.0.1:3450" || e.Author != "Yoda" || time.Since(e.Time) > time.Minute {
+		t.Errorf("recorded %+v", e)
+	}
+}
+
+func Test_parseSince(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"7d", now.AddDate(0, 0, -7), false},
+		{"0d", now, false},
+		{"36h", now.Add(-36 * time.Hour), false},
+		{"90m", now.Add(-90 * time.Minute), false},
+		{"2024-03-05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.Local), false},
+		{"2024-03-05T10:00:00Z", time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC), false},
+		{"-7d", time.Time{}, true},
+		{"-1h", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+		{"2024-13-01", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSince(tt.value, now)
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("parseSince(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
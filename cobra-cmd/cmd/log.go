@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+
+	"clikit/clierr"
+	"clikit/logging"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// logOptions are the values of the logging flags, read before the config file so it
+// can be logged.
+var logOptions logging.Options
+
+// initLogging sets the default logger from the logging flags. The diagnostics go to
+// stderr, the warnings and the errors only unless -v shows what the client does and
+// -vv every call. --quiet only leaves the errors.
+func initLogging() {
+	if err := logging.Setup(os.Stderr, logOptions, slog.LevelWarn); err != nil {
+		fatal(clierr.Wrap(clierr.Usage, err))
+	}
+}
+
+// fatal logs the error and exits with the code of its category, see package clierr,
+// which is what the commands do when they can't do what they were asked. The errors
+// of the server are network ones, but for the invalid arguments it rejects.
+func fatal(err error) {
+	var statusErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &statusErr) {
+		category := clierr.Network
+		if statusErr.GRPCStatus().Code() == codes.InvalidArgument {
+			category = clierr.Usage
+		}
+		err = clierr.Wrap(category, err)
+	}
+	clierr.Exit(err)
+}
+
+func init() {
+	// Adds a flag called --verbose to every command, that can be shortened to -v and repeated
+	// Adds a flag called --quiet to every command, that can be shortened to -q
+	// Adds a flag called --log-format to every command, that defaults to text
+	rootCmd.PersistentFlags().CountVarP(&logOptions.Verbose, "verbose", "v", "Log what the client does, -vv logs every call too")
+	rootCmd.PersistentFlags().BoolVarP(&logOptions.Quiet, "quiet", "q", false, "Only log the errors")
+	rootCmd.PersistentFlags().StringVar(&logOptions.Format, "log-format", "text", "Format of the logs written to stderr: text or json")
+	rootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
+	rootCmd.RegisterFlagCompletionFunc("log-format", cobra.FixedCompletions([]string{"text", "json"}, cobra.ShellCompDirectiveNoFileComp))
+}
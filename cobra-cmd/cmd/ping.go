@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"clikit/clierr"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// pingCmd represents the ping command
+var pingCmd = &cobra.Command{
+	Use:   "ping",
+	Short: "Checks that the QOTD server is reachable and how fast it answers",
+	Long: `This command calls the health service of the QOTD server --count times, every
+--interval, and prints for every call whether it answered, the status of the
+service, whether the connection is over TLS, and the time the call took. Servers
+without a health service are called with GetQOTD, and so are the HTTP gateways.
+Every call is made once with the deadline of --timeout, the retries are off.
+It exits with 5, the code of the network errors, when no call was answered.
+
+Example usage:
+qotd ping --addr qotd.example.com:443 --tls --count 10 --interval 500ms
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		count, interval := viper.GetInt("count"), viper.GetDuration("interval")
+		if count < 0 {
+			fatal(clierr.Errorf(clierr.Usage, "count can't be negative"))
+		}
+		if interval <= 0 {
+			fatal(clierr.Errorf(clierr.Usage, "interval has to be positive"))
+		}
+		c, err := newClient(cmd.Flags())
+		if err != nil {
+			fatal(err)
+		}
+		defer c.Close()
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		if received := pingServer(ctx, c, count, interval); received == 0 {
+			c.Close()
+			os.Exit(clierr.Network.ExitCode())
+		}
+	},
+}
+
+// pingServer pings the server count times, or until ctx is done when count is 0, and
+// prints the statistics. It returns the number of answers.
+func pingServer(ctx context.Context, c *qotdClient, count int, interval time.Duration) int {
+	var sent, received int
+	var minRTT, maxRTT, totalRTT time.Duration
+	for count == 0 || sent < count {
+		if sent > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		start := time.Now()
+		r, err := c.transport.ping(pingCtx)
+		rtt := time.Since(start)
+		cancel()
+		if ctx.Err() != nil {
+			break
+		}
+		sent++
+		// the server is read after the call, which is when auto knows the transport
+		server := c.transport.server()
+		if err != nil {
+			fmt.Printf("no answer from %s: %v\n", server, err)
+			continue
+		}
+		fmt.Printf("answer from %s: status=%s method=%s tls=%s time=%s\n", server, r.status, r.method, r.tls, rtt.Round(time.Microsecond))
+		if received == 0 || rtt < minRTT {
+			minRTT = rtt
+		}
+		maxRTT = max(maxRTT, rtt)
+		totalRTT += rtt
+		received++
+	}
+
+	fmt.Printf("\n--- %s ping statistics ---\n", c.transport.server())
+	loss := 0.0
+	if sent > 0 {
+		loss = 100 * float64(sent-received) / float64(sent)
+	}
+	fmt.Printf("%d calls, %d answered, %.0f%% lost\n", sent, received, loss)
+	if received > 0 {
+		avg := totalRTT / time.Duration(received)
+		fmt.Printf("time min/avg/max = %s/%s/%s\n", minRTT.Round(time.Microsecond), avg.Round(time.Microsecond), maxRTT.Round(time.Microsecond))
+	}
+	return received
+}
+
+func init() {
+	qotdCmd.AddCommand(pingCmd)
+
+	// Adds the flags of the connection to the server, see addConnFlags
+	// Adds a flag called --count that can be shortened to -c and defaults to 4
+	// Adds a flag called --interval that defaults to 1s
+	addConnFlags(pingCmd.Flags())
+	pingCmd.Flags().IntP("count", "c", 4, "Number of calls made, 0 to call until interrupted")
+	pingCmd.Flags().Duration("interval", time.Second, "Time between two calls")
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"clikit/clierr"
+
+	"github.com/spf13/viper"
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// proxyFor returns the proxy reaching addr, a host:port called over scheme, or nil to
+// connect directly. It's --proxy when it's set, direct turning the proxies off, else
+// $HTTPS_PROXY, or $HTTP_PROXY for http, else $ALL_PROXY. The ones of the environment
+// aren't used for the loopback addresses and the hosts of $NO_PROXY.
+func proxyFor(scheme, addr string) (*url.URL, error) {
+	if p := viper.GetString("proxy"); p != "" {
+		if p == "direct" {
+			return nil, nil
+		}
+		return parseProxy(p)
+	}
+
+	config := httpproxy.FromEnvironment()
+	all := os.Getenv("ALL_PROXY")
+	if all == "" {
+		all = os.Getenv("all_proxy")
+	}
+	if config.HTTPSProxy == "" {
+		config.HTTPSProxy = all
+	}
+	if config.HTTPProxy == "" {
+		config.HTTPProxy = all
+	}
+	u, err := config.ProxyFunc()(&url.URL{Scheme: scheme, Host: addr})
+	if err != nil || u == nil {
+		return nil, err
+	}
+	return parseProxy(u.String())
+}
+
+// parseProxy parses the URL of a proxy, http:// being implied without a scheme.
+func parseProxy(p string) (*url.URL, error) {
+	if !strings.Contains(p, "://") {
+		p = "http://" + p
+	}
+	u, err := url.Parse(p)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %q: %w", p, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, clierr.Errorf(clierr.Usage, "proxy %q has to be an http, https, socks5 or socks5h URL", p)
+	}
+	if u.Hostname() == "" {
+		return nil, clierr.Errorf(clierr.Usage, "proxy %q has no host", p)
+	}
+	return u, nil
+}
+
+// dialProxy connects to addr through the proxy, with SOCKS5 or HTTP CONNECT. The host
+// of addr is resolved by the proxy, which may be the only one able to.
+func dialProxy(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	if proxyURL.Scheme == "socks5" || proxyURL.Scheme == "socks5h" {
+		d, err := proxy.FromURL(proxyURL, proxy.Direct)
+		if err != nil {
+			return nil, err
+		}
+		return d.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	}
+
+	port := proxyURL.Port()
+	if port == "" {
+		port = "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(proxyURL.Hostname(), port))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy %s: %w", proxyURL.Host, err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: addr}, Host: addr, Header: http.Header{}}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)))
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %w", proxyURL.Host, err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %w", proxyURL.Host, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: CONNECT %s: %s", proxyURL.Host, addr, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	if br.Buffered() > 0 {
+		// the server spoke first, its bytes are in the reader already
+		return &bufferedConn{conn, br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn reads what was buffered while reading the answer of the proxy first.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// proxyDialer returns the dialer of the gRPC connections, going through the proxy of
+// proxyFor when there's one.
+func proxyDialer() func(ctx context.Context, addr string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		proxyURL, err := proxyFor("https", addr)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL == nil {
+			slog.Debug("dialing", "addr", addr)
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", addr)
+		}
+		slog.Debug("dialing through a proxy", "addr", addr, "proxy", proxyURL.Redacted())
+		return dialProxy(ctx, proxyURL, addr)
+	}
+}
+
+// httpProxy is the proxy of the requests to the HTTP gateway, net/http doing the rest.
+func httpProxy(req *http.Request) (*url.URL, error) {
+	return proxyFor(req.URL.Scheme, req.URL.Host)
+}
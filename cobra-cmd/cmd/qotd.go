@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// qotdCmd represents the qotd command, holding the commands of the QOTD client
+var qotdCmd = &cobra.Command{
+	Use:   "qotd",
+	Short: "A client of the QOTD server, getting quotes of the day",
+	Long: `qotd gets quotes of the day from the QOTD server, over gRPC or its HTTP
+gateway, remembers them to work offline, and searches and browses them.
+
+Every flag can be set in the config file too, ~/.qotd.yaml by default, or in
+the environment as QOTD_ followed by its name in upper case, like QOTD_ADDR.`,
+
+	// Gives the flags not given their value from the environment or the config file,
+	// before binding them like the root command does
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		initConfig()
+		if _, err := applyConfig(configSection(cmd), cmd.Flags()); err != nil {
+			fatal(err)
+		}
+		if err := viper.BindPFlags(cmd.Flags()); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(qotdCmd)
+
+	// Adds a flag called --config, the config file of all the qotd commands
+	qotdCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.qotd.yaml)")
+}
@@ -5,47 +5,100 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"clikit/clierr"
+	"clikit/logging"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "cobracli",
-	Short: "A brief description of your application",
-	Long: `A longer description that spans multiple lines and likely contains
-examples and usage of using your application. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+	Use:   "goclitool",
+	Short: "The QOTD client, the file commands and the CSV converter in one tool",
+	Long: `goclitool gathers the tools of this repository in one binary:
+
+  goclitool qotd ...               the client of the QOTD server, formerly qotd
+  goclitool fs ...                 the file commands, formerly simpleCli
+  goclitool convert csv2json ...   the CSV to JSON converter, formerly csv2json
+
+Installed under the name of one of the former tools too, like with a symbolic
+link named qotd, it runs that tool, so "qotd get" is "goclitool qotd get".
+
+On an error, the exit code tells its kind: 1 internal, 2 usage, 3 validation,
+4 io and 5 network. With --log-format=json, the error is logged with its
+category and code.`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+
+	// Binds the flags of the command being run, which the commands read with viper
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return viper.BindPFlags(cmd.Flags())
+	},
+}
+
+// tools are the commands run by goclitool when it's run under the name of one of
+// the former tools.
+var tools = map[string][]string{
+	"qotd":      {"qotd"},
+	"simpleCli": {"fs"},
+	"csv2json":  {"convert", "csv2json"},
+}
+
+// invokedAs is the former tool goclitool is run as, empty when it's run as goclitool.
+var invokedAs string
+
+// cliArgs are the arguments of the command run, the command of the former tool
+// included, so the daemon can be started again with them.
+var cliArgs = os.Args[1:]
+
+// programName is the name of the tool in the usages of a command running one, the
+// former tool goclitool is run as, else the path of the command.
+func programName(cmd *cobra.Command) string {
+	if invokedAs != "" {
+		return invokedAs
+	}
+	return cmd.CommandPath()
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	name := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	// selfupdate updates goclitool whatever the tool it's run as
+	if tool, ok := tools[name]; ok && (len(cliArgs) == 0 || cliArgs[0] != "selfupdate") {
+		invokedAs = name
+		cliArgs = append(slices.Clone(tool), cliArgs...)
+		rootCmd.SetArgs(cliArgs)
+	}
+	registerFlagCompletions(rootCmd)
+	// Logging like the flags will ask the errors found before they're parsed
+	logging.Setup(os.Stderr, logging.Options{}, slog.LevelWarn)
+	// The errors cobra returns are the ones of the command line, logged like the others
+	rootCmd.SilenceErrors = true
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		fatal(clierr.Wrap(clierr.Usage, err))
 	}
 }
 
 func init() {
+	cobra.OnInitialize(initLogging)
+
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.cobracli.yaml)")
-
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
-
-
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"clikit/clierr"
+
+	"goclitool/output"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// searchResult is a quote found by search, with its score.
+type searchResult struct {
+	Author string
+	Quote  string
+	Score  int
+}
+
+// scoreQuote returns how well the quote matches the terms, 0 when it has none of them.
+// Every term found counts much more than finding the same term again, and the terms
+// found together as written count more still.
+func scoreQuote(q quote, terms []string) int {
+	text := strings.ToLower(q.Quote)
+	author := strings.ToLower(q.Author)
+	score := 0
+	for _, term := range terms {
+		n := strings.Count(text, term)
+		if n > 0 {
+			score += 10 + n - 1
+		}
+		if strings.Contains(author, term) {
+			score += 5
+		}
+	}
+	if score > 0 && len(terms) > 1 && strings.Contains(text, strings.Join(terms, " ")) {
+		score += 20
+	}
+	return score
+}
+
+// searchQuotes returns the quotes matching some of the terms, whose author contains
+// the given one whatever the case, best first.
+func searchQuotes(quotes []quote, terms []string, author string) []searchResult {
+	for i, term := range terms {
+		terms[i] = strings.ToLower(term)
+	}
+	author = strings.ToLower(author)
+	results := []searchResult{}
+	for _, q := range quotes {
+		if !strings.Contains(strings.ToLower(q.Author), author) {
+			continue
+		}
+		if score := scoreQuote(q, terms); score > 0 {
+			results = append(results, searchResult{q.Author, q.Quote, score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results
+}
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search <terms>...",
+	Short: "Searches the quotes fetched before for some words",
+	Long: `This command searches the quotes of the cache and of the history for the
+given words, whatever their case, and prints the best matches first. The QOTD
+server can only give a quote of an author, so the quotes searched are the ones
+fetched before by get, watch or browse.
+
+Example usage:
+qotd search truth lie --author=twain -o table
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		limit := viper.GetInt("limit")
+		if limit < 0 {
+			fatal(clierr.Errorf(clierr.Usage, "limit can't be negative"))
+		}
+		p, err := output.New(viper.GetString("output"))
+		if err != nil {
+			fatal(err)
+		}
+		quotes, err := knownQuotes()
+		if err != nil {
+			fatal(err)
+		}
+
+		// the terms can be given as one argument too, like "truth lie"
+		terms := strings.Fields(strings.Join(args, " "))
+		results := searchQuotes(quotes, terms, viper.GetString("author"))
+		if limit > 0 && len(results) > limit {
+			results = results[:limit]
+		}
+		if err := p.Print(os.Stdout, results); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	qotdCmd.AddCommand(searchCmd)
+
+	// Adds a flag called --author that can be shortened to -a
+	// Adds a flag called --limit that defaults to 10
+	// Adds a flag called --output that can be shortened to -o and defaults to text
+	searchCmd.Flags().StringP("author", "a", "", "Only search the quotes whose author contains this, whatever the case")
+	searchCmd.Flags().Int("limit", 10, "Number of quotes printed at most (0 for all)")
+	output.AddFlag(searchCmd.Flags())
+}
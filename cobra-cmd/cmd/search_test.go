@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func Test_scoreQuote(t *testing.T) {
+	q := quote{"Mark Twain", "The truth is the truth, a lie is a lie"}
+	tests := []struct {
+		terms []string
+		want  int
+	}{
+		{[]string{"fiction"}, 0},
+		{[]string{"lie"}, 11},
+		{[]string{"truth"}, 11},
+		{[]string{"twain"}, 5},
+		{[]string{"truth", "lie"}, 22},
+		{[]string{"a", "lie"}, 11 + 5 + 11 + 20}, // "a" being in the author too
+		{[]string{"fiction", "twain"}, 5},
+	}
+	for _, tt := range tests {
+		if got := scoreQuote(q, tt.terms); got != tt.want {
+			t.Errorf("scoreQuote(%v) = %d, want %d", tt.terms, got, tt.want)
+		}
+	}
+}
+
+func Test_searchQuotes(t *testing.T) {
+	quotes := []quote{
+		{"Mark Twain", "Truth is stranger than fiction"},
+		{"Yoda", "Do, or do not"},
+		{"Mark Twain", "If you tell the truth you don't have to remember anything"},
+		{"Oscar Wilde", "The truth is rarely pure and never simple, truth"},
+	}
+	tests := []struct {
+		name   string
+		terms  []string
+		author string
+		want   []string // The authors and scores found, best first
+	}{
+		{"Best first, ties in order", []string{"TRUTH"}, "", []string{"Oscar Wilde 11", "Mark Twain 10", "Mark Twain 10"}},
+		{"Author", []string{"truth"}, "twain", []string{"Mark Twain 10", "Mark Twain 10"}},
+		{"Terms together", []string{"truth", "is"}, "", []string{"Oscar Wilde 41", "Mark Twain 40", "Mark Twain 10"}},
+		{"Nothing found", []string{"jedi"}, "", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, r := range searchQuotes(quotes, tt.terms, tt.author) {
+				got = append(got, fmt.Sprintf("%s %d", r.Author, r.Score))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("searchQuotes(%v, %q) = %v, want %v", tt.terms, tt.author, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+
+	"clikit/selfupdate"
+
+	"github.com/spf13/cobra"
+)
+
+// selfUpdateOptions are the values of the flags of selfupdate.
+var selfUpdateOptions selfupdate.Options
+
+// selfUpdater updates the executable of goclitool, whatever the tool it's run as.
+func selfUpdater() selfupdate.Updater {
+	return selfupdate.Updater{Tool: "goclitool", Version: buildVersion().Version}
+}
+
+// selfupdateCmd represents the selfupdate command
+var selfupdateCmd = &cobra.Command{
+	Use:   "selfupdate",
+	Short: "Replaces goclitool with its build in the latest release",
+	Long: `This command checks the latest release of goclitool on GitHub and, when it's
+newer than the version running, downloads the build of the platform, verifies it
+against the checksums of the release, signed when goclitool was built with a
+public key, and replaces the executable with it. The executable is only replaced
+once the download is complete and verified.
+
+The stable channel only has the releases, the beta one the pre-releases too.
+Run under the name of one of the former tools, like qotd selfupdate, it updates
+goclitool too.
+
+Example usage:
+goclitool selfupdate --check-only
+goclitool selfupdate --channel beta
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		u := selfUpdater()
+		if err := u.Run(ctx, selfUpdateOptions); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(selfupdateCmd)
+
+	// Adds the flags called --channel, --check-only and --endpoint
+	fs := flag.NewFlagSet("selfupdate", flag.ContinueOnError)
+	selfupdate.AddFlags(fs, &selfUpdateOptions)
+	selfupdateCmd.Flags().AddGoFlagSet(fs)
+}
@@ -0,0 +1,314 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"clikit/clierr"
+
+	pb "github.com/PacktPublishing/Go-for-DevOps/chapter/6/grpc/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// transport makes the calls to the QOTD server, so the commands don't depend on how
+// it's reached. The errors carry a gRPC status code whatever the transport, so the
+// retries and the fallbacks work the same.
+type transport interface {
+	getQOTD(ctx context.Context, author string) (quote, error)
+	ping(ctx context.Context) (pingResult, error)
+	server() string // as recorded in the history
+	Close() error
+}
+
+// pingResult is what a ping tells about the server.
+type pingResult struct {
+	status string // of the service, like SERVING
+	method string // called to ping
+	tls    string // version of TLS of the connection, or plaintext
+}
+
+// tlsVersion returns the version of the TLS connection, or plaintext without one.
+func tlsVersion(state *tls.ConnectionState) string {
+	if state == nil {
+		return "plaintext"
+	}
+	return tls.VersionName(state.Version)
+}
+
+// grpcTransport calls the QOTD service over gRPC.
+type grpcTransport struct {
+	client pb.QOTDClient
+	conn   *grpc.ClientConn
+	addr   string
+}
+
+// newGRPCTransport dials addr, over TLS when config isn't nil, sending the token on
+// every call when it isn't empty, which newClient only allows without TLS for
+// --insecure-token. The connection goes through the proxy of proxyFor.
+func newGRPCTransport(addr string, config *tls.Config, token string) (*grpcTransport, error) {
+	creds := insecure.NewCredentials()
+	if config != nil {
+		creds = credentials.NewTLS(config)
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds), grpc.WithContextDialer(proxyDialer())}
+	if token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials{token: token, insecure: config == nil}))
+	}
+	conn, err := grpc.Dial(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcTransport{client: pb.NewQOTDClient(conn), conn: conn, addr: addr}, nil
+}
+
+func (t *grpcTransport) getQOTD(ctx context.Context, author string) (quote, error) {
+	resp, err := t.client.GetQOTD(ctx, &pb.GetReq{Author: author})
+	if err != nil {
+		return quote{}, err
+	}
+	return quote{resp.Author, resp.Quote}, nil
+}
+
+// ping calls the health service of the server, and GetQOTD when it has none.
+func (t *grpcTransport) ping(ctx context.Context) (pingResult, error) {
+	var p peer.Peer
+	resp, err := healthpb.NewHealthClient(t.conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Peer(&p))
+	result := pingResult{method: "grpc.health.v1.Health/Check"}
+	if status.Code(err) == codes.Unimplemented {
+		_, err = t.client.GetQOTD(ctx, &pb.GetReq{}, grpc.Peer(&p))
+		result = pingResult{status: "SERVING", method: "GetQOTD"}
+	} else if err == nil {
+		result.status = resp.Status.String()
+	}
+	if err != nil {
+		return pingResult{}, err
+	}
+	var state *tls.ConnectionState
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		state = &info.State
+	}
+	result.tls = tlsVersion(state)
+	return result, nil
+}
+
+func (t *grpcTransport) server() string {
+	return t.addr
+}
+
+func (t *grpcTransport) Close() error {
+	return t.conn.Close()
+}
+
+// httpTransport calls the JSON over HTTP gateway of the QOTD service, which answers
+// GET /v1/qotd?author=... with {"author": ..., "quote": ...}.
+type httpTransport struct {
+	client *http.Client
+	url    string // of the gateway, without the path of the call
+	token  string
+}
+
+// newHTTPTransport returns a transport to the gateway at addr, a URL or a host:port
+// reached over https when config isn't nil.
+func newHTTPTransport(addr string, config *tls.Config, token string) (*httpTransport, error) {
+	if !strings.Contains(addr, "://") {
+		scheme := "http://"
+		if config != nil {
+			scheme = "https://"
+		}
+		addr = scheme + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, clierr.Errorf(clierr.Usage, "http-addr %q has to be an http or https URL", addr)
+	}
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           httpProxy,
+		TLSClientConfig: config,
+	}}
+	return &httpTransport{client: client, url: strings.TrimSuffix(u.String(), "/"), token: token}, nil
+}
+
+func (t *httpTransport) getQOTD(ctx context.Context, author string) (quote, error) {
+	q, _, err := t.get(ctx, author)
+	return q, err
+}
+
+// ping makes a GetQOTD call, the gateways having no health check.
+func (t *httpTransport) ping(ctx context.Context) (pingResult, error) {
+	_, state, err := t.get(ctx, "")
+	if err != nil {
+		return pingResult{}, err
+	}
+	return pingResult{status: "SERVING", method: "GET /v1/qotd", tls: tlsVersion(state)}, nil
+}
+
+// get makes the call to the gateway, also returning the state of its TLS connection.
+func (t *httpTransport) get(ctx context.Context, author string) (quote, *tls.ConnectionState, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url+"/v1/qotd?"+url.Values{"author": {author}}.Encode(), nil)
+	if err != nil {
+		return quote{}, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return quote{}, nil, status.Error(codes.DeadlineExceeded, err.Error())
+		}
+		return quote{}, nil, status.Error(codes.Unavailable, err.Error())
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return quote{}, nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		// the gateways write the error as {"message": ...}, else the body is used as is
+		var e struct{ Message string }
+		message := strings.TrimSpace(string(body))
+		if json.Unmarshal(body, &e) == nil && e.Message != "" {
+			message = e.Message
+		}
+		return quote{}, nil, status.Errorf(httpCode(resp.StatusCode), "%s: %s", resp.Status, message)
+	}
+
+	var q struct {
+		Author string `json:"author"`
+		Quote  string `json:"quote"`
+	}
+	if err := json.Unmarshal(body, &q); err != nil {
+		return quote{}, nil, fmt.Errorf("%s: %w", t.url, err)
+	}
+	return quote{q.Author, q.Quote}, resp.TLS, nil
+}
+
+func (t *httpTransport) server() string {
+	return t.url
+}
+
+func (t *httpTransport) Close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
+
+// httpCode returns the gRPC code of an HTTP status, the way the gateways map them.
+func httpCode(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	return codes.Unknown
+}
+
+// autoTransport calls over gRPC, and over HTTP from the first call gRPC can't make,
+// like when its port is blocked by a proxy, or when the port never answers until gRPC
+// has answered once. The calls made before that give gRPC half of their deadline, so
+// there's time left for HTTP.
+type autoTransport struct {
+	grpc         transport
+	http         transport
+	useHTTP      atomic.Bool
+	grpcAnswered atomic.Bool
+}
+
+func (t *autoTransport) getQOTD(ctx context.Context, author string) (quote, error) {
+	if !t.useHTTP.Load() {
+		grpcCtx, cancel := t.grpcContext(ctx)
+		q, err := t.grpc.getQOTD(grpcCtx, author)
+		cancel()
+		if !t.fallBack(ctx, err) {
+			return q, err
+		}
+	}
+	return t.http.getQOTD(ctx, author)
+}
+
+func (t *autoTransport) ping(ctx context.Context) (pingResult, error) {
+	if !t.useHTTP.Load() {
+		grpcCtx, cancel := t.grpcContext(ctx)
+		r, err := t.grpc.ping(grpcCtx)
+		cancel()
+		if !t.fallBack(ctx, err) {
+			return r, err
+		}
+	}
+	return t.http.ping(ctx)
+}
+
+// grpcContext returns the context of a gRPC call, with half of the deadline of ctx
+// until gRPC has answered once.
+func (t *autoTransport) grpcContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || t.grpcAnswered.Load() {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, time.Now().Add(time.Until(deadline)/2))
+}
+
+// fallBack tells if the call failing with err has to be made over HTTP, switching to
+// it for the next ones too: when gRPC is unavailable, or hasn't answered yet in half
+// of the deadline of ctx.
+func (t *autoTransport) fallBack(ctx context.Context, err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable:
+	case codes.DeadlineExceeded:
+		if t.grpcAnswered.Load() || ctx.Err() != nil {
+			return false
+		}
+	default:
+		t.grpcAnswered.Store(true)
+		return false
+	}
+	t.switchToHTTP(err)
+	return true
+}
+
+func (t *autoTransport) switchToHTTP(err error) {
+	if !t.useHTTP.Swap(true) {
+		slog.Info("gRPC can't be reached, switching to HTTP", "err", err, "server", t.http.server())
+	}
+}
+
+func (t *autoTransport) server() string {
+	if t.useHTTP.Load() {
+		return t.http.server()
+	}
+	return t.grpc.server()
+}
+
+func (t *autoTransport) Close() error {
+	return errors.Join(t.grpc.Close(), t.http.Close())
+}
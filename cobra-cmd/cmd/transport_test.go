@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeTransport answers every call with its quote, or fails with its error.
+type fakeTransport struct {
+	q     quote
+	err   error
+	calls int
+}
+
+func (t *fakeTransport) getQOTD(ctx context.Context, author string) (quote, error) {
+	t.calls++
+	return t.q, t.err
+}
+
+func (t *fakeTransport) ping(ctx context.Context) (pingResult, error) {
+	t.calls++
+	return pingResult{status: "SERVING"}, t.err
+}
+
+func (t *fakeTransport) server() string { return "fake" }
+func (t *fakeTransport) Close() error   { return nil }
+
+func Test_autoTransport(t *testing.T) {
+	tests := []struct {
+		name         string
+		grpcErrs     []error // of the successive calls
+		wantHTTP     []bool  // whether each call was made over HTTP
+		wantLastCode codes.Code
+	}{
+		{"gRPC answers", []error{nil, nil}, []bool{false, false}, codes.OK},
+		{"gRPC unavailable", []error{status.Error(codes.Unavailable, "refused")}, []bool{true}, codes.OK},
+		{"gRPC never answers", []error{status.Error(codes.DeadlineExceeded, "timeout")}, []bool{true}, codes.OK},
+		{"gRPC slow once it answered", []error{nil, status.Error(codes.DeadlineExceeded, "timeout")}, []bool{false, false}, codes.DeadlineExceeded},
+		{"gRPC unavailable once it answered", []error{nil, status.Error(codes.Unavailable, "refused"), nil}, []bool{false, true, true}, codes.OK},
+		{"gRPC error from the server", []error{status.Error(codes.NotFound, "no author"), status.Error(codes.DeadlineExceeded, "timeout")}, []bool{false, false}, codes.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grpcT, httpT := &fakeTransport{q: quote{"a", "grpc"}}, &fakeTransport{q: quote{"a", "http"}}
+			auto := &autoTransport{grpc: grpcT, http: httpT}
+			var err error
+			for i, grpcErr := range tt.grpcErrs {
+				grpcT.err = grpcErr
+				httpCalls := httpT.calls
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				var q quote
+				q, err = auto.getQOTD(ctx, "")
+				cancel()
+				if gotHTTP := httpT.calls > httpCalls; gotHTTP != tt.wantHTTP[i] {
+					t.Errorf("call %d over HTTP = %v, want %v", i+1, gotHTTP, tt.wantHTTP[i])
+				}
+				if err == nil && q.Quote != map[bool]string{false: "grpc", true: "http"}[tt.wantHTTP[i]] {
+					t.Errorf("call %d = %v", i+1, q)
+				}
+			}
+			if code := status.Code(err); code != tt.wantLastCode {
+				t.Errorf("last call error = %v, want %v", err, tt.wantLastCode)
+			}
+		})
+	}
+}
+
+func Test_autoTransport_silentListener(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("ALL_PROXY", "")
+	connFlags(t)
+
+	// accepts the connections of gRPC and never writes a byte to them
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"author": "a", "quote": "over http"}`)
+	}))
+	defer gateway.Close()
+
+	grpcT, err := newGRPCTransport(ln.Addr().String(), nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpT, err := newHTTPTransport(gateway.URL, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auto := &autoTransport{grpc: grpcT, http: httpT}
+	client := &qotdClient{transport: auto, timeout: time.Second, retries: 0, backoff: time.Millisecond}
+	defer client.Close()
+
+	start := time.Now()
+	author, q, err := client.QOTD(context.Background(), "")
+	if err != nil || author != "a" || q != "over http" {
+		t.Fatalf("QOTD() = %q, %q, %v", author, q, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("QOTD() took %v, more than the timeout", elapsed)
+	}
+	if auto.server() != gateway.URL {
+		t.Errorf("server() = %s after the fallback, want %s", auto.server(), gateway.URL)
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"goclitool/output"
+
+	"github.com/spf13/cobra"
+)
+
+// The build metadata, set when building a release with
+//
+//	go build -ldflags "-X goclitool/cmd.version=1.2.0 -X goclitool/cmd.commit=$(git rev-parse HEAD) -X goclitool/cmd.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// The ones left empty are read from what the go command embeds in the binary.
+var (
+	version string
+	commit  string
+	date    string
+)
+
+// versionInfo is what version prints.
+type versionInfo struct {
+	Version   string
+	Commit    string
+	Date      string
+	GoVersion string
+	Platform  string
+}
+
+// buildVersion returns the build metadata, from the ldflags else from the build info
+// of the binary: the module version with go install, and the commit with go build in
+// a git checkout. What's known nowhere is "unknown", and the version "dev".
+func buildVersion() versionInfo {
+	info := versionInfo{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		var modified bool
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = s.Value
+				}
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		// the commit read from the checkout isn't what was built when it had changes
+		if modified && commit == "" && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.Date == "" {
+		info.Date = "unknown"
+	}
+	return info
+}
+
+// versionCmd represents the version command
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Prints the version of goclitool and how it was built",
+	Long: `This command prints the version of goclitool, the git commit and the date it was
+built from, and the Go version and the platform it was built with. Put it in the
+bug reports.
+
+Example usage:
+goclitool version --output json
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		p, err := newPrinter()
+		if err != nil {
+			fatal(err)
+		}
+		if err := p.Print(os.Stdout, buildVersion()); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+	// Adds a flag called --version to goclitool, printing the version only
+	rootCmd.Version = buildVersion().Version
+
+	// Adds a flag called --output that can be shortened to -o and defaults to text
+	output.AddFlag(versionCmd.Flags())
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+
+	"clikit/clierr"
+
+	"goclitool/output"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Prints every new quote of the day from the QOTD server",
+	Long: `This command asks the QOTD server for a quote every --interval and prints
+it when it changed, until interrupted with Ctrl-C. The QOTD server has no
+streaming call, so the quotes are polled. It takes the same flags as get to
+choose the server, the author and the output format. With -o json every quote
+is on its own line, to pipe the feed into other tools.
+
+Example usage:
+qotd watch --interval=1m -o json | jq -r .Quote
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		interval := viper.GetDuration("interval")
+		if interval <= 0 {
+			fatal(clierr.Errorf(clierr.Usage, "interval has to be positive"))
+		}
+		p, err := newPrinter()
+		if err != nil {
+			fatal(err)
+		}
+		c, err := newClient(cmd.Flags())
+		if err != nil {
+			fatal(err)
+		}
+		defer c.Close()
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		if err := watchQuotes(ctx, c, p, interval); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+// watchQuotes prints the quote, then the next ones that differ from the last one
+// printed, until ctx is done. The errors are reported without stopping, the server
+// may be back at the next tick.
+func watchQuotes(ctx context.Context, c *qotdClient, p *output.Printer, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last *quote
+	for {
+		a, q, err := c.QOTD(ctx, viper.GetString("author"))
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil:
+			slog.Error("can't get a quote", "err", err)
+		case last == nil || *last != (quote{a, q}):
+			last = &quote{a, q}
+			rememberQuotes(*last)
+			recordHistory(c.transport.server(), *last)
+			if err := p.Print(os.Stdout, *last); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func init() {
+	qotdCmd.AddCommand(watchCmd)
+
+	// Adds the flags of the connection to the server, see addConnFlags
+	// Adds a flag called --author that can be shortened to -a
+	// Adds a flag called --output that can be shortened to -o and defaults to text
+	// Adds a flag called --interval that defaults to 10s
+	addConnFlags(watchCmd.Flags())
+	watchCmd.Flags().StringP("author", "a", "", "Specify the author to get quotes for")
+	output.AddFlag(watchCmd.Flags())
+	watchCmd.Flags().Duration("interval", 10*time.Second, "Time between two quotes asked to the server")
+}
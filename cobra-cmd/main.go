@@ -4,7 +4,7 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 */
 package main
 
-import "cobracli/cmd"
+import "goclitool/cmd"
 
 func main() {
 	cmd.Execute()
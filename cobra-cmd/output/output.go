@@ -0,0 +1,194 @@
+// Package output prints the results of the commands in the format chosen with
+// --output, like kubectl does.
+package output
+
+import (
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// Usage is the help of the --output flag.
+const Usage = "Output format: text, json, ndjson, yaml, table, csv, go-template=TEMPLATE or go-template-file=FILE"
+
+// AddFlag adds --output, that can be shortened to -o and defaults to text.
+func AddFlag(fs *pflag.FlagSet) {
+	fs.StringP("output", "o", "text", Usage)
+}
+
+// Printer writes values in one of the output formats. The values are structs or
+// slices of structs, whose exported fields are printed.
+type Printer struct {
+	format string
+	tmpl   *template.Template // for go-template and go-template-file
+}
+
+// New returns the printer of the format, checking it and parsing its template.
+func New(format string) (*Printer, error) {
+	name, arg, _ := strings.Cut(format, "=")
+	switch name {
+	case "text", "json", "ndjson", "yaml", "table", "csv":
+		if arg != "" {
+			return nil, fmt.Errorf("output %s doesn't take a value", name)
+		}
+		return &Printer{format: name}, nil
+	case "go-template", "go-template-file":
+		text := arg
+		if name == "go-template-file" {
+			b, err := os.ReadFile(arg)
+			if err != nil {
+				return nil, err
+			}
+			text = string(b)
+		}
+		if text == "" {
+			return nil, fmt.Errorf("output %s needs a template, like %s={{.Quote}}", name, name)
+		}
+		tmpl, err := template.New("output").Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		return &Printer{format: "go-template", tmpl: tmpl}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, it has to be one of text, json, ndjson, yaml, table, csv, go-template=TEMPLATE or go-template-file=FILE", format)
+}
+
+// Print writes the value to w.
+func (p *Printer) Print(w io.Writer, v any) error {
+	switch p.format {
+	case "json":
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	case "ndjson":
+		return printNDJSON(w, v)
+	case "yaml":
+		// indented like the config files, written with the same library
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "table":
+		return printTable(w, v)
+	case "csv":
+		return printCSV(w, v)
+	case "go-template":
+		// like kubectl, the template decides where the new lines go
+		return p.tmpl.Execute(w, v)
+	}
+	return printText(w, v)
+}
+
+// rows returns the structs of the value, which is a struct or a slice of them.
+func rows(v any) []reflect.Value {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Slice {
+		return []reflect.Value{value}
+	}
+	rows := make([]reflect.Value, value.Len())
+	for i := range rows {
+		rows[i] = reflect.Indirect(value.Index(i))
+	}
+	return rows
+}
+
+// fields returns the names and the values of the exported fields of the struct.
+func fields(row reflect.Value) (names []string, values []any) {
+	for i := 0; i < row.NumField(); i++ {
+		if f := row.Type().Field(i); f.IsExported() {
+			names = append(names, f.Name)
+			values = append(values, row.Field(i).Interface())
+		}
+	}
+	return names, values
+}
+
+// printText writes every field on its own line, and a blank line between the rows.
+func printText(w io.Writer, v any) error {
+	for i, row := range rows(v) {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		names, values := fields(row)
+		for j, name := range names {
+			if _, err := fmt.Fprintln(w, name+": ", values[j]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// printTable writes a row per struct, under the names of the fields in capitals.
+func printTable(w io.Writer, v any) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, row := range rows(v) {
+		names, values := fields(row)
+		if i == 0 {
+			fmt.Fprintln(table, strings.ToUpper(strings.Join(names, "\t")))
+		}
+		cells := make([]string, len(values))
+		for j, value := range values {
+			cells[j] = fmt.Sprint(value)
+		}
+		fmt.Fprintln(table, strings.Join(cells, "\t"))
+	}
+	return table.Flush()
+}
+
+// printNDJSON writes every struct as JSON on its own line.
+func printNDJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	for _, row := range rows(v) {
+		if err := enc.Encode(row.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printCSV writes a record per struct, under the names of the fields. The values
+// that can be written as text, like the times, are, so they can be read back.
+func printCSV(w io.Writer, v any) error {
+	cw := csv.NewWriter(w)
+	for i, row := range rows(v) {
+		names, values := fields(row)
+		if i == 0 {
+			if err := cw.Write(names); err != nil {
+				return err
+			}
+		}
+		record := make([]string, len(values))
+		for j, value := range values {
+			if m, ok := value.(encoding.TextMarshaler); ok {
+				b, err := m.MarshalText()
+				if err != nil {
+					return err
+				}
+				record[j] = string(b)
+				continue
+			}
+			record[j] = fmt.Sprint(value)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,67 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type quote struct {
+	Author string
+	Quote  string
+	At     time.Time
+	cached bool
+}
+
+func TestPrinter(t *testing.T) {
+	at := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	one := quote{"Yoda", "Do, or do not", at, true}
+	two := []quote{one, {"Ada", "a, \"b\"", at, false}}
+	tmplFile := filepath.Join(t.TempDir(), "quote.tmpl")
+	if err := os.WriteFile(tmplFile, []byte("{{range .}}{{.Author}};{{end}}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		format string
+		v      any
+		want   string
+	}{
+		{"text", one, "Author:  Yoda\nQuote:  Do, or do not\nAt:  2024-05-10 12:00:00 +0000 UTC\n"},
+		{"text", two, "Author:  Yoda\nQuote:  Do, or do not\nAt:  2024-05-10 12:00:00 +0000 UTC\n\nAuthor:  Ada\nQuote:  a, \"b\"\nAt:  2024-05-10 12:00:00 +0000 UTC\n"},
+		{"json", one, `{"Author":"Yoda","Quote":"Do, or do not","At":"2024-05-10T12:00:00Z"}` + "\n"},
+		{"json", []quote{}, "[]\n"},
+		{"ndjson", two, `{"Author":"Yoda","Quote":"Do, or do not","At":"2024-05-10T12:00:00Z"}` + "\n" + `{"Author":"Ada","Quote":"a, \"b\"","At":"2024-05-10T12:00:00Z"}` + "\n"},
+		{"ndjson", &one, `{"Author":"Yoda","Quote":"Do, or do not","At":"2024-05-10T12:00:00Z"}` + "\n"},
+		{"yaml", one, "author: Yoda\nquote: Do, or do not\nat: 2024-05-10T12:00:00Z\n"},
+		{"yaml", two, "- author: Yoda\n  quote: Do, or do not\n  at: 2024-05-10T12:00:00Z\n- author: Ada\n  quote: a, \"b\"\n  at: 2024-05-10T12:00:00Z\n"},
+		{"table", two, "AUTHOR  QUOTE          AT\nYoda    Do, or do not  2024-05-10 12:00:00 +0000 UTC\nAda     a, \"b\"         2024-05-10 12:00:00 +0000 UTC\n"},
+		{"csv", two, "Author,Quote,At\nYoda,\"Do, or do not\",2024-05-10T12:00:00Z\nAda,\"a, \"\"b\"\"\",2024-05-10T12:00:00Z\n"},
+		{"go-template={{.Author}}: {{.Quote}}", one, "Yoda: Do, or do not"},
+		{"go-template-file=" + tmplFile, two, "Yoda;Ada;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			p, err := New(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b strings.Builder
+			if err := p.Print(&b, tt.v); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Print() =\n%q\nwant\n%q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_errors(t *testing.T) {
+	for _, format := range []string{"xml", "json=x", "go-template=", "go-template={{.Quote", "go-template-file=/does/not/exist"} {
+		if _, err := New(format); err == nil {
+			t.Errorf("New(%q) returned no error", format)
+		}
+	}
+}
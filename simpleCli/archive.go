@@ -0,0 +1,120 @@
+package simplecli
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"clikit/clierr"
+)
+
+// runArchive implements "simpleCli archive [options] <file>", listing the content of a
+// zip, tar or tar.gz file like list does for a directory.
+func runArchive(args []string) error {
+	flags := newFlagSet("archive", "archive [options] <file.zip|file.tar|file.tar.gz>")
+	display := addDisplayFlags(flags)
+	match := flags.String("match", "", "Only list the entries whose name matches this glob, like '*.go'")
+	ext := flags.String("ext", "", "Only list the entries with one of these comma separated extensions, like .csv,.json")
+	noColor := flags.Bool("no-color", false, "Don't color the names by type, they're only colored when printing to a terminal")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return clierr.Errorf(clierr.Usage, "archive needs a single zip, tar or tar.gz file")
+	}
+	if err := display.validate(); err != nil {
+		return err
+	}
+	filters := filterFlags{opts: listOptions{match: *match, all: true}, ext: *ext, noColor: *noColor}
+	opts, err := filters.options()
+	if err != nil {
+		return err
+	}
+
+	entries, err := archiveEntries(positional[0])
+	if err != nil {
+		return err
+	}
+	var matching []entry
+	for _, e := range entries {
+		if opts.matches(baseName(e.path)) {
+			matching = append(matching, e)
+		}
+	}
+	return display.print(os.Stdout, matching, opts.color)
+}
+
+// archiveEntries reads the entries of the archive, whose format is found from its
+// first bytes rather than its extension.
+func archiveEntries(path string) ([]entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	magic, _ := r.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")), bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return zipEntries(f, info.Size())
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return tarEntries(gz)
+	}
+	return tarEntries(r)
+}
+
+func zipEntries(r io.ReaderAt, size int64) ([]entry, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]entry, len(zr.File))
+	for i, file := range zr.File {
+		entries[i] = entry{path: strings.TrimSuffix(file.Name, "/"), info: file.FileInfo()}
+	}
+	return entries, nil
+}
+
+func tarEntries(r io.Reader) ([]entry, error) {
+	tr := tar.NewReader(r)
+	var entries []entry
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("not a zip, tar or tar.gz file: %w", err)
+		}
+		// the metadata of the whole archive isn't a file
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		entries = append(entries, entry{
+			path:   strings.TrimSuffix(header.Name, "/"),
+			info:   header.FileInfo(),
+			target: header.Linkname,
+		})
+	}
+}
+
+// baseName is the last element of a path inside an archive, which always uses slashes.
+func baseName(name string) string {
+	return name[strings.LastIndex(name, "/")+1:]
+}
@@ -0,0 +1,12 @@
+// Command simpleCli runs the file commands of package simplecli.
+package main
+
+import (
+	"os"
+
+	"simpleCli"
+)
+
+func main() {
+	simplecli.Main(os.Args[0], os.Args[1:])
+}
@@ -0,0 +1,39 @@
+package simplecli
+
+import (
+	"io/fs"
+	"os"
+)
+
+// ANSI colors of the names, like ls --color does.
+const (
+	colorReset = "\033[0m"
+	colorDir   = "\033[1;34m"
+	colorExec  = "\033[1;32m"
+	colorLink  = "\033[1;36m"
+)
+
+// colorEnabled tells if stdout is a terminal, unless colors were turned off with
+// --no-color or the NO_COLOR environment variable.
+func colorEnabled(noColor bool) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return stdoutIsTerminal
+}
+
+// colorize colors the name by the type of the entry: directories, symlinks and executables.
+func colorize(name string, mode fs.FileMode, enabled bool) string {
+	if !enabled {
+		return name
+	}
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		return colorLink + name + colorReset
+	case mode.IsDir():
+		return colorDir + name + colorReset
+	case mode.IsRegular() && mode&0o111 != 0:
+		return colorExec + name + colorReset
+	}
+	return name
+}
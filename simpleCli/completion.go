@@ -0,0 +1,184 @@
+package simplecli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"clikit/clierr"
+)
+
+// globalFlags are the options given before the command.
+var globalFlags = []completionFlag{
+	{"config", "Read the default values of the flags from this file", false},
+	{"no-pager", "Don't send long outputs to the pager", true},
+	{"interactive", "Run several commands from a prompt", true},
+	{"help", "Print the commands", true},
+}
+
+// completionFlag is a flag offered by the completion scripts.
+type completionFlag struct {
+	name   string
+	usage  string
+	isBool bool // whether the flag takes no value
+}
+
+// option writes the flag like it's typed, -r or --recursive.
+func (f completionFlag) option() string {
+	if len(f.name) == 1 {
+		return "-" + f.name
+	}
+	return "--" + f.name
+}
+
+// runCompletion implements "simpleCli completion bash|zsh|fish".
+func runCompletion(args []string) error {
+	flags := newFlagSet("completion", "completion bash|zsh|fish")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return clierr.Errorf(clierr.Usage, "completion needs the shell: bash, zsh or fish")
+	}
+	name := filepath.Base(os.Args[0])
+	switch positional[0] {
+	case "bash":
+		return bashCompletion(os.Stdout, name)
+	case "zsh":
+		return zshCompletion(os.Stdout, name)
+	case "fish":
+		return fishCompletion(os.Stdout, name)
+	}
+	return clierr.Errorf(clierr.Usage, "completion has no script for %s, only for bash, zsh and fish", positional[0])
+}
+
+// commandFlags returns the flags of a command, collected by running it with flagsHook set.
+func commandFlags(cmd command) []completionFlag {
+	var collected []completionFlag
+	flagsHook = func(flags *flag.FlagSet) {
+		flags.VisitAll(func(f *flag.Flag) {
+			boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+			collected = append(collected, completionFlag{f.Name, f.Usage, ok && boolFlag.IsBoolFlag()})
+		})
+	}
+	defer func() { flagsHook = nil }()
+	cmd.run(nil)
+	return collected
+}
+
+func options(flags []completionFlag) string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = f.option()
+	}
+	return strings.Join(names, " ")
+}
+
+func bashCompletion(w io.Writer, name string) error {
+	fn := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(name)
+	var names []string
+	var cases strings.Builder
+	for _, cmd := range commands() {
+		names = append(names, cmd.name)
+		fmt.Fprintf(&cases, "        %s) flags=%q ;;\n", cmd.name, options(commandFlags(cmd)))
+	}
+	_, err := fmt.Fprintf(w, `# bash completion for %[1]s, load it with: source <(%[1]s completion bash)
+%[2]s() {
+    local cur=${COMP_WORDS[COMP_CWORD]} cmd= flags i
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case ${COMP_WORDS[i]} in
+            --config) ((i++)) ;;
+            -*) ;;
+            *) cmd=${COMP_WORDS[i]}; break ;;
+        esac
+    done
+    if [[ -z $cmd ]]; then
+        if [[ $cur == -* ]]; then
+            COMPREPLY=($(compgen -W %[3]q -- "$cur"))
+        else
+            COMPREPLY=($(compgen -W %[4]q -- "$cur"))
+        fi
+        return
+    fi
+    case $cmd in
+%[5]s    esac
+    if [[ $cur == -* ]]; then
+        COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+    else
+        COMPREPLY=($(compgen -f -- "$cur"))
+    fi
+}
+complete -o filenames -F %[2]s %[1]s
+`, name, fn, options(globalFlags), strings.Join(names, " "), cases.String())
+	return err
+}
+
+// zshQuote escapes a description for the specs of _arguments and _describe.
+var zshQuote = strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`)
+
+func zshCompletion(w io.Writer, name string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %[1]s\n# zsh completion for %[1]s, load it with: source <(%[1]s completion zsh)\n", name)
+	fmt.Fprintf(&b, "_%s() {\n    local -a commands\n    commands=(\n", name)
+	for _, cmd := range commands() {
+		fmt.Fprintf(&b, "        '%s:%s'\n", cmd.name, zshQuote.Replace(cmd.summary))
+	}
+	b.WriteString("    )\n    _arguments -C \\\n")
+	for _, f := range globalFlags {
+		b.WriteString("        " + zshSpec(f) + " \\\n")
+	}
+	b.WriteString("        '1:command:->command' \\\n        '*::argument:->argument'\n")
+	b.WriteString("    case $state in\n        command) _describe command commands ;;\n        argument)\n            case $words[1] in\n")
+	for _, cmd := range commands() {
+		fmt.Fprintf(&b, "                %s) _arguments", cmd.name)
+		for _, f := range commandFlags(cmd) {
+			b.WriteString(" " + zshSpec(f))
+		}
+		b.WriteString(" '*:file:_files' ;;\n")
+	}
+	b.WriteString("            esac ;;\n    esac\n}\n")
+	fmt.Fprintf(&b, "compdef _%[1]s %[1]s\n", name)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func zshSpec(f completionFlag) string {
+	if f.isBool {
+		return fmt.Sprintf("'%s[%s]'", f.option(), zshQuote.Replace(f.usage))
+	}
+	return fmt.Sprintf("'%s=[%s]:value:_files'", f.option(), zshQuote.Replace(f.usage))
+}
+
+func fishCompletion(w io.Writer, name string) error {
+	quote := strings.NewReplacer(`\`, `\\`, "'", `\'`)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# fish completion for %[1]s, load it with: %[1]s completion fish | source\n", name)
+	for _, f := range globalFlags {
+		fmt.Fprintf(&b, "complete -c %s -n __fish_use_subcommand %s -d '%s'\n", name, fishFlag(f), quote.Replace(f.usage))
+	}
+	for _, cmd := range commands() {
+		fmt.Fprintf(&b, "complete -c %s -n __fish_use_subcommand -f -a %s -d '%s'\n", name, cmd.name, quote.Replace(cmd.summary))
+	}
+	for _, cmd := range commands() {
+		for _, f := range commandFlags(cmd) {
+			fmt.Fprintf(&b, "complete -c %s -n '__fish_seen_subcommand_from %s' %s -d '%s'\n", name, cmd.name, fishFlag(f), quote.Replace(f.usage))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func fishFlag(f completionFlag) string {
+	option := "-l " + f.name
+	if len(f.name) == 1 {
+		option = "-s " + f.name
+	}
+	if !f.isBool {
+		option += " -r"
+	}
+	return option
+}
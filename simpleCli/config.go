@@ -0,0 +1,128 @@
+package simplecli
+
+import (
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"clikit/clierr"
+	"clikit/config"
+)
+
+// configPath is the file given with --config, ~/.goclirc is read when it's empty.
+var configPath string
+
+// loadedConfig caches the config, read once even when several commands run.
+var loadedConfig *config.Config
+
+// loadConfig reads the config file, a missing ~/.goclirc being the same as an empty one.
+// The SIMPLECLI_* environment variables, like SIMPLECLI_SORT, win over it.
+func loadConfig() (*config.Config, error) {
+	if loadedConfig != nil {
+		return loadedConfig, nil
+	}
+	path, optional := configPath, false
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return &config.Config{EnvPrefix: "SIMPLECLI"}, nil
+		}
+		path, optional = filepath.Join(home, ".goclirc"), true
+	}
+	file, err := config.ReadFile(path, optional)
+	if err != nil {
+		return nil, err
+	}
+	if file.Found {
+		slog.Info("read the config file", "path", path)
+	}
+	loadedConfig = &config.Config{File: file, EnvPrefix: "SIMPLECLI"}
+	return loadedConfig, nil
+}
+
+// applyConfig sets the flags of the command to the values of the environment and of the
+// config, before the command line is parsed so its flags win. The keys are the names of
+// the flags, and color = false is the same as no-color = true. Keys that aren't flags
+// of the command are left for the other commands.
+func applyConfig(flags *flag.FlagSet) ([]config.Setting, error) {
+	c, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	// color is applied first, so no-color is shown with the value it gives
+	var color []config.Setting
+	if noColor := flags.Lookup("no-color"); noColor != nil {
+		color, err = c.Apply(flags.Name(), []config.Flag{{Name: "color", Value: "true", Set: func(value string) error {
+			color, err := strconv.ParseBool(value)
+			if err != nil {
+				return errors.New("has to be true or false")
+			}
+			return noColor.Value.Set(strconv.FormatBool(!color))
+		}}})
+		if err != nil {
+			return nil, err
+		}
+	}
+	settings, err := c.Apply(flags.Name(), config.Flags(flags))
+	if err != nil {
+		return nil, err
+	}
+	return append(settings, color...), nil
+}
+
+// runConfig implements "simpleCli config show", printing the settings of the commands
+// that aren't their default, or all of them with --all, and where they come from.
+func runConfig(args []string) error {
+	flags := newFlagSet("config", "config show [options] [command...]")
+	all := flags.Bool("all", false, "Print the settings left to their default too")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 || positional[0] != "show" {
+		return clierr.Errorf(clierr.Usage, "config needs the show command, like config show list")
+	}
+	var cmds []command
+	for _, name := range positional[1:] {
+		cmd, ok := findCommand(name)
+		if !ok {
+			return unknownCommand(name)
+		}
+		cmds = append(cmds, cmd)
+	}
+	if len(cmds) == 0 {
+		cmds = commands()
+	}
+
+	var shown []config.Setting
+	for _, cmd := range cmds {
+		settings, err := applyConfig(commandFlagSet(cmd))
+		if err != nil {
+			return err
+		}
+		for _, s := range settings {
+			if *all || s.Source != config.SourceDefault {
+				s.Name = cmd.name + "." + s.Name
+				shown = append(shown, s)
+			}
+		}
+	}
+	return config.Print(os.Stdout, shown)
+}
+
+// commandFlagSet returns the flags of a command, without running it.
+func commandFlagSet(cmd command) *flag.FlagSet {
+	var flags *flag.FlagSet
+	flagsHook = func(fs *flag.FlagSet) {
+		flags = fs
+	}
+	defer func() { flagsHook = nil }()
+	cmd.run(nil)
+	if flags == nil {
+		return flag.NewFlagSet(cmd.name, flagErrorHandling)
+	}
+	return flags
+}
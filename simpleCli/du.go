@@ -0,0 +1,139 @@
+package simplecli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"sort"
+
+	"clikit/clierr"
+)
+
+// duEntry is the size of a directory, or of a file for --top.
+type duEntry struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	IsDir bool   `json:"isDir"`
+	depth int    // level under the listed directory, 0 for itself
+}
+
+// runDu implements "simpleCli du [options] [directory]".
+func runDu(args []string) error {
+	flags := newFlagSet("du", "du [options] [directory]")
+	human := flags.Bool("human", false, "Print the sizes like 1.5K or 12M")
+	top := flags.Int("top", 0, "Only print the N largest entries of the directory, files and subdirectories")
+	maxDepth := flags.Int("max-depth", 0, "Only print the directories up to this level, their size still counts everything (0 for no limit)")
+	var excludes patternList
+	flags.Var(&excludes, "exclude", "Leave out the entries matching these comma separated globs, and their content (can be repeated)")
+	var walkers int
+	addWalkersFlag(flags, &walkers)
+	asJSON := flags.Bool("json", false, "Print the sizes as a JSON array of path, size and isDir")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	root, err := rootArg("du", positional)
+	if err != nil {
+		return err
+	}
+	if *top < 0 || *maxDepth < 0 {
+		return clierr.Errorf(clierr.Usage, "top and max-depth can't be negative")
+	}
+	// hidden entries take space too
+	opts := listOptions{all: true, excludes: excludes}
+
+	usage, err := diskUsage(root, ".", 0, opts, newWalkPool(walkers))
+	if err != nil {
+		return err
+	}
+	var entries []duEntry
+	var children []duEntry
+	for _, e := range usage {
+		if e.depth == 1 {
+			children = append(children, e)
+		}
+		if e.IsDir && (*maxDepth == 0 || e.depth <= *maxDepth) {
+			entries = append(entries, e)
+		}
+	}
+	if *top > 0 {
+		sort.SliceStable(children, func(i, j int) bool { return children[i].Size > children[j].Size })
+		entries = children[:min(*top, len(children))]
+	}
+
+	if *asJSON {
+		if entries == nil {
+			entries = []duEntry{}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Println(string(data))
+		return err
+	}
+	for _, e := range entries {
+		size := fmt.Sprint(e.Size)
+		if *human {
+			size = humanSize(e.Size)
+		}
+		fmt.Printf("%-8s %s\n", size, e.Path)
+	}
+	return nil
+}
+
+// diskUsage returns the sizes of dir, whose path relative to the listed directory is
+// rel, and of everything under it. The directories come after their content like with
+// du, so dir is the last one. The subdirectories are walked by the pool. Like with walk,
+// the entries that can't be read are logged and left out of the sizes.
+func diskUsage(dir, rel string, depth int, opts listOptions, pool *walkPool) ([]duEntry, error) {
+	entries, err := readDir(dir)
+	if err != nil && depth == 0 {
+		return nil, err
+	}
+	if err != nil {
+		slog.Warn("can't read the directory", "path", dir, "err", err)
+	}
+	// every child keeps its sizes apart until they're merged in order
+	children := make([][]duEntry, len(entries))
+	var tasks []func()
+	for i, entry := range entries {
+		childRel := filepath.Join(rel, entry.Name())
+		if opts.skipped(childRel, entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			c := &children[i]
+			tasks = append(tasks, func() {
+				// only the listed directory fails the walk
+				*c, _ = diskUsage(path, childRel, depth+1, opts, pool)
+			})
+			continue
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // removed in the meantime
+		}
+		if err != nil {
+			slog.Warn("can't read the entry", "path", path, "err", err)
+			continue
+		}
+		children[i] = []duEntry{{path, info.Size(), false, depth + 1}}
+	}
+	pool.run(tasks)
+
+	var usage []duEntry
+	var total int64
+	for _, c := range children {
+		if len(c) > 0 {
+			// the child itself is the last one
+			total += c[len(c)-1].Size
+		}
+		usage = append(usage, c...)
+	}
+	return append(usage, duEntry{dir, total, true, depth}), nil
+}
@@ -0,0 +1,333 @@
+package simplecli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"clikit/clierr"
+
+	"golang.org/x/term"
+)
+
+// progressThreshold is the size from which a copy prints its progress on a terminal.
+var progressThreshold int64 = 16 << 20
+
+// fileOp holds the flags of cp, mv and rm.
+type fileOp struct {
+	recursive bool
+	force     bool
+	dryRun    bool
+}
+
+func addFileOpFlags(flags *flag.FlagSet, recursive bool) *fileOp {
+	op := &fileOp{}
+	if recursive {
+		flags.BoolVar(&op.recursive, "recursive", false, "Work on directories and their content")
+		flags.BoolVar(&op.recursive, "r", false, "Work on directories and their content (shorthand)")
+	}
+	flags.BoolVar(&op.force, "force", false, "Overwrite the existing files, or for rm ignore the missing ones")
+	flags.BoolVar(&op.force, "f", false, "Overwrite the existing files, or for rm ignore the missing ones (shorthand)")
+	flags.BoolVar(&op.dryRun, "dry-run", false, "Print what would be done without doing it")
+	return op
+}
+
+// runCp implements "simpleCli cp [options] <source>... <destination>".
+func runCp(args []string) error {
+	flags := newFlagSet("cp", "cp [options] <source>... <destination>")
+	op := addFileOpFlags(flags, true)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	return transfer("cp", positional, op.copyPath)
+}
+
+// runMv implements "simpleCli mv [options] <source>... <destination>".
+func runMv(args []string) error {
+	flags := newFlagSet("mv", "mv [options] <source>... <destination>")
+	op := addFileOpFlags(flags, false)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	return transfer("mv", positional, op.movePath)
+}
+
+// runRm implements "simpleCli rm [options] <path>...".
+func runRm(args []string) error {
+	flags := newFlagSet("rm", "rm [options] <path>...")
+	op := addFileOpFlags(flags, true)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		return clierr.Errorf(clierr.Usage, "rm needs the paths to remove")
+	}
+	for _, path := range positional {
+		if err := op.removePath(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transfer copies or moves the sources to the destination, which has to be a
+// directory when there are several sources. A source goes into the destination
+// when it's a directory, like with cp and mv.
+func transfer(name string, positional []string, do func(src, dst string) error) error {
+	if len(positional) < 2 {
+		return clierr.Errorf(clierr.Usage, "%s needs a source and a destination", name)
+	}
+	sources, dest := positional[:len(positional)-1], positional[len(positional)-1]
+	info, err := os.Stat(dest)
+	destDir := err == nil && info.IsDir()
+	if len(sources) > 1 && !destDir {
+		return clierr.Errorf(clierr.Usage, "%s is not a directory", dest)
+	}
+	for _, src := range sources {
+		dst := dest
+		if destDir {
+			dst = filepath.Join(dest, filepath.Base(src))
+		}
+		if err := do(src, dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyPath copies a file, a symlink, or a directory with its content when recursive.
+// The modes and modification times are kept.
+func (op *fileOp) copyPath(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.IsDir():
+		if !op.recursive {
+			return clierr.Errorf(clierr.Usage, "%s is a directory, use --recursive", src)
+		}
+		if within(dst, src) {
+			return clierr.Errorf(clierr.Usage, "can't copy %s into itself", src)
+		}
+		return op.copyDir(src, dst, info)
+	case info.Mode()&fs.ModeSymlink != 0:
+		if err := sameFile(src, dst, os.Lstat); err != nil {
+			return err
+		}
+		if err := op.replaceable(dst); err != nil {
+			return err
+		}
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		if op.dryRun {
+			fmt.Printf("link %s -> %s\n", dst, target)
+			return nil
+		}
+		if err := os.Remove(dst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return os.Symlink(target, dst)
+	}
+	return op.copyFile(src, dst, info)
+}
+
+func (op *fileOp) copyDir(src, dst string, info fs.FileInfo) error {
+	if op.dryRun {
+		fmt.Printf("mkdir %s\n", dst)
+	} else if err := os.MkdirAll(dst, info.Mode().Perm()|0o700); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := op.copyPath(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+			return err
+		}
+	}
+	if op.dryRun {
+		return nil
+	}
+	// the content was written with the owner's permissions, the directory gets its own now
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, time.Now(), info.ModTime())
+}
+
+func (op *fileOp) copyFile(src, dst string, info fs.FileInfo) error {
+	// the destination is followed when it's a symlink, like the copy will
+	if err := sameFile(src, dst, os.Stat); err != nil {
+		return err
+	}
+	if err := op.replaceable(dst); err != nil {
+		return err
+	}
+	if op.dryRun {
+		fmt.Printf("copy %s -> %s\n", src, dst)
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = out
+	if info.Size() >= progressThreshold && term.IsTerminal(int(os.Stderr.Fd())) {
+		p := &progress{name: dst, total: info.Size()}
+		defer p.finish()
+		w = io.MultiWriter(out, p)
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, time.Now(), info.ModTime())
+}
+
+// replaceable fails when the destination exists and --force wasn't given.
+func (op *fileOp) replaceable(dst string) error {
+	info, err := os.Lstat(dst)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return clierr.Errorf(clierr.Usage, "%s is a directory, it can't be overwritten", dst)
+	}
+	if !op.force {
+		return clierr.Errorf(clierr.Usage, "%s already exists, use --force to overwrite it", dst)
+	}
+	return nil
+}
+
+// sameFile fails when src and dst are the same file, like a path and a hard link to
+// it, as the destination would be truncated or removed with the source.
+func sameFile(src, dst string, stat func(string) (fs.FileInfo, error)) error {
+	srcInfo, err := stat(src)
+	if err != nil {
+		return nil
+	}
+	if dstInfo, err := stat(dst); err == nil && os.SameFile(srcInfo, dstInfo) {
+		return clierr.Errorf(clierr.Usage, "'%s' and '%s' are the same file", src, dst)
+	}
+	return nil
+}
+
+// movePath renames the source, or copies then removes it when it's moved to
+// another file system.
+func (op *fileOp) movePath(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() && within(dst, src) {
+		return clierr.Errorf(clierr.Usage, "can't move %s into itself", src)
+	}
+	if err := sameFile(src, dst, os.Lstat); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		if err := op.replaceable(dst); err != nil {
+			return err
+		}
+	}
+	if op.dryRun {
+		fmt.Printf("move %s -> %s\n", src, dst)
+		return nil
+	}
+	err = os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	copyOp := &fileOp{recursive: true, force: op.force}
+	if err := copyOp.copyPath(src, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// removePath removes a file, or a directory with its content when recursive.
+func (op *fileOp) removePath(path string) error {
+	if clean := filepath.Clean(path); clean == "." || clean == ".." || clean == string(filepath.Separator) {
+		return clierr.Errorf(clierr.Usage, "refusing to remove %s", path)
+	}
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) && op.force {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() && !op.recursive {
+		return clierr.Errorf(clierr.Usage, "%s is a directory, use --recursive", path)
+	}
+	if op.dryRun {
+		fmt.Printf("remove %s\n", path)
+		return nil
+	}
+	if info.IsDir() {
+		return os.RemoveAll(path)
+	}
+	return os.Remove(path)
+}
+
+// within tells if path is dir or one of its descendants.
+func within(path, dir string) bool {
+	absPath, err1 := filepath.Abs(path)
+	absDir, err2 := filepath.Abs(dir)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// progress prints how much of a large copy is done on stderr, a few times a second.
+type progress struct {
+	name        string
+	total, done int64
+	printed     time.Time
+}
+
+func (p *progress) Write(b []byte) (int, error) {
+	p.done += int64(len(b))
+	if time.Since(p.printed) >= 200*time.Millisecond {
+		p.print()
+	}
+	return len(b), nil
+}
+
+func (p *progress) print() {
+	p.printed = time.Now()
+	fmt.Fprintf(os.Stderr, "\rcopying %s: %3d%% (%s/%s)", p.name, p.done*100/p.total, humanSize(p.done), humanSize(p.total))
+}
+
+func (p *progress) finish() {
+	p.print()
+	fmt.Fprintln(os.Stderr)
+}
@@ -0,0 +1,96 @@
+package simplecli
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"clikit/config"
+)
+
+// fileOpsDir creates a directory with a file "a" holding "data", a hard link "link" to
+// it, a symlink "sym" to it, a file "b" and a directory "dir" with a file "c".
+func fileOpsDir(t *testing.T) {
+	t.Helper()
+	loadedConfig = &config.Config{EnvPrefix: "SIMPLECLI"}
+	flagErrorHandling = flag.ContinueOnError
+	t.Cleanup(func() { loadedConfig, flagErrorHandling = nil, flag.ExitOnError })
+
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	for name, content := range map[string]string{"a": "data", "b": "other", "dir/c": "nested"} {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Link("a", "link"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a", "sym"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_fileOps(t *testing.T) {
+	run := map[string]func([]string) error{"cp": runCp, "mv": runMv, "rm": runRm}
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+		want    map[string]string // The content of the files after, "" when removed
+	}{
+		{"cp same file", []string{"cp", "-f", "a", "a"}, "'a' and 'a' are the same file", map[string]string{"a": "data"}},
+		{"cp to a hard link", []string{"cp", "-f", "a", "link"}, "are the same file", map[string]string{"a": "data", "link": "data"}},
+		{"cp to a symlink of the source", []string{"cp", "-f", "a", "sym"}, "are the same file", map[string]string{"a": "data"}},
+		{"cp symlink onto itself", []string{"cp", "-f", "sym", "sym"}, "are the same file", map[string]string{"sym": "data"}},
+		{"cp existing", []string{"cp", "a", "b"}, "use --force", map[string]string{"b": "other"}},
+		{"cp -f existing", []string{"cp", "-f", "a", "b"}, "", map[string]string{"a": "data", "b": "data"}},
+		{"cp into a directory", []string{"cp", "a", "b", "dir"}, "", map[string]string{"dir/a": "data", "dir/b": "other"}},
+		{"cp directory", []string{"cp", "dir", "copy"}, "use --recursive", map[string]string{"copy/c": ""}},
+		{"cp -r directory", []string{"cp", "-r", "dir", "copy"}, "", map[string]string{"copy/c": "nested", "dir/c": "nested"}},
+		{"cp -r directory into itself", []string{"cp", "-r", "dir", "dir/sub"}, "into itself", map[string]string{"dir/sub/c": ""}},
+		{"mv same file", []string{"mv", "-f", "a", "./a"}, "are the same file", map[string]string{"a": "data"}},
+		{"mv to a hard link", []string{"mv", "-f", "a", "link"}, "are the same file", map[string]string{"a": "data", "link": "data"}},
+		{"mv onto a symlink of the source", []string{"mv", "-f", "a", "sym"}, "", map[string]string{"a": "", "sym": "data"}},
+		{"mv existing", []string{"mv", "a", "b"}, "use --force", map[string]string{"a": "data", "b": "other"}},
+		{"mv -f existing", []string{"mv", "-f", "a", "b"}, "", map[string]string{"a": "", "b": "data"}},
+		{"mv directory", []string{"mv", "dir", "moved"}, "", map[string]string{"dir/c": "", "moved/c": "nested"}},
+		{"mv directory into itself", []string{"mv", "dir", "dir/sub"}, "into itself", map[string]string{"dir/c": "nested"}},
+		{"rm hard link", []string{"rm", "link"}, "", map[string]string{"a": "data", "link": ""}},
+		{"rm missing", []string{"rm", "missing"}, "no such file", nil},
+		{"rm -f missing", []string{"rm", "-f", "missing", "b"}, "", map[string]string{"b": ""}},
+		{"rm directory", []string{"rm", "dir"}, "use --recursive", map[string]string{"dir/c": "nested"}},
+		{"rm -r directory", []string{"rm", "-r", "dir"}, "", map[string]string{"dir/c": ""}},
+		{"rm current directory", []string{"rm", "-r", "-f", "."}, "refusing to remove", map[string]string{"a": "data"}},
+		{"rm --dry-run", []string{"rm", "--dry-run", "a"}, "", map[string]string{"a": "data"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileOpsDir(t)
+			err := run[tt.args[0]](tt.args[1:])
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("%v: error = %v, want %q", tt.args, err, tt.wantErr)
+			}
+			for name, want := range tt.want {
+				got, err := os.ReadFile(name)
+				if want == "" && !os.IsNotExist(err) {
+					t.Errorf("%v: %s left behind", tt.args, name)
+				} else if want != "" && string(got) != want {
+					t.Errorf("%v: %s = %q, %v, want %q", tt.args, name, got, err, want)
+				}
+			}
+		})
+	}
+}
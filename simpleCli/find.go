@@ -0,0 +1,240 @@
+package simplecli
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"clikit/clierr"
+)
+
+// findPredicate tells if an entry is one of the results of find.
+type findPredicate func(path string, e entry) bool
+
+// runFind implements "simpleCli find [options] [directory]", printing the entries
+// matching every predicate, or running --exec on them.
+func runFind(args []string) error {
+	flags := newFlagSet("find", "find [options] [directory]")
+	name := flags.String("name", "", "Name of the entries, as a glob like '*.go'")
+	pattern := flags.String("regex", "", "Regular expression the path of the entries has to match")
+	size := flags.String("size", "", "Size of the files: +10M for more than 10M, -1K for less than 1K, 100 for exactly 100 bytes (units K, M, G)")
+	mtime := flags.String("mtime", "", "Modification time: -7d for less than 7 days ago, +2h for more than 2 hours ago, 3d for between 3 and 4 days ago (units s, m, h, d, w)")
+	fileType := flags.String("type", "", "Type of the entries: f for files, d for directories, l for symlinks")
+	execCmd := flags.String("exec", "", "Command run for every result instead of printing it, {} being replaced by its path, like 'gzip {}'")
+	maxDepth := flags.Int("max-depth", 0, "Number of levels searched (0 for no limit)")
+	var excludes patternList
+	flags.Var(&excludes, "exclude", "Leave out the entries matching these comma separated globs, and their content (can be repeated)")
+	followLinks := flags.Bool("follow-symlinks", false, "Walk the symlinks to directories like directories, the ones looping back are skipped")
+	var walkers int
+	addWalkersFlag(flags, &walkers)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	root, err := rootArg("find", positional)
+	if err != nil {
+		return err
+	}
+	if *maxDepth < 0 {
+		return clierr.Errorf(clierr.Usage, "max-depth can't be negative")
+	}
+	predicates, err := findPredicates(*name, *pattern, *size, *mtime, *fileType, time.Now())
+	if err != nil {
+		return err
+	}
+	var command []string
+	if *execCmd != "" {
+		if command, err = splitWords(*execCmd); err != nil {
+			return fmt.Errorf("exec: %w", err)
+		}
+	}
+
+	opts := listOptions{recursive: true, all: true, maxDepth: *maxDepth, excludes: excludes, followLinks: *followLinks, walkers: walkers}
+	entries, err := listDir(root, opts)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, e := range entries {
+		path := filepath.Join(root, e.path)
+		if !matchesAll(predicates, path, e) {
+			continue
+		}
+		if command == nil {
+			fmt.Println(path)
+			continue
+		}
+		if err := runExec(command, path); err != nil {
+			slog.Error("running the command", "path", path, "err", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("exec failed for %d entries", failed)
+	}
+	return nil
+}
+
+// findPredicates returns the predicates of the flags that were given, all of them
+// having to match.
+func findPredicates(name, pattern, size, mtime, fileType string, now time.Time) ([]findPredicate, error) {
+	var predicates []findPredicate
+	if name != "" {
+		if _, err := filepath.Match(name, ""); err != nil {
+			return nil, fmt.Errorf("name: %w", err)
+		}
+		predicates = append(predicates, func(path string, e entry) bool {
+			ok, _ := filepath.Match(name, filepath.Base(path))
+			return ok
+		})
+	}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("regex: %w", err)
+		}
+		predicates = append(predicates, func(path string, e entry) bool {
+			return re.MatchString(filepath.ToSlash(path))
+		})
+	}
+	if size != "" {
+		sign, n, unit, err := parseSize(size)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, func(path string, e entry) bool {
+			if e.info.IsDir() {
+				return false
+			}
+			// like find, the size is rounded up to the unit
+			units := (e.info.Size() + unit - 1) / unit
+			return compareSign(sign, units, n)
+		})
+	}
+	if mtime != "" {
+		sign, age, unit, err := parseAge(mtime)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, func(path string, e entry) bool {
+			elapsed := now.Sub(e.info.ModTime())
+			switch sign {
+			case '+':
+				return elapsed > age
+			case '-':
+				return elapsed < age
+			}
+			// like find, the age is rounded down to the unit
+			return elapsed >= age && elapsed < age+unit
+		})
+	}
+	switch fileType {
+	case "":
+	case "f", "d", "l":
+		predicates = append(predicates, func(path string, e entry) bool {
+			switch fileType {
+			case "f":
+				return e.info.Mode().IsRegular()
+			case "d":
+				return e.info.IsDir()
+			}
+			return e.target != "" || e.info.Mode()&fs.ModeSymlink != 0
+		})
+	default:
+		return nil, clierr.Errorf(clierr.Usage, "type has to be either f, d or l")
+	}
+	return predicates, nil
+}
+
+func matchesAll(predicates []findPredicate, path string, e entry) bool {
+	for _, predicate := range predicates {
+		if !predicate(path, e) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSize parses a --size like +10M into its sign, its number and the size of its unit.
+func parseSize(value string) (byte, int64, int64, error) {
+	sign, rest := splitSign(value)
+	unit := int64(1)
+	if rest != "" {
+		switch rest[len(rest)-1] {
+		case 'c':
+			rest = rest[:len(rest)-1]
+		case 'k', 'K':
+			unit, rest = 1<<10, rest[:len(rest)-1]
+		case 'M':
+			unit, rest = 1<<20, rest[:len(rest)-1]
+		case 'G':
+			unit, rest = 1<<30, rest[:len(rest)-1]
+		}
+	}
+	n, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil || n < 0 {
+		return 0, 0, 0, clierr.Errorf(clierr.Usage, "size %q has to be a number of bytes, K, M or G like +10M", value)
+	}
+	return sign, n, unit, nil
+}
+
+// parseAge parses a --mtime like -7d into its sign, its duration and its unit, days by
+// default.
+func parseAge(value string) (byte, time.Duration, time.Duration, error) {
+	sign, rest := splitSign(value)
+	unit := 24 * time.Hour
+	units := map[byte]time.Duration{'s': time.Second, 'm': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if rest != "" {
+		if u, ok := units[rest[len(rest)-1]]; ok {
+			unit, rest = u, rest[:len(rest)-1]
+		}
+	}
+	n, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil || n < 0 {
+		return 0, 0, 0, clierr.Errorf(clierr.Usage, "mtime %q has to be a number of s, m, h, d or w like -7d", value)
+	}
+	return sign, time.Duration(n) * unit, unit, nil
+}
+
+func splitSign(value string) (byte, string) {
+	if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+		return value[0], value[1:]
+	}
+	return 0, value
+}
+
+func compareSign(sign byte, got, want int64) bool {
+	switch sign {
+	case '+':
+		return got > want
+	case '-':
+		return got < want
+	}
+	return got == want
+}
+
+// runExec runs the command with {} replaced by the path, or the path added at the end
+// when the command has no {}.
+func runExec(command []string, path string) error {
+	args := make([]string, 0, len(command)+1)
+	replaced := false
+	for _, arg := range command {
+		if strings.Contains(arg, "{}") {
+			arg, replaced = strings.ReplaceAll(arg, "{}", path), true
+		}
+		args = append(args, arg)
+	}
+	if !replaced {
+		args = append(args, path)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, realStdout, os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,146 @@
+package simplecli
+
+import (
+	"io/fs"
+	"testing"
+	"time"
+)
+
+func Test_parseSize(t *testing.T) {
+	tests := []struct {
+		value    string
+		wantSign byte
+		wantN    int64
+		wantUnit int64
+		wantErr  bool
+	}{
+		{"100", 0, 100, 1, false},
+		{"100c", 0, 100, 1, false},
+		{"+10M", '+', 10, 1 << 20, false},
+		{"-1K", '-', 1, 1 << 10, false},
+		{"2k", 0, 2, 1 << 10, false},
+		{"+3G", '+', 3, 1 << 30, false},
+		{"", 0, 0, 0, true},
+		{"M", 0, 0, 0, true},
+		{"10T", 0, 0, 0, true},
+		{"--1", 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		sign, n, unit, err := parseSize(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSize(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if sign != tt.wantSign || n != tt.wantN || unit != tt.wantUnit {
+			t.Errorf("parseSize(%q) = %q, %d, %d, want %q, %d, %d", tt.value, sign, n, unit, tt.wantSign, tt.wantN, tt.wantUnit)
+		}
+	}
+}
+
+func Test_parseAge(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		value    string
+		wantSign byte
+		wantAge  time.Duration
+		wantUnit time.Duration
+		wantErr  bool
+	}{
+		{"7", 0, 7 * day, day, false},
+		{"-7d", '-', 7 * day, day, false},
+		{"+2h", '+', 2 * time.Hour, time.Hour, false},
+		{"30m", 0, 30 * time.Minute, time.Minute, false},
+		{"+45s", '+', 45 * time.Second, time.Second, false},
+		{"1w", 0, 7 * day, 7 * day, false},
+		{"", 0, 0, 0, true},
+		{"d", 0, 0, 0, true},
+		{"2y", 0, 0, 0, true},
+		{"+-2", 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		sign, age, unit, err := parseAge(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAge(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if sign != tt.wantSign || age != tt.wantAge || unit != tt.wantUnit {
+			t.Errorf("parseAge(%q) = %q, %v, %v, want %q, %v, %v", tt.value, sign, age, unit, tt.wantSign, tt.wantAge, tt.wantUnit)
+		}
+	}
+}
+
+// fakeInfo is the fs.FileInfo of an entry that doesn't exist on disk.
+type fakeInfo struct {
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i fakeInfo) Name() string       { return "" }
+func (i fakeInfo) Size() int64        { return i.size }
+func (i fakeInfo) Mode() fs.FileMode  { return i.mode }
+func (i fakeInfo) ModTime() time.Time { return i.modTime }
+func (i fakeInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i fakeInfo) Sys() any           { return nil }
+
+func Test_findPredicates(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	file := func(size int64, age time.Duration) entry {
+		return entry{info: fakeInfo{size: size, modTime: now.Add(-age)}}
+	}
+	dir := entry{info: fakeInfo{mode: fs.ModeDir, modTime: now}}
+	link := entry{info: fakeInfo{mode: fs.ModeSymlink, modTime: now}}
+	type flags struct{ name, pattern, size, mtime, fileType string }
+	tests := []struct {
+		name  string
+		flags flags
+		path  string
+		e     entry
+		want  bool
+	}{
+		{"No predicates", flags{}, "a/b.go", file(1, 0), true},
+		{"Name matching the base", flags{name: "*.go"}, "a/b.go", file(1, 0), true},
+		{"Name not matching the directory", flags{name: "a*"}, "a/b.go", file(1, 0), false},
+		{"Regex on the path", flags{pattern: `^a/.*\.go$`}, "a/b.go", file(1, 0), true},
+		{"Regex not matching", flags{pattern: `^b`}, "a/b.go", file(1, 0), false},
+		{"Size exactly", flags{size: "100"}, "f", file(100, 0), true},
+		{"Size rounded up to the unit", flags{size: "1K"}, "f", file(1000, 0), true},
+		{"Size above the unit", flags{size: "1K"}, "f", file(1025, 0), false},
+		{"Size more than", flags{size: "+1M"}, "f", file(3<<20, 0), true},
+		{"Size less than", flags{size: "-1K"}, "f", file(1, 0), false},
+		{"Size empty file less than", flags{size: "-1K"}, "f", file(0, 0), true},
+		{"Size of a directory", flags{size: "-1K"}, "d", dir, false},
+		{"Mtime within the day", flags{mtime: "2"}, "f", file(1, 2*24*time.Hour+23*time.Hour), true},
+		{"Mtime the next day", flags{mtime: "2"}, "f", file(1, 3*24*time.Hour), false},
+		{"Mtime the day before", flags{mtime: "2"}, "f", file(1, 2*24*time.Hour-time.Minute), false},
+		{"Mtime within the hour", flags{mtime: "10h"}, "f", file(1, 10*time.Hour+59*time.Minute), true},
+		{"Mtime 10 days, not 20", flags{mtime: "10"}, "f", file(1, 15*24*time.Hour), false},
+		{"Mtime less than", flags{mtime: "-7d"}, "f", file(1, 6*24*time.Hour), true},
+		{"Mtime more than", flags{mtime: "+2h"}, "f", file(1, 3*time.Hour), true},
+		{"Mtime not more than", flags{mtime: "+2h"}, "f", file(1, time.Hour), false},
+		{"Type file", flags{fileType: "f"}, "f", file(1, 0), true},
+		{"Type file of a directory", flags{fileType: "f"}, "d", dir, false},
+		{"Type directory", flags{fileType: "d"}, "d", dir, true},
+		{"Type symlink", flags{fileType: "l"}, "l", link, true},
+		{"Type followed symlink", flags{fileType: "l"}, "l", entry{info: dir.info, target: "d"}, true},
+		{"All of them", flags{name: "*.csv", size: "+1K", fileType: "f"}, "a.csv", file(2048, 0), true},
+		{"One of them failing", flags{name: "*.csv", size: "+1K", fileType: "f"}, "a.csv", file(10, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			predicates, err := findPredicates(tt.flags.name, tt.flags.pattern, tt.flags.size, tt.flags.mtime, tt.flags.fileType, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := matchesAll(predicates, tt.path, tt.e); got != tt.want {
+				t.Errorf("matchesAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, f := range []flags{{name: "["}, {pattern: "("}, {size: "x"}, {mtime: "x"}, {fileType: "x"}} {
+		if _, err := findPredicates(f.name, f.pattern, f.size, f.mtime, f.fileType, now); err == nil {
+			t.Errorf("findPredicates(%+v) returned no error", f)
+		}
+	}
+}
@@ -1,3 +1,16 @@
 module simpleCli
 
 go 1.21.5
+
+require (
+	clikit v0.0.0
+	github.com/fsnotify/fsnotify v1.7.0
+	golang.org/x/term v0.15.0
+)
+
+require (
+	golang.org/x/sys v0.15.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace clikit => ../clikit
@@ -0,0 +1,91 @@
+package simplecli
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"clikit/clierr"
+)
+
+// locales holds the greeting of every language, as a template given the Name.
+//
+//go:embed locales/*.tmpl
+var locales embed.FS
+
+// runGreet implements "simpleCli greet --name=NAME --lang=fr".
+func runGreet(args []string) error {
+	flags := newFlagSet("greet", "greet [options]")
+	name := flags.String("name", "Valentine", "The name of the passed in user")
+	lang := flags.String("lang", "", "Language of the greeting, like fr or pt_BR (defaults to LC_ALL, LC_MESSAGES or LANG, then English)")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return clierr.Errorf(clierr.Usage, "greet doesn't take any argument, use --name")
+	}
+	greeting, err := greet(*name, *lang)
+	if err != nil {
+		return err
+	}
+	fmt.Print(greeting)
+	return nil
+}
+
+// greet renders the greeting in the language, or in the one of the environment when
+// it's empty. Languages without a greeting fall back to English.
+func greet(name, lang string) (string, error) {
+	if lang == "" {
+		lang = envLanguage()
+	}
+	text, err := locales.ReadFile("locales/en.tmpl")
+	if err != nil {
+		return "", err
+	}
+	for _, candidate := range languageCandidates(lang) {
+		if localized, err := locales.ReadFile("locales/" + candidate + ".tmpl"); err == nil {
+			text = localized
+			break
+		}
+	}
+	tmpl, err := template.New("greeting").Parse(string(text))
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, struct{ Name string }{name}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// envLanguage is the language of the messages set in the environment, like the
+// POSIX tools read it.
+func envLanguage() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// languageCandidates turns a locale like pt_BR.UTF-8 into the names of the greetings
+// tried in order, pt_br then pt.
+func languageCandidates(lang string) []string {
+	lang, _, _ = strings.Cut(lang, ".")
+	lang, _, _ = strings.Cut(lang, "@")
+	lang = strings.ToLower(strings.ReplaceAll(lang, "-", "_"))
+	if lang == "" || lang == "c" || lang == "posix" {
+		return nil
+	}
+	candidates := []string{lang}
+	if base, _, ok := strings.Cut(lang, "_"); ok {
+		candidates = append(candidates, base)
+	}
+	return candidates
+}
@@ -0,0 +1,167 @@
+package simplecli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sync"
+
+	"clikit/clierr"
+)
+
+// colorMatch is the ANSI color of the matches, like grep --color does.
+const colorMatch = "\033[1;31m"
+
+// binarySniffLen is how much of a file is read to tell if it's binary, like git does.
+const binarySniffLen = 8000
+
+// runGrep implements "simpleCli grep [options] <pattern> [path]".
+func runGrep(args []string) error {
+	flags := newFlagSet("grep", "grep [options] <pattern> [path]")
+	filters := addFilterFlags(flags)
+	ignoreCase := flags.Bool("ignore-case", false, "Match the pattern whatever the case of the letters")
+	flags.BoolVar(ignoreCase, "i", false, "Match the pattern whatever the case of the letters (shorthand)")
+	isRegex := flags.Bool("regex", false, "Read the pattern as a regular expression instead of plain text")
+	flags.BoolVar(isRegex, "E", false, "Read the pattern as a regular expression instead of plain text (shorthand)")
+	addWalkersFlag(flags, &filters.opts.walkers)
+	workers := flags.Int("workers", runtime.NumCPU(), "Number of files searched at the same time")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		return clierr.Errorf(clierr.Usage, "grep needs a pattern")
+	}
+	root, err := rootArg("grep", positional[1:])
+	if err != nil {
+		return err
+	}
+	opts, err := filters.options()
+	if err != nil {
+		return err
+	}
+	if *workers < 1 {
+		return clierr.Errorf(clierr.Usage, "workers has to be at least 1")
+	}
+
+	pattern := positional[0]
+	if !*isRegex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if *ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("pattern: %w", err)
+	}
+	files, err := grepFiles(root, opts)
+	if err != nil {
+		return err
+	}
+	searchFiles(os.Stdout, files, re, *workers, opts.color)
+	return nil
+}
+
+// grepFiles returns the regular files under root, or root itself when it's a file.
+func grepFiles(root string, opts listOptions) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{root}, nil
+	}
+	opts.recursive = true
+	entries, err := listDir(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.info.Mode().IsRegular() {
+			files = append(files, filepath.Join(root, entry.path))
+		}
+	}
+	return files, nil
+}
+
+// searchFiles searches the files with a pool of workers, printing the matches in the
+// order of the files. Files that can't be read are reported on stderr.
+func searchFiles(w io.Writer, files []string, re *regexp.Regexp, workers int, color bool) {
+	type result struct {
+		lines []string
+		err   error
+	}
+	results := make([]chan result, len(files))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < min(workers, len(files)); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				lines, err := searchFile(files[i], re, color)
+				results[i] <- result{lines, err}
+			}
+		}()
+	}
+	go func() {
+		for i := range files {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+	}()
+
+	for _, pending := range results {
+		r := <-pending
+		for _, line := range r.lines {
+			fmt.Fprintln(w, line)
+		}
+		if r.err != nil {
+			slog.Error(r.err.Error())
+		}
+	}
+}
+
+// searchFile returns the matching lines of the file as file:line:text, nothing for
+// binary files.
+func searchFile(path string, re *regexp.Regexp, color bool) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	if head, _ := r.Peek(binarySniffLen); bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+
+	var matches []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if !re.MatchString(line) {
+			continue
+		}
+		if color {
+			line = re.ReplaceAllStringFunc(line, func(match string) string { return colorMatch + match + colorReset })
+		}
+		matches = append(matches, fmt.Sprintf("%s:%d:%s", path, lineNumber, line))
+	}
+	if err := scanner.Err(); err != nil {
+		return matches, fmt.Errorf("%s: %w", path, err)
+	}
+	return matches, nil
+}
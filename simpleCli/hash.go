@@ -0,0 +1,170 @@
+package simplecli
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"clikit/clierr"
+)
+
+// hashAlgorithms are the digests of the hash command, by name.
+var hashAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha1":   sha1.New,
+	"md5":    md5.New,
+}
+
+// runHash implements "simpleCli hash [options] <path>...", printing the digests like
+// sha256sum does so the output can be checked later with --check.
+func runHash(args []string) error {
+	flags := newFlagSet("hash", "hash [options] <path>...\n       simpleCli hash --check <manifest>")
+	algo := flags.String("algo", "sha256", "Digest computed: sha256, sha1 or md5 (guessed from the manifest with --check)")
+	check := flags.Bool("check", false, "Read the digests from the manifest files and verify them")
+	var excludes patternList
+	flags.Var(&excludes, "exclude", "Leave out the entries of the directories matching these comma separated globs (can be repeated)")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		return clierr.Errorf(clierr.Usage, "hash needs the files or directories to hash")
+	}
+	algoSet := false
+	flags.Visit(func(f *flag.Flag) { algoSet = algoSet || f.Name == "algo" })
+	if _, ok := hashAlgorithms[*algo]; !ok {
+		return clierr.Errorf(clierr.Usage, "algo has to be either sha256, sha1 or md5")
+	}
+	if *check {
+		if !algoSet {
+			*algo = ""
+		}
+		return checkManifests(positional, *algo)
+	}
+
+	for _, path := range positional {
+		files, err := hashFiles(path, listOptions{all: true, excludes: excludes})
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			digest, err := fileDigest(file, hashAlgorithms[*algo])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s  %s\n", digest, filepath.ToSlash(file))
+		}
+	}
+	return nil
+}
+
+// hashFiles returns the path when it's a file, or the regular files of the tree when
+// it's a directory.
+func hashFiles(path string, opts listOptions) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	opts.recursive = true
+	entries, err := listDir(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if e.info.Mode().IsRegular() {
+			files = append(files, filepath.Join(path, e.path))
+		}
+	}
+	return files, nil
+}
+
+func fileDigest(path string, newHash func() hash.Hash) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkManifests verifies the "digest  path" lines of the manifests, printing the
+// result of every file. The algorithm is guessed from the length of the digests
+// when it isn't given.
+func checkManifests(manifests []string, algo string) error {
+	failed := 0
+	for _, manifest := range manifests {
+		f, err := os.Open(manifest)
+		if err != nil {
+			return err
+		}
+		scanner := bufio.NewScanner(f)
+		for lineNumber := 1; scanner.Scan(); lineNumber++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			// sha256sum writes "digest  path", or "digest *path" for binary files
+			want, path, ok := strings.Cut(line, " ")
+			path = strings.TrimPrefix(strings.TrimPrefix(path, " "), "*")
+			name := algo
+			if name == "" {
+				name = algorithmOf(want)
+			}
+			newHash, known := hashAlgorithms[name]
+			if !ok || path == "" || !known {
+				f.Close()
+				return clierr.Errorf(clierr.Validation, "%s:%d: expected a digest and a path", manifest, lineNumber)
+			}
+			got, err := fileDigest(filepath.FromSlash(path), newHash)
+			switch {
+			case err != nil:
+				failed++
+				fmt.Printf("%s: FAILED (%v)\n", path, err)
+			case !strings.EqualFold(got, want):
+				failed++
+				fmt.Printf("%s: FAILED\n", path)
+			default:
+				fmt.Printf("%s: OK\n", path)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return clierr.Errorf(clierr.Validation, "%d files don't match their digest", failed)
+	}
+	return nil
+}
+
+// algorithmOf guesses the algorithm of a hex digest from its length.
+func algorithmOf(digest string) string {
+	switch len(digest) {
+	case 64:
+		return "sha256"
+	case 40:
+		return "sha1"
+	case 32:
+		return "md5"
+	}
+	return ""
+}
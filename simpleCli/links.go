@@ -0,0 +1,45 @@
+package simplecli
+
+import (
+	"io/fs"
+	"os"
+)
+
+// colorBroken is the color of the symlinks whose target doesn't exist.
+const colorBroken = "\033[1;31m"
+
+// newEntry describes a file found at path, resolving it when it's a symlink: its target
+// is kept, and the link is broken when the target doesn't exist. Followed links take
+// the information of their target, so a link to a directory is walked like one.
+func newEntry(path, rel string, info fs.FileInfo, follow bool) entry {
+	e := entry{path: rel, info: info}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		return e
+	}
+	e.target, _ = os.Readlink(path)
+	target, err := os.Stat(path)
+	if err != nil {
+		e.broken = true
+		return e
+	}
+	if follow {
+		e.info = target
+	}
+	return e
+}
+
+// displayName is the path of the entry colored by type, followed by the target of the
+// symlinks when showTarget is set, like name -> target.
+func (e entry) displayName(color, showTarget bool) string {
+	name := colorize(e.path, e.info.Mode(), color)
+	if e.broken && color {
+		name = colorBroken + e.path + colorReset
+	}
+	if showTarget && e.target != "" {
+		name += " -> " + e.target
+		if e.broken {
+			name += " (broken)"
+		}
+	}
+	return name
+}
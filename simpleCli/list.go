@@ -0,0 +1,293 @@
+package simplecli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"clikit/clierr"
+)
+
+// entry is a file or directory found while listing, with its path relative to the
+// listed directory.
+type entry struct {
+	path   string
+	info   fs.FileInfo
+	target string // where the symlink points, empty for the other files
+	broken bool   // whether the target of the symlink doesn't exist
+}
+
+// listOptions are the flags of the list mode.
+type listOptions struct {
+	recursive bool
+	maxDepth  int      // levels walked when recursive, 0 for no limit
+	match     string   // glob the names have to match, like *.go, empty to list everything
+	exts      []string // extensions the names have to end with, like .csv, lowercased
+	all       bool     // whether the entries starting with a dot are listed
+	excludes  []string // globs of the entries left out, with their content
+	color     bool     // whether the names are colored by type
+	// followLinks walks the symlinks to directories, skipping the ones leading back to
+	// a directory being walked
+	followLinks bool
+	walkers     int // directories read at the same time when recursive, see walkPool
+}
+
+// filterFlags are the flags choosing the entries, shared by list and tree.
+type filterFlags struct {
+	opts    listOptions
+	ext     string
+	noColor bool
+}
+
+func addFilterFlags(flags *flag.FlagSet) *filterFlags {
+	f := &filterFlags{}
+	flags.IntVar(&f.opts.maxDepth, "max-depth", 0, "Number of levels listed, implies --recursive for list (0 for no limit)")
+	flags.StringVar(&f.opts.match, "match", "", "Only list the entries whose name matches this glob, like '*.go'")
+	flags.StringVar(&f.ext, "ext", "", "Only list the entries with one of these comma separated extensions, like .csv,.json")
+	flags.BoolVar(&f.opts.all, "a", false, "List the entries starting with a dot too (shorthand)")
+	flags.BoolVar(&f.opts.all, "all", false, "List the entries starting with a dot too")
+	flags.Var((*patternList)(&f.opts.excludes), "exclude", "Leave out the entries matching these comma separated globs, and their content, like node_modules,'*.tmp' (can be repeated)")
+	flags.BoolVar(&f.opts.followLinks, "follow-symlinks", false, "Walk the symlinks to directories like directories, the ones looping back are skipped")
+	flags.BoolVar(&f.noColor, "no-color", false, "Don't color the names by type, they're only colored when printing to a terminal")
+	return f
+}
+
+// options validates the filter flags and returns them as listOptions.
+func (f *filterFlags) options() (listOptions, error) {
+	opts := f.opts
+	if opts.maxDepth < 0 {
+		return opts, clierr.Errorf(clierr.Usage, "max-depth can't be negative")
+	}
+	if _, err := filepath.Match(opts.match, ""); err != nil {
+		return opts, fmt.Errorf("match: %w", err)
+	}
+	opts.exts = parseExts(f.ext)
+	opts.color = colorEnabled(f.noColor)
+	return opts, nil
+}
+
+// rootArg returns the directory given to list or tree, the current one by default.
+func rootArg(name string, positional []string) (string, error) {
+	switch len(positional) {
+	case 0:
+		return ".", nil
+	case 1:
+		return positional[0], nil
+	}
+	return "", clierr.Errorf(clierr.Usage, "%s takes a single directory", name)
+}
+
+// runList implements "simpleCli list [options] [directory]".
+func runList(args []string) error {
+	flags := newFlagSet("list", "list [options] [directory]")
+	filters := addFilterFlags(flags)
+	recursive := flags.Bool("recursive", false, "List the subdirectories too")
+	addWalkersFlag(flags, &filters.opts.walkers)
+	flags.BoolVar(recursive, "r", false, "List the subdirectories too (shorthand)")
+	display := addDisplayFlags(flags)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	root, err := rootArg("list", positional)
+	if err != nil {
+		return err
+	}
+	opts, err := filters.options()
+	if err != nil {
+		return err
+	}
+	opts.recursive = *recursive || opts.maxDepth > 0
+	if err := display.validate(); err != nil {
+		return err
+	}
+
+	entries, err := listDir(root, opts)
+	if err != nil {
+		return err
+	}
+	return display.print(os.Stdout, entries, opts.color)
+}
+
+// patternList is a flag that can be repeated, every value being a comma separated list.
+type patternList []string
+
+func (p *patternList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patternList) Set(value string) error {
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return err
+		}
+		*p = append(*p, pattern)
+	}
+	return nil
+}
+
+// parseExts turns the --ext list into lowercased extensions with their leading dot.
+func parseExts(value string) []string {
+	var exts []string
+	for _, ext := range strings.Split(value, ",") {
+		if ext = strings.ToLower(strings.TrimSpace(ext)); ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// skipped tells if an entry is hidden or excluded, in which case neither the entry
+// nor its content is listed. Patterns with a slash are matched against the path.
+func (opts listOptions) skipped(rel, name string) bool {
+	if !opts.all && strings.HasPrefix(name, ".") {
+		return true
+	}
+	for _, pattern := range opts.excludes {
+		target := name
+		if strings.Contains(pattern, "/") {
+			target = filepath.ToSlash(rel)
+		}
+		if ok, _ := filepath.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matches tells if the name of an entry passes the --match and --ext filters.
+func (opts listOptions) matches(name string) bool {
+	if opts.match != "" {
+		if ok, _ := filepath.Match(opts.match, name); !ok {
+			return false
+		}
+	}
+	return len(opts.exts) == 0 || slices.Contains(opts.exts, strings.ToLower(filepath.Ext(name)))
+}
+
+// listDir returns the entries of the directory, and the ones of its subdirectories
+// when recursive.
+func listDir(root string, opts listOptions) ([]entry, error) {
+	if !opts.recursive {
+		files, err := os.Open(root)
+		if err != nil {
+			return nil, err
+		}
+		defer files.Close()
+
+		fileInfo, err := files.Readdir(-1)
+		if err != nil {
+			return nil, err
+		}
+		var entries []entry
+		for _, file := range fileInfo {
+			if !opts.skipped(file.Name(), file.Name()) && opts.matches(file.Name()) {
+				entries = append(entries, newEntry(filepath.Join(root, file.Name()), file.Name(), file, opts.followLinks))
+			}
+		}
+		return entries, nil
+	}
+
+	var ancestors []string
+	if opts.followLinks {
+		real, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return nil, err
+		}
+		ancestors = []string{real}
+	}
+	return walk(root, "", 1, opts, newWalkPool(opts.walkers), ancestors)
+}
+
+// readDir reads the directories walked, replaced by the tests failing some of them.
+var readDir = os.ReadDir
+
+// walk returns the entries of dir, whose path relative to the listed directory is rel,
+// and the ones of its subdirectories, each directory followed by its content. The
+// directories at the last level are listed, not their content, and the directories
+// filtered out are still walked. The subdirectories are walked by the pool. ancestors
+// holds the real paths of the directories being walked when following the symlinks.
+// Like ls -R, the entries that can't be read are logged and skipped, only the listed
+// directory failing the walk.
+func walk(dir, rel string, level int, opts listOptions, pool *walkPool, ancestors []string) ([]entry, error) {
+	dirEntries, err := readDir(dir)
+	if err != nil && rel == "" {
+		return nil, err
+	}
+	if err != nil {
+		// the entries read before the error are still listed
+		slog.Warn("can't read the directory", "path", dir, "err", err)
+	}
+	// every child keeps its entry and its content apart until they're merged in order
+	type child struct {
+		entry   *entry
+		content []entry
+	}
+	children := make([]child, len(dirEntries))
+	var tasks []func()
+	for i, d := range dirEntries {
+		childRel := filepath.Join(rel, d.Name())
+		if opts.skipped(childRel, d.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, d.Name())
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // removed in the meantime
+		}
+		if err != nil {
+			slog.Warn("can't read the entry", "path", path, "err", err)
+			continue
+		}
+		e := newEntry(path, childRel, info, opts.followLinks)
+		if opts.matches(d.Name()) {
+			children[i].entry = &e
+		}
+		if !e.info.IsDir() || (opts.maxDepth > 0 && level >= opts.maxDepth) {
+			continue
+		}
+
+		childAncestors := ancestors
+		if opts.followLinks {
+			real, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				slog.Warn("can't resolve the link", "path", path, "err", err)
+				continue
+			}
+			if slices.Contains(ancestors, real) {
+				slog.Warn("not following the link, it loops back", "path", path, "target", real)
+				continue
+			}
+			// clipped so the walks of the siblings don't share the same array
+			childAncestors = append(slices.Clip(ancestors), real)
+		}
+		c := &children[i]
+		tasks = append(tasks, func() {
+			// only the listed directory fails the walk
+			c.content, _ = walk(path, childRel, level+1, opts, pool, childAncestors)
+		})
+	}
+	pool.run(tasks)
+
+	var entries []entry
+	for _, c := range children {
+		if c.entry != nil {
+			entries = append(entries, *c.entry)
+		}
+		entries = append(entries, c.content...)
+	}
+	return entries, nil
+}
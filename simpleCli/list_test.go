@@ -0,0 +1,75 @@
+package simplecli
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTree creates the files, and their directories, under a temporary directory it
+// returns.
+func writeTree(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, name := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func entryNames(entries []entry) []string {
+	var names []string
+	for _, e := range entries {
+		names = append(names, filepath.ToSlash(e.path))
+	}
+	return names
+}
+
+func Test_listDir_unreadable(t *testing.T) {
+	root := writeTree(t, "a/x", "b/y", "b/c/z", "d")
+	// root can read any directory, so the failures are made up
+	defer func(read func(string) ([]os.DirEntry, error)) { readDir = read }(readDir)
+	readDir = func(dir string) ([]os.DirEntry, error) {
+		if filepath.Base(dir) == "b" || dir == "missing" {
+			return nil, &fs.PathError{Op: "open", Path: dir, Err: fs.ErrPermission}
+		}
+		return os.ReadDir(dir)
+	}
+
+	entries, err := listDir(root, listOptions{recursive: true, walkers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := entryNames(entries), []string{"a", "a/x", "b", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("listDir() = %v, want %v", got, want)
+	}
+	if _, err := listDir("missing", listOptions{recursive: true, walkers: 1}); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("listDir() of an unreadable directory error = %v", err)
+	}
+
+	// du leaves the content of b out of the sizes
+	usage, err := diskUsage(root, ".", 0, listOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes := map[string]int64{}
+	for _, u := range usage {
+		rel, _ := filepath.Rel(root, u.Path)
+		sizes[filepath.ToSlash(rel)] = u.Size
+	}
+	if want := map[string]int64{"a/x": 3, "a": 3, "b": 0, "d": 1, ".": 4}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("diskUsage() = %v, want %v", sizes, want)
+	}
+	if _, err := diskUsage("missing", ".", 0, listOptions{}, nil); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("diskUsage() of an unreadable directory error = %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package simplecli
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"text/tabwriter"
+	"time"
+)
+
+// printLong writes the entries like ls -l does: permissions, owner, size, modification
+// time and path.
+func printLong(w io.Writer, entries []entry, human, color bool) error {
+	table := tabwriter.NewWriter(w, 0, 0, 0, ' ', tabwriter.AlignRight)
+	now := time.Now()
+	for _, entry := range entries {
+		size := fmt.Sprint(entry.info.Size())
+		if human {
+			size = humanSize(entry.info.Size())
+		}
+		fmt.Fprintf(table, "%s\t %s\t %s\t %s\t %s\n", entry.info.Mode(), entryOwner(entry.info), size, modTime(entry.info.ModTime(), now), entry.displayName(color, true))
+	}
+	return table.Flush()
+}
+
+// modTime writes the time like ls does, with the year instead of the time of day for
+// the files older than six months.
+func modTime(t, now time.Time) string {
+	if t.Before(now.AddDate(0, -6, 0)) || t.After(now) {
+		return t.Format("Jan _2  2006")
+	}
+	return t.Format("Jan _2 15:04")
+}
+
+// humanSize writes the size with a unit, like 1.5K or 12M.
+func humanSize(size int64) string {
+	const units = "KMGTPE"
+	if size < 1024 {
+		return fmt.Sprintf("%dB", size)
+	}
+	value := float64(size)
+	unit := -1
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f%c", value, units[unit])
+	}
+	return fmt.Sprintf("%.0f%c", value, units[unit])
+}
+
+// entryOwner is the owner of the file, or the one recorded by a tar archive.
+func entryOwner(info fs.FileInfo) string {
+	if header, ok := info.Sys().(*tar.Header); ok {
+		if header.Uname != "" {
+			return header.Uname
+		}
+		return fmt.Sprint(header.Uid)
+	}
+	if _, ok := info.Sys().(*zip.FileHeader); ok {
+		return "-"
+	}
+	return owner(info)
+}
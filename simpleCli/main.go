@@ -1,26 +1,264 @@
-package main
+package simplecli
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
+
+	"clikit/clierr"
+	"clikit/logging"
 )
 
-func main() {
-	name := flag.String("name", "Valentine", "The name of the passed in user")
-	flag.Parse()
-	// NArg is the number of arguments passed after the flag
-	if flag.NArg() == 0 {
-		fmt.Printf("Hello, %s!\n", *name)
-	} else if flag.Arg(0) == "list" {
-		files, _ := os.Open(".")
-		defer files.Close()
-
-		fileInfo, _ := files.Readdir(-1)
-		for _, file := range fileInfo {
-			fmt.Println(file.Name())
+// command is a subcommand of simpleCli, run with the arguments following its name.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+	paged   bool // whether a long output goes to the pager
+}
+
+// commands returns the subcommands, in the order they're listed by the usage.
+func commands() []command {
+	return []command{
+		{"greet", "Print a greeting", runGreet, false},
+		{"list", "List the entries of a directory", runList, true},
+		{"tree", "Draw the hierarchy of a directory", runTree, true},
+		{"watch", "Print the changes made to a file or directory", runWatch, false},
+		{"du", "Print the disk usage of a directory", runDu, true},
+		{"cp", "Copy files and directories", runCp, false},
+		{"mv", "Move files and directories", runMv, false},
+		{"rm", "Remove files and directories", runRm, false},
+		{"grep", "Search the content of files", runGrep, true},
+		{"hash", "Print or check the digests of files", runHash, true},
+		{"find", "Find the files by name, size, modification time or type", runFind, true},
+		{"archive", "List the content of a zip, tar or tar.gz file", runArchive, true},
+		{"config", "Print the settings of the commands and where they come from", runConfig, true},
+		{"completion", "Print the completion script of bash, zsh or fish", runCompletion, false},
+		{"selfupdate", "Replace simpleCli with the latest release", runSelfUpdate, false},
+	}
+}
+
+// Command describes a subcommand of simpleCli to the tools running it, like goclitool
+// listing them in its help and completing their flags.
+type Command struct {
+	Name    string
+	Summary string
+	Flags   *flag.FlagSet
+}
+
+// Commands returns the subcommands with their flags, in the order they're listed by
+// the usage.
+func Commands() []Command {
+	var described []Command
+	for _, cmd := range commands() {
+		described = append(described, Command{cmd.name, cmd.summary, commandFlagSet(cmd)})
+	}
+	return described
+}
+
+// flagErrorHandling is how the commands handle a wrong flag, the interactive mode
+// reports it instead of exiting.
+var flagErrorHandling = flag.ExitOnError
+
+// program is how simpleCli is run, shown by the usages, like "simpleCli" or
+// "goclitool fs".
+var program = "simpleCli"
+
+// Main runs simpleCli with the arguments following the name of the program, which is
+// how the usages call it.
+func Main(name string, args []string) {
+	program = name
+	// The global options come before the command, like simpleCli --config=my.rc --no-pager -v list
+	noPager := false
+	var logOptions logging.Options
+options:
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		option, value, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		switch option {
+		case "no-pager":
+			noPager = true
+		case "v", "verbose":
+			logOptions.Verbose++
+		case "vv":
+			logOptions.Verbose += 2
+		case "q", "quiet":
+			logOptions.Quiet = true
+		case "config":
+			if !hasValue && len(args) < 2 {
+				exitGracefully(clierr.Errorf(clierr.Usage, "config needs the path of a file"))
+			}
+			if !hasValue {
+				value, args = args[1], args[1:]
+			}
+			configPath = value
+		case "log-format":
+			if !hasValue && len(args) < 2 {
+				exitGracefully(clierr.Errorf(clierr.Usage, "log-format needs text or json"))
+			}
+			if !hasValue {
+				value, args = args[1], args[1:]
+			}
+			logOptions.Format = value
+		default:
+			break options
+		}
+		args = args[1:]
+	}
+	if err := logging.Setup(os.Stderr, logOptions, slog.LevelWarn); err != nil {
+		exitGracefully(clierr.Wrap(clierr.Usage, err))
+	}
+	if len(args) == 1 && (args[0] == "--interactive" || args[0] == "-interactive" || args[0] == "-i") {
+		if err := runInteractive(); err != nil {
+			exitGracefully(err)
+		}
+		return
+	}
+	// Without a command, or with the flags of greet only, the user is greeted
+	if len(args) == 0 || (strings.HasPrefix(args[0], "-") && !isHelp(args[0])) {
+		args = append([]string{"greet"}, args...)
+	}
+	if isHelp(args[0]) {
+		usage()
+		return
+	}
+	if args[0] == "help" && len(args) > 1 {
+		// help list is the same as list -h
+		args = []string{args[1], "-h"}
+	} else if args[0] == "help" {
+		usage()
+		return
+	}
+
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		exitGracefully(unknownCommand(args[0]))
+	}
+	finish := func() {}
+	if cmd.paged && !noPager {
+		finish = startPager()
+	}
+	err := cmd.run(args[1:])
+	finish()
+	if err != nil {
+		exitGracefully(err)
+	}
+}
+
+func isHelp(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+func usage() {
+	fmt.Printf("Usage: %s <command> [options]\n\nCommands:\n", program)
+	for _, cmd := range commands() {
+		fmt.Printf("  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Printf("\nRun \"%s <command> -h\" for the options of a command, or \"%s --interactive\"\n", program, program)
+	fmt.Println("to run several commands from a prompt.")
+	fmt.Println("\nThe default values of the flags are read from ~/.goclirc, or from the file given with")
+	fmt.Println("--config before the command, as key = value lines where the keys are the names of the")
+	fmt.Println("flags, like sort = size or color = false. Keys under a [list] line only apply to list.")
+	fmt.Println("The environment wins over the file, like SIMPLECLI_SORT=size, and the command line wins")
+	fmt.Println("over both. \"config show\" prints where the settings come from.")
+	fmt.Println("\nThe diagnostics go to stderr, -v or --verbose before the command logs more of them,")
+	fmt.Println("-q or --quiet only the errors, and --log-format=json writes them as JSON lines.")
+	fmt.Println("\nOn an error, the exit code tells its kind: 1 internal, 2 usage, 3 validation, 4 io")
+	fmt.Println("and 5 network. With --log-format=json, the error is logged with its category and code.")
+	fmt.Println("\nOutputs longer than the terminal go to $PAGER, or less, unless --no-pager is given")
+	fmt.Println("before the command.")
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// unknownCommand is the error of a command that doesn't exist, suggesting the
+// commands with a close name.
+func unknownCommand(name string) error {
+	var suggestions []string
+	for _, cmd := range commands() {
+		if strings.HasPrefix(cmd.name, name) || editDistance(name, cmd.name) <= 2 {
+			suggestions = append(suggestions, cmd.name)
+		}
+	}
+	if len(suggestions) == 0 {
+		return clierr.Errorf(clierr.Usage, "unknown command %q, run %s -h for the list of commands", name, program)
+	}
+	return clierr.Errorf(clierr.Usage, "unknown command %q, did you mean %s?", name, strings.Join(suggestions, " or "))
+}
+
+// editDistance is the Levenshtein distance between two names.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// flagsHook, when set, is given the flags of a command instead of parsing them, the
+// command then returning errFlagsCollected. It's how completion learns the flags.
+var flagsHook func(flags *flag.FlagSet)
+
+var errFlagsCollected = errors.New("flags collected")
+
+// newFlagSet returns the flags of a command, described by its usage line like
+// "list [options] [directory]".
+func newFlagSet(name, usageLine string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flagErrorHandling)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s\nOptions:\n", program, usageLine)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseArgs parses the flags of a command, which may come before or after its
+// positional arguments, and returns the positional ones. The config file gives the
+// default values of the flags.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	if flagsHook != nil {
+		flagsHook(flags)
+		return nil, errFlagsCollected
+	}
+	if _, err := applyConfig(flags); err != nil {
+		return nil, clierr.Wrap(clierr.Validation, err)
+	}
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
 		}
-	} else {
-		fmt.Println("Check documentation")
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
 	}
 }
+
+// exitGracefully logs the error and exits with the code of its category, see package
+// clierr.
+func exitGracefully(err error) {
+	clierr.Exit(err)
+}
@@ -0,0 +1,29 @@
+package simplecli
+
+import "testing"
+
+func Test_Commands(t *testing.T) {
+	described := Commands()
+	if len(described) != len(commands()) {
+		t.Fatalf("Commands() returned %d commands, want %d", len(described), len(commands()))
+	}
+	globals := []string{"no-pager", "v", "verbose", "vv", "q", "quiet", "config", "log-format"}
+	for _, cmd := range described {
+		if cmd.Flags == nil {
+			t.Errorf("%s has no flags", cmd.Name)
+			continue
+		}
+		// the global options come before the command, a flag of the same name would be taken for one
+		for _, name := range globals {
+			if cmd.Flags.Lookup(name) != nil {
+				t.Errorf("%s has a flag named like the global option %s", cmd.Name, name)
+			}
+		}
+	}
+	if described[1].Name != "list" || described[1].Flags.Lookup("sort") == nil {
+		t.Errorf("Commands()[1] = %s, without its flag sort", described[1].Name)
+	}
+	if flagsHook != nil {
+		t.Error("Commands() left flagsHook set")
+	}
+}
@@ -0,0 +1,116 @@
+package simplecli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"clikit/clierr"
+)
+
+// displayFlags are the flags choosing how the entries are printed, shared by list
+// and archive.
+type displayFlags struct {
+	long    bool
+	human   bool
+	sortBy  string
+	reverse bool
+	targets bool
+	output  string
+}
+
+func addDisplayFlags(flags *flag.FlagSet) *displayFlags {
+	d := &displayFlags{}
+	flags.BoolVar(&d.long, "l", false, "List the permissions, owner, size and modification time of every entry")
+	flags.BoolVar(&d.human, "human", false, "Print the sizes of the long listing like 1.5K or 12M")
+	flags.StringVar(&d.sortBy, "sort", "name", "Order of the entries: name, size (largest first), mtime (newest first) or ext")
+	flags.BoolVar(&d.reverse, "reverse", false, "Reverse the order of the entries")
+	flags.BoolVar(&d.targets, "targets", false, "Print where the symlinks point, like name -> target, broken links being marked (always on with -l)")
+	flags.StringVar(&d.output, "output", "text", "Output of the listing: text, or json and csv for records with the name, size, mode, mtime and isDir of every entry")
+	return d
+}
+
+func (d *displayFlags) validate() error {
+	switch d.output {
+	case "text":
+	case "json", "csv":
+		if d.long {
+			return clierr.Errorf(clierr.Usage, "l can't be combined with --output=%s", d.output)
+		}
+	default:
+		return clierr.Errorf(clierr.Usage, "output has to be either text, json or csv")
+	}
+	return nil
+}
+
+// print sorts the entries and writes them in the output format.
+func (d *displayFlags) print(w io.Writer, entries []entry, color bool) error {
+	if err := sortEntries(entries, d.sortBy, d.reverse); err != nil {
+		return err
+	}
+	switch {
+	case d.output == "json":
+		return printJSON(w, entries)
+	case d.output == "csv":
+		return printCSV(w, entries)
+	case d.long:
+		return printLong(w, entries, d.human, color)
+	}
+	for _, entry := range entries {
+		if _, err := fmt.Fprintln(w, entry.displayName(color, d.targets)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entryRecord is an entry as written by --output=json and csv.
+type entryRecord struct {
+	Name  string `json:"name"`
+	Size  int64  `json:"size"`
+	Mode  string `json:"mode"`
+	MTime string `json:"mtime"` // RFC3339
+	IsDir bool   `json:"isDir"`
+	// Target is where a symlink points, Broken whether it doesn't exist
+	Target string `json:"target,omitempty"`
+	Broken bool   `json:"broken,omitempty"`
+}
+
+func newEntryRecord(e entry) entryRecord {
+	return entryRecord{
+		Name:   e.path,
+		Size:   e.info.Size(),
+		Mode:   e.info.Mode().String(),
+		MTime:  e.info.ModTime().Format(time.RFC3339),
+		IsDir:  e.info.IsDir(),
+		Target: e.target,
+		Broken: e.broken,
+	}
+}
+
+// printJSON writes the entries as a JSON array.
+func printJSON(w io.Writer, entries []entry) error {
+	records := make([]entryRecord, len(entries))
+	for i, e := range entries {
+		records[i] = newEntryRecord(e)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+// printCSV writes the entries as CSV, with a header line.
+func printCSV(w io.Writer, entries []entry) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"name", "size", "mode", "mtime", "isDir", "target", "broken"})
+	for _, e := range entries {
+		r := newEntryRecord(e)
+		writer.Write([]string{r.Name, strconv.FormatInt(r.Size, 10), r.Mode, r.MTime, strconv.FormatBool(r.IsDir), r.Target, strconv.FormatBool(r.Broken)})
+	}
+	writer.Flush()
+	return writer.Error()
+}
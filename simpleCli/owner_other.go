@@ -0,0 +1,10 @@
+//go:build !unix
+
+package simplecli
+
+import "io/fs"
+
+// owner isn't known on this platform.
+func owner(info fs.FileInfo) string {
+	return "-"
+}
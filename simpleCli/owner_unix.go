@@ -0,0 +1,30 @@
+//go:build unix
+
+package simplecli
+
+import (
+	"io/fs"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// owners caches the user names looked up by owner.
+var owners = map[uint32]string{}
+
+// owner returns the name of the user owning the file, or its uid when it has no name.
+func owner(info fs.FileInfo) string {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	if name, ok := owners[stat.Uid]; ok {
+		return name
+	}
+	name := strconv.FormatUint(uint64(stat.Uid), 10)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+	owners[stat.Uid] = name
+	return name
+}
@@ -0,0 +1,129 @@
+package simplecli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// stdoutIsTerminal is whether the real stdout is a terminal, kept since os.Stdout is
+// replaced by a pipe while paging.
+var stdoutIsTerminal = term.IsTerminal(int(os.Stdout.Fd()))
+
+// realStdout is the stdout the program was started with, given to the commands it
+// runs, like find --exec, so they don't write into the pager.
+var realStdout = os.Stdout
+
+// startPager sends what the command prints to a pager once it doesn't fit the terminal,
+// shorter outputs being printed as they are. The pager is $PAGER, less when it isn't
+// set, or a minimal internal pager when less isn't installed. The returned function
+// has to be called once the command is done, it waits for the pager to quit.
+func startPager() (finish func()) {
+	_, height, err := term.GetSize(int(os.Stdout.Fd()))
+	pagerCmd, set := os.LookupEnv("PAGER")
+	if !stdoutIsTerminal || err != nil || height < 2 || (set && (pagerCmd == "" || pagerCmd == "cat")) {
+		return func() {}
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return func() {}
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// the lines are kept until there's more than a screen of them
+		reader := bufio.NewReader(r)
+		var buffered bytes.Buffer
+		for lines := 0; lines < height-1; lines++ {
+			line, err := reader.ReadBytes('\n')
+			buffered.Write(line)
+			if err != nil {
+				stdout.Write(buffered.Bytes())
+				return
+			}
+		}
+		if err := runPager(pagerCmd, io.MultiReader(&buffered, reader), stdout, height); err != nil {
+			slog.Error("running the pager", "err", err)
+		}
+		// the rest is dropped when the user quits the pager early, so the command can end
+		io.Copy(io.Discard, reader)
+	}()
+
+	return func() {
+		w.Close()
+		<-done
+		os.Stdout = stdout
+	}
+}
+
+func runPager(pagerCmd string, r io.Reader, stdout *os.File, height int) error {
+	if pagerCmd == "" {
+		if _, err := exec.LookPath("less"); err != nil {
+			return internalPager(r, stdout, height)
+		}
+		pagerCmd = "less"
+	}
+	args := strings.Fields(pagerCmd)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = r, stdout, os.Stderr
+	// like git, less keeps the colors and the output on the screen when it quits
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	return cmd.Run()
+}
+
+// internalPager prints a screen of lines at a time, waiting for space to print the next
+// screen, enter to print the next line, or q to quit.
+func internalPager(r io.Reader, stdout *os.File, height int) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		_, err := io.Copy(stdout, r)
+		return err
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	limit := height - 1
+	for shown := 0; scanner.Scan(); shown++ {
+		if shown == limit {
+			key, err := readKey(fd, stdout)
+			if err != nil {
+				return err
+			}
+			switch key {
+			case 'q', 'Q', 3: // 3 is Ctrl-C, which raw mode doesn't turn into a signal
+				return nil
+			case '\r', '\n':
+				limit++
+			default:
+				limit += height - 1
+			}
+		}
+		fmt.Fprintln(stdout, scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// readKey prompts for a key and reads it without waiting for enter.
+func readKey(fd int, stdout *os.File) (byte, error) {
+	fmt.Fprint(stdout, "--More--")
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return 0, err
+	}
+	key := make([]byte, 1)
+	_, err = os.Stdin.Read(key)
+	term.Restore(fd, state)
+	fmt.Fprint(stdout, "\r\033[K")
+	return key[0], err
+}
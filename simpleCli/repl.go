@@ -0,0 +1,211 @@
+package simplecli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// replSession is the state kept between the commands of the interactive mode, on top
+// of the current directory.
+type replSession struct {
+	filters []string // flags added to every list and tree, set with the filter command
+	history []string
+}
+
+// runInteractive reads commands until exit. On a terminal the lines can be edited and
+// the history is browsed with the arrow keys, otherwise the commands are read line by
+// line so they can be piped in.
+func runInteractive() error {
+	// a wrong flag or -h shouldn't end the session
+	flagErrorHandling = flag.ContinueOnError
+	session := &replSession{}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return session.runLines(os.Stdin)
+	}
+
+	fd := int(os.Stdin.Fd())
+	terminal := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "")
+	fmt.Println(`Type "help" for the commands, "exit" or Ctrl-D to quit.`)
+	for {
+		terminal.SetPrompt(prompt())
+		// The terminal is only raw while reading, so the commands print as usual
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		line, err := terminal.ReadLine()
+		term.Restore(fd, state)
+		if err == io.EOF {
+			fmt.Println()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !session.exec(line) {
+			return nil
+		}
+	}
+}
+
+// runLines runs the commands read from r, without a prompt.
+func (s *replSession) runLines(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if !s.exec(scanner.Text()) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+func prompt() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		dir = "?"
+	}
+	return "simpleCli:" + dir + "> "
+}
+
+// exec runs a line, printing the error of the command if any. It returns false once
+// the session is over.
+func (s *replSession) exec(line string) bool {
+	args, err := splitWords(line)
+	if err != nil {
+		slog.Error(err.Error())
+		return true
+	}
+	if len(args) == 0 {
+		return true
+	}
+	s.history = append(s.history, line)
+
+	switch args[0] {
+	case "exit", "quit":
+		return false
+	case "help":
+		fmt.Println("Commands: greet, list, tree, cd [dir], pwd, filter [flags|clear], history, exit")
+		fmt.Println(`Run "<command> -h" for the options of a command.`)
+	case "cd":
+		err = changeDir(args[1:])
+	case "pwd":
+		var dir string
+		if dir, err = os.Getwd(); err == nil {
+			fmt.Println(dir)
+		}
+	case "filter":
+		err = s.setFilters(args[1:])
+	case "history":
+		for i, previous := range s.history {
+			fmt.Printf("%4d  %s\n", i+1, previous)
+		}
+	default:
+		cmd, ok := findCommand(args[0])
+		if !ok {
+			err = unknownCommand(args[0])
+			break
+		}
+		if cmd.name == "list" || cmd.name == "tree" {
+			args = append(append(args[:1:1], s.filters...), args[1:]...)
+		}
+		err = cmd.run(args[1:])
+	}
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		slog.Error(err.Error())
+	}
+	return true
+}
+
+// changeDir moves to the directory, or to the home directory without one.
+func changeDir(args []string) error {
+	switch len(args) {
+	case 0:
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		return os.Chdir(home)
+	case 1:
+		return os.Chdir(args[0])
+	}
+	return errors.New("cd takes a single directory")
+}
+
+// setFilters keeps the filter flags used by every list and tree, like
+// "filter --ext=.go --exclude=vendor". Without flags it prints them, and
+// "filter clear" removes them.
+func (s *replSession) setFilters(args []string) error {
+	switch {
+	case len(args) == 0:
+		if len(s.filters) == 0 {
+			fmt.Println("no filters")
+		} else {
+			fmt.Println(strings.Join(s.filters, " "))
+		}
+		return nil
+	case len(args) == 1 && args[0] == "clear":
+		s.filters = nil
+		return nil
+	}
+	// the flags are checked now rather than on every list
+	flags := newFlagSet("filter", "filter [options]")
+	filters := addFilterFlags(flags)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("filter only takes flags, not %s", positional[0])
+	}
+	if _, err := filters.options(); err != nil {
+		return err
+	}
+	s.filters = args
+	return nil
+}
+
+// splitWords splits a line into words like a shell does, words being quoted with
+// single or double quotes to keep their spaces.
+func splitWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("missing closing quote %c", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,31 @@
+package simplecli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"clikit/clierr"
+	"clikit/selfupdate"
+)
+
+// SelfUpdater updates the executable running simpleCli, the one of goclitool when
+// it's run by goclitool fs.
+var SelfUpdater = selfupdate.Updater{Tool: "simpleCli", Version: selfupdate.CurrentVersion()}
+
+// runSelfUpdate implements "simpleCli selfupdate [--check-only] [--channel=beta]".
+func runSelfUpdate(args []string) error {
+	flags := newFlagSet("selfupdate", "selfupdate [options]")
+	var options selfupdate.Options
+	selfupdate.AddFlags(flags, &options)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return clierr.Errorf(clierr.Usage, "selfupdate doesn't take any argument")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return SelfUpdater.Run(ctx, options)
+}
@@ -0,0 +1,40 @@
+package simplecli
+
+import (
+	"path/filepath"
+	"sort"
+
+	"clikit/clierr"
+)
+
+// sortEntries orders the entries by name, size, mtime or ext, like ls does: the largest
+// and the newest entries come first. Ties are ordered by name so the output is stable.
+func sortEntries(entries []entry, by string, reverse bool) error {
+	var less func(a, b entry) bool
+	switch by {
+	case "", "name":
+		less = func(a, b entry) bool { return false } // only the paths are compared
+	case "size":
+		less = func(a, b entry) bool { return a.info.Size() > b.info.Size() }
+	case "mtime":
+		less = func(a, b entry) bool { return a.info.ModTime().After(b.info.ModTime()) }
+	case "ext":
+		less = func(a, b entry) bool { return filepath.Ext(a.path) < filepath.Ext(b.path) }
+	default:
+		return clierr.Errorf(clierr.Usage, "sort has to be either name, size, mtime or ext, not %s", by)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if reverse {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.path < b.path
+	})
+	return nil
+}
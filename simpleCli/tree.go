@@ -0,0 +1,121 @@
+package simplecli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// treeCounts are the directories and files printed in the tree.
+type treeCounts struct {
+	dirs, files int
+}
+
+// runTree implements "simpleCli tree [options] [directory]".
+func runTree(args []string) error {
+	flags := newFlagSet("tree", "tree [options] [directory]")
+	filters := addFilterFlags(flags)
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	root, err := rootArg("tree", positional)
+	if err != nil {
+		return err
+	}
+	opts, err := filters.options()
+	if err != nil {
+		return err
+	}
+	return printTree(os.Stdout, root, opts)
+}
+
+// printTree draws the hierarchy of the directory with box-drawing connectors, every
+// directory followed by the number of its entries and every symlink by its target.
+// The --max-depth, hidden, --exclude and --match filters apply, the last ones to the
+// files only.
+func printTree(w io.Writer, root string, opts listOptions) error {
+	children, err := treeChildren(root, ".", opts)
+	if err != nil {
+		return err
+	}
+	ancestors := map[string]bool{}
+	if opts.followLinks {
+		real, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return err
+		}
+		ancestors[real] = true
+	}
+	fmt.Fprintln(w, root)
+	var counts treeCounts
+	printTreeLevel(w, root, ".", children, "", 1, opts, ancestors, &counts)
+	_, err = fmt.Fprintf(w, "\n%d directories, %d files\n", counts.dirs, counts.files)
+	return err
+}
+
+// treeChildren returns the entries of a directory shown in the tree, sorted by name.
+// Their path is their name.
+func treeChildren(dir, rel string, opts listOptions) ([]entry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var children []entry
+	for _, d := range dirEntries {
+		if opts.skipped(filepath.Join(rel, d.Name()), d.Name()) {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue // removed in the meantime
+		}
+		child := newEntry(filepath.Join(dir, d.Name()), d.Name(), info, opts.followLinks)
+		if !child.info.IsDir() && !opts.matches(d.Name()) {
+			continue
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+// printTreeLevel prints the children of a directory, prefixed by the connectors of the
+// levels above. Directories that can't be read are printed with their error, and
+// followed symlinks looping back to a directory above aren't walked again.
+func printTreeLevel(w io.Writer, dir, rel string, children []entry, prefix string, level int, opts listOptions, ancestors map[string]bool, counts *treeCounts) {
+	for i, child := range children {
+		connector, indent := "├── ", "│   "
+		if i == len(children)-1 {
+			connector, indent = "└── ", "    "
+		}
+		name := child.displayName(opts.color, true)
+		if !child.info.IsDir() {
+			counts.files++
+			fmt.Fprintf(w, "%s%s%s\n", prefix, connector, name)
+			continue
+		}
+
+		counts.dirs++
+		path, childRel := filepath.Join(dir, child.path), filepath.Join(rel, child.path)
+		real := ""
+		if opts.followLinks {
+			real, _ = filepath.EvalSymlinks(path)
+			if ancestors[real] {
+				fmt.Fprintf(w, "%s%s%s [loops back]\n", prefix, connector, name)
+				continue
+			}
+		}
+		grandChildren, err := treeChildren(path, childRel, opts)
+		if err != nil {
+			fmt.Fprintf(w, "%s%s%s [%v]\n", prefix, connector, name, err)
+			continue
+		}
+		fmt.Fprintf(w, "%s%s%s (%d)\n", prefix, connector, name, len(grandChildren))
+		if opts.maxDepth == 0 || level < opts.maxDepth {
+			ancestors[real] = true
+			printTreeLevel(w, path, childRel, grandChildren, prefix+indent, level+1, opts, ancestors, counts)
+			delete(ancestors, real)
+		}
+	}
+}
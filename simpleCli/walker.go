@@ -0,0 +1,80 @@
+package simplecli
+
+import (
+	"errors"
+	"flag"
+	"strconv"
+	"sync"
+)
+
+// defaultWalkers is the number of directories read at the same time by default. Walking
+// waits on the disk or the network far more than on the CPU, so it's more than the cores.
+const defaultWalkers = 16
+
+// walkers is the value of --walkers, which has to be at least 1.
+type walkers int
+
+func (w *walkers) String() string {
+	return strconv.Itoa(int(*w))
+}
+
+func (w *walkers) Set(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	if n < 1 {
+		return errors.New("has to be at least 1")
+	}
+	*w = walkers(n)
+	return nil
+}
+
+// addWalkersFlag adds --walkers, defaulting to defaultWalkers.
+func addWalkersFlag(flags *flag.FlagSet, n *int) {
+	*n = defaultWalkers
+	flags.Var((*walkers)(n), "walkers", "Number of directories read at the same time when walking recursively")
+}
+
+// walkPool bounds the number of goroutines walking directories. The subdirectories are
+// walked on another goroutine while there is a free slot, and on the current one
+// otherwise, so a walk never waits for a slot and can't deadlock.
+type walkPool struct {
+	slots chan struct{}
+}
+
+// newWalkPool returns a pool of n goroutines, or nil to walk on the current goroutine
+// only when n is 1 or less.
+func newWalkPool(n int) *walkPool {
+	if n <= 1 {
+		return nil
+	}
+	// the current goroutine is one of the walkers
+	return &walkPool{slots: make(chan struct{}, n-1)}
+}
+
+// run runs the tasks and waits for them. They write their results to their own slot,
+// which the caller merges in order so the output doesn't depend on the scheduling.
+func (p *walkPool) run(tasks []func()) {
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		if p == nil {
+			task()
+			continue
+		}
+		select {
+		case p.slots <- struct{}{}:
+			wg.Add(1)
+			go func(task func()) {
+				defer func() {
+					<-p.slots
+					wg.Done()
+				}()
+				task()
+			}(task)
+		default:
+			task()
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,52 @@
+package simplecli
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func Test_walkPool_order(t *testing.T) {
+	// 4 levels of 4 directories, each with 3 files, so the pool runs out of slots
+	var files []string
+	var add func(dir string, level int)
+	add = func(dir string, level int) {
+		for i := 0; i < 3; i++ {
+			files = append(files, fmt.Sprintf("%sfile%d", dir, i))
+		}
+		if level == 4 {
+			return
+		}
+		for i := 0; i < 4; i++ {
+			add(fmt.Sprintf("%sdir%d/", dir, i), level+1)
+		}
+	}
+	add("", 0)
+	root := writeTree(t, files...)
+
+	walks := map[int][]string{}
+	usages := map[int][]duEntry{}
+	for _, walkers := range []int{1, 16} {
+		entries, err := listDir(root, listOptions{recursive: true, walkers: walkers})
+		if err != nil {
+			t.Fatal(err)
+		}
+		walks[walkers] = entryNames(entries)
+		if usages[walkers], err = diskUsage(root, ".", 0, listOptions{}, newWalkPool(walkers)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := len(files) + 4 + 16 + 64 + 256; len(walks[1]) != want {
+		t.Fatalf("listDir() returned %d entries, want %d", len(walks[1]), want)
+	}
+	if !reflect.DeepEqual(walks[1], walks[16]) {
+		t.Errorf("listDir() with 16 walkers = %v, want the order of 1 walker %v", walks[16], walks[1])
+	}
+	if !reflect.DeepEqual(usages[1], usages[16]) {
+		t.Errorf("diskUsage() with 16 walkers = %v, want the order of 1 walker %v", usages[16], usages[1])
+	}
+	// each directory is followed by its content
+	if walks[1][0] != "dir0" || walks[1][1] != "dir0/dir0" || walks[1][len(walks[1])-1] != "file2" {
+		t.Errorf("listDir() = %v...", walks[1][:3])
+	}
+}
@@ -0,0 +1,156 @@
+package simplecli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchEvent is a change printed by the watch command.
+type watchEvent struct {
+	Time  string `json:"time"` // RFC3339
+	Event string `json:"event"`
+	Path  string `json:"path"`
+}
+
+// eventName names the operation like inotifywait does, empty for the ones not reported.
+func eventName(op fsnotify.Op) string {
+	switch {
+	case op.Has(fsnotify.Create):
+		return "create"
+	case op.Has(fsnotify.Write):
+		return "modify"
+	case op.Has(fsnotify.Remove):
+		return "delete"
+	case op.Has(fsnotify.Rename):
+		return "rename"
+	case op.Has(fsnotify.Chmod):
+		return "attrib"
+	}
+	return ""
+}
+
+// runWatch implements "simpleCli watch [options] [path]", printing the changes made to
+// the path until interrupted.
+func runWatch(args []string) error {
+	flags := newFlagSet("watch", "watch [options] [path]")
+	var includes, excludes patternList
+	flags.Var(&includes, "include", "Only report the entries whose name matches these comma separated globs, like '*.go' (can be repeated)")
+	flags.Var(&excludes, "exclude", "Don't report the entries matching these comma separated globs, nor watch their content (can be repeated)")
+	recursive := flags.Bool("recursive", false, "Watch the subdirectories too, including the ones created later")
+	flags.BoolVar(recursive, "r", false, "Watch the subdirectories too (shorthand)")
+	all := flags.Bool("all", false, "Report the entries starting with a dot too")
+	flags.BoolVar(all, "a", false, "Report the entries starting with a dot too (shorthand)")
+	asJSON := flags.Bool("json", false, "Print every event as a JSON line with its time, event and path")
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	root, err := rootArg("watch", positional)
+	if err != nil {
+		return err
+	}
+	opts := listOptions{all: *all, excludes: excludes}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := addWatches(watcher, root, *recursive, opts); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			name := eventName(event.Op)
+			rel, err := filepath.Rel(root, event.Name)
+			if err != nil || rel == "." {
+				rel = event.Name
+			}
+			if name == "" || opts.skipped(rel, filepath.Base(event.Name)) {
+				continue
+			}
+			// directories created while watching are watched too
+			if *recursive && name == "create" {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := addWatches(watcher, event.Name, true, opts); err != nil {
+						slog.Error(err.Error())
+					}
+				}
+			}
+			if len(includes) > 0 && !matchesAny(includes, filepath.Base(event.Name)) {
+				continue
+			}
+			if err := printEvent(watchEvent{time.Now().Format(time.RFC3339), name, event.Name}, *asJSON); err != nil {
+				return err
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Error(err.Error())
+		}
+	}
+}
+
+// addWatches watches the path, and its subdirectories when recursive, leaving out the
+// hidden and excluded ones.
+func addWatches(watcher *fsnotify.Watcher, root string, recursive bool, opts listOptions) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !recursive || !info.IsDir() {
+		return watcher.Add(root)
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// a directory removed in the meantime isn't an error
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if rel, _ := filepath.Rel(root, path); rel != "." && opts.skipped(rel, d.Name()) {
+			return fs.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func printEvent(event watchEvent, asJSON bool) error {
+	if !asJSON {
+		_, err := fmt.Printf("%s %-6s %s\n", event.Time, event.Event, event.Path)
+		return err
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(string(data))
+	return err
+}